@@ -1,3 +1,10 @@
 module poster
 
 go 1.24
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/santhosh-tekuri/jsonschema/v6 v6.0.2
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,241 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Форматы архивов
+const (
+	FormatTar   = "tar"
+	FormatTarGz = "tar.gz"
+	FormatZip   = "zip"
+)
+
+// FormatOf определяет формат архива по имени файла: "" - не архив
+func FormatOf(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return FormatTarGz
+	case strings.HasSuffix(lower, ".tar"):
+		return FormatTar
+	case strings.HasSuffix(lower, ".zip"):
+		return FormatZip
+	}
+	return ""
+}
+
+// Is проверяет, является ли файл архивом: .tar, .tar.gz, .tgz или .zip
+func Is(name string) bool {
+	return FormatOf(name) != ""
+}
+
+// Read читает файлы архива по порядку, не распаковывая его на диск: fn получает имя файла в архиве (через "/")
+// и содержимое. Директории и специальные файлы пропускаются, имена вне корня архива (../, абсолютные) - ошибка.
+func Read(name string, fn func(entry string, data []byte) error) error {
+	return Walk(name, func(entry string, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %v", name, entry, err)
+		}
+		return fn(entry, data)
+	})
+}
+
+// Walk обходит файлы архива по порядку, как Read, но передает fn содержимое потоком: в памяти не больше
+// одного файла, а большой файл можно читать по частям. r действует только до возврата из fn.
+func Walk(name string, fn func(entry string, r io.Reader) error) error {
+	switch FormatOf(name) {
+	case FormatZip:
+		return readZip(name, fn)
+	case FormatTar, FormatTarGz:
+		return readTar(name, fn)
+	default:
+		return fmt.Errorf("неизвестный формат архива %q: ожидалось .tar, .tar.gz, .tgz или .zip", name)
+	}
+}
+
+// readTar читает tar или tar.gz архив потоком
+func readTar(name string, fn func(entry string, r io.Reader) error) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if FormatOf(name) == FormatTarGz {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		entry, err := clean(header.Name)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if err := fn(entry, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// readZip читает zip архив
+func readZip(name string, fn func(entry string, r io.Reader) error) error {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		entry, err := clean(f.Name)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %s: %v", name, entry, err)
+		}
+		err = fn(entry, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clean приводит имя файла архива к пути через "/" без ./ в начале.
+// Имена, выходящие за корень архива, - ошибка: по ним строятся пути ответов.
+func clean(name string) (string, error) {
+	entry := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if path.IsAbs(entry) || entry == ".." || strings.HasPrefix(entry, "../") {
+		return "", fmt.Errorf("недопустимое имя файла в архиве %q", name)
+	}
+	return entry, nil
+}
+
+// Writer записывает файлы в архив. Архив создается под временным именем и появляется только после Close,
+// поэтому после сбоя не остается недописанного архива. Безопасен при вызове из нескольких воркеров.
+type Writer struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	gz   *gzip.Writer
+	tar  *tar.Writer
+	zip  *zip.Writer
+}
+
+// Create создает архив, формат определяется по имени файла
+func Create(name string) (*Writer, error) {
+	format := FormatOf(name)
+	if format == "" {
+		return nil, fmt.Errorf("неизвестный формат архива %q: ожидалось .tar, .tar.gz, .tgz или .zip", name)
+	}
+	if dir := filepath.Dir(name); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	file, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{path: name, file: file}
+	switch format {
+	case FormatZip:
+		w.zip = zip.NewWriter(file)
+	case FormatTarGz:
+		w.gz = gzip.NewWriter(file)
+		w.tar = tar.NewWriter(w.gz)
+	case FormatTar:
+		w.tar = tar.NewWriter(file)
+	}
+	return w, nil
+}
+
+// Add добавляет файл в архив, entry - имя в архиве через "/"
+func (w *Writer) Add(entry string, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.zip != nil {
+		f, err := w.zip.CreateHeader(&zip.FileHeader{Name: entry, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	}
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     entry,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+	}
+	if err := w.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := w.tar.Write(data)
+	return err
+}
+
+// Close дописывает архив и переименовывает его в итоговое имя
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	defer os.Remove(w.file.Name()) // После успешного переименования ничего не удаляет
+
+	var err error
+	if w.zip != nil {
+		err = w.zip.Close()
+	} else {
+		err = w.tar.Close()
+		if w.gz != nil {
+			if gzErr := w.gz.Close(); err == nil {
+				err = gzErr
+			}
+		}
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("запись архива %s: %v", w.path, err)
+	}
+	if err := os.Chmod(w.file.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(w.file.Name(), w.path)
+}
@@ -0,0 +1,132 @@
+package archive
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestFormatOf проверяет определение формата по имени файла
+func TestFormatOf(t *testing.T) {
+	tests := map[string]string{
+		"requests.tar":    FormatTar,
+		"requests.tar.gz": FormatTarGz,
+		"REQUESTS.TGZ":    FormatTarGz,
+		"out/resp.zip":    FormatZip,
+		"requests":        "",
+		"requests.jsonl":  "",
+		"requests.gz":     "",
+	}
+	for name, want := range tests {
+		if got := FormatOf(name); got != want {
+			t.Errorf("FormatOf(%q) = %q, ожидалось %q", name, got, want)
+		}
+	}
+}
+
+// TestWriterRead проверяет запись архива и чтение его обратно во всех форматах
+func TestWriterRead(t *testing.T) {
+	files := map[string]string{
+		"a.json":          `{"a": 1}`,
+		"orders/b.json":   `{"b": 2}`,
+		"orders/x/c.json": `{"c": 3}`,
+	}
+	order := []string{"a.json", "orders/b.json", "orders/x/c.json"}
+
+	for _, name := range []string{"out.tar", "out.tar.gz", "out.tgz", "out.zip"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "nested", name)
+			w, err := Create(path)
+			if err != nil {
+				t.Fatalf("Create() вернул ошибку: %v", err)
+			}
+			for _, entry := range order {
+				if err := w.Add(entry, []byte(files[entry])); err != nil {
+					t.Fatalf("Add(%s) вернул ошибку: %v", entry, err)
+				}
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("архив появился до Close: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() вернул ошибку: %v", err)
+			}
+
+			got := make(map[string]string)
+			var names []string
+			err = Read(path, func(entry string, data []byte) error {
+				got[entry] = string(data)
+				names = append(names, entry)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Read() вернул ошибку: %v", err)
+			}
+			if !reflect.DeepEqual(got, files) || !reflect.DeepEqual(names, order) {
+				t.Errorf("Read() = %v (порядок %v), ожидалось %v", got, names, files)
+			}
+
+			entries, _ := os.ReadDir(filepath.Dir(path))
+			if len(entries) != 1 {
+				t.Errorf("после Close остались временные файлы: %v", entries)
+			}
+		})
+	}
+}
+
+// TestRead_Entries проверяет пропуск директорий и ошибку на именах вне корня архива
+func TestRead_Entries(t *testing.T) {
+	write := func(t *testing.T, headers ...*tar.Header) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "in.tar")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		tw := tar.NewWriter(file)
+		for _, header := range headers {
+			if err := tw.WriteHeader(header); err != nil {
+				t.Fatal(err)
+			}
+			if header.Size > 0 {
+				tw.Write([]byte(strings.Repeat("x", int(header.Size))))
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	path := write(t,
+		&tar.Header{Typeflag: tar.TypeDir, Name: "./orders/", Mode: 0755},
+		&tar.Header{Typeflag: tar.TypeReg, Name: "./orders/a.json", Mode: 0644, Size: 1},
+		&tar.Header{Typeflag: tar.TypeSymlink, Name: "link.json", Linkname: "orders/a.json"},
+	)
+	var names []string
+	if err := Read(path, func(entry string, data []byte) error {
+		names = append(names, entry)
+		return nil
+	}); err != nil {
+		t.Fatalf("Read() вернул ошибку: %v", err)
+	}
+	if !reflect.DeepEqual(names, []string{"orders/a.json"}) {
+		t.Errorf("файлы = %v, ожидалось [orders/a.json]", names)
+	}
+
+	for _, name := range []string{"../a.json", "/etc/a.json", "orders/../../a.json"} {
+		path := write(t, &tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: 1})
+		err := Read(path, func(string, []byte) error { return nil })
+		if err == nil || !strings.Contains(err.Error(), "недопустимое имя") {
+			t.Errorf("%s: Read() = %v, ожидалась ошибка имени", name, err)
+		}
+	}
+
+	if err := Read("requests.rar", nil); err == nil {
+		t.Error("неизвестный формат: ожидалась ошибка")
+	}
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Типы авторизации запросов
+const (
+	TypeNone   = "none"   // Без авторизации: отменяет авторизацию из файла конфигурации или профиля
+	TypeBearer = "bearer" // Authorization: Bearer <токен>
+	TypeBasic  = "basic"  // Authorization: Basic base64(пользователь:пароль)
+)
+
+// Types = допустимые типы авторизации ("" - по заданным значениям)
+var Types = []string{"", TypeNone, TypeBearer, TypeBasic}
+
+// Auth = авторизация запросов из конфигурации
+type Auth struct {
+	Type     string // TypeNone, TypeBearer или TypeBasic
+	Token    string // Токен для TypeBearer
+	User     string // Пользователь для TypeBasic
+	Password string // Пароль для TypeBasic
+}
+
+// New проверяет параметры авторизации. Пустой тип выводится из значений: токен - bearer,
+// пользователь - basic, ничего - без авторизации. Явный тип нужен, только если заданы и токен,
+// и пользователь, например когда профиль меняет тип авторизации общего файла.
+func New(kind, token, user, password string) (Auth, error) {
+	if !slices.Contains(Types, kind) {
+		return Auth{}, fmt.Errorf("тип %q должен быть одним из %v", kind, Types[1:])
+	}
+	if kind == "" {
+		switch {
+		case token != "" && user != "":
+			return Auth{}, fmt.Errorf("заданы и токен, и пользователь: укажите тип %s или %s", TypeBearer, TypeBasic)
+		case token != "":
+			kind = TypeBearer
+		case user != "":
+			kind = TypeBasic
+		default:
+			kind = TypeNone
+		}
+	}
+
+	switch kind {
+	case TypeBearer:
+		if token == "" {
+			return Auth{}, fmt.Errorf("тип %s требует токен", kind)
+		}
+		return Auth{Type: kind, Token: token}, nil
+	case TypeBasic:
+		if user == "" {
+			return Auth{}, fmt.Errorf("тип %s требует пользователя", kind)
+		}
+		return Auth{Type: kind, User: user, Password: password}, nil
+	}
+	return Auth{Type: TypeNone}, nil
+}
+
+// Header возвращает значение заголовка Authorization (пусто - без авторизации)
+func (a Auth) Header() string {
+	switch a.Type {
+	case TypeBearer:
+		return "Bearer " + a.Token
+	case TypeBasic:
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(a.User+":"+a.Password))
+	}
+	return ""
+}
+
+// Headers собирает общие заголовки запросов: заголовки конфигурации и Authorization.
+// Авторизация важнее заголовка Authorization из списка заголовков.
+func Headers(headers map[string]string, a Auth) http.Header {
+	h := make(http.Header, len(headers)+1)
+	for name, value := range headers {
+		h.Set(name, value)
+	}
+	if value := a.Header(); value != "" {
+		h.Set("Authorization", value)
+	}
+	return h
+}
+
+// ParseHeaders разбирает заголовки "X-Api-Key=abc,X-Tenant=42". Заголовки разделяются запятыми,
+// запятая в значении экранируется: "Cache-Control=no-cache\, max-age=0" (см. Escape).
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, part := range split(s) {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		name, value, found := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !found || !validName(name) {
+			return nil, fmt.Errorf("ожидалось заголовок=значение: %q (запятая в значении экранируется \\,)", part)
+		}
+		headers[http.CanonicalHeaderKey(name)] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+// Escape экранирует значение заголовка для списка ParseHeaders: "," - "\,", "\" - "\\"
+func Escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `,`, `\,`).Replace(value)
+}
+
+// split делит список заголовков по запятым, кроме экранированных, и снимает экранирование.
+// Обратная косая черта перед другим символом остается как есть: "C:\dir" не нужно экранировать.
+func split(s string) []string {
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && (s[i+1] == ',' || s[i+1] == '\\'):
+			i++
+			part.WriteByte(s[i])
+		case s[i] == ',':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(s[i])
+		}
+	}
+	return append(parts, part.String())
+}
+
+// validName проверяет имя заголовка: символы token из RFC 9110
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Mask скрывает значения заголовков для журнала и логов: "X-Api-Key=***"
+func Mask(headers string) string {
+	parsed, err := ParseHeaders(headers)
+	if err != nil || len(parsed) == 0 {
+		return headers
+	}
+	names := make([]string, 0, len(parsed))
+	for name := range parsed {
+		names = append(names, name+"=***")
+	}
+	slices.Sort(names)
+	return strings.Join(names, ",")
+}
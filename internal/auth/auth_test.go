@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestNew проверяет выбор типа авторизации и заголовок Authorization
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name                        string
+		kind, token, user, password string
+		want                        string // Заголовок Authorization
+		wantErr                     bool
+	}{
+		{"без авторизации", "", "", "", "", "", false},
+		{"токен", "", "t0k", "", "", "Bearer t0k", false},
+		{"пользователь", "", "", "user", "pass", "Basic dXNlcjpwYXNz", false},
+		{"явный тип при токене и пользователе", TypeBasic, "t0k", "user", "pass", "Basic dXNlcjpwYXNz", false},
+		{"none отменяет авторизацию", TypeNone, "t0k", "user", "", "", false},
+		{"токен и пользователь без типа", "", "t0k", "user", "", "", true},
+		{"bearer без токена", TypeBearer, "", "user", "", "", true},
+		{"basic без пользователя", TypeBasic, "t0k", "", "", "", true},
+		{"неизвестный тип", "digest", "", "", "", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, err := New(test.kind, test.token, test.user, test.password)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("New() = %v, ожидалась ошибка: %v", err, test.wantErr)
+			}
+			if got := a.Header(); got != test.want {
+				t.Errorf("Header() = %q, ожидалось %q", got, test.want)
+			}
+		})
+	}
+}
+
+// TestParseHeaders проверяет разбор списка заголовков
+func TestParseHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"пусто", "", map[string]string{}, false},
+		{"несколько", "x-api-key=abc, X-Tenant=42", map[string]string{"X-Api-Key": "abc", "X-Tenant": "42"}, false},
+		{"экранированная запятая", `Accept=text/html;q=0.9\, application/json,X-Id=1`, map[string]string{"Accept": "text/html;q=0.9, application/json", "X-Id": "1"}, false},
+		{"= после экранированной запятой", `Cache-Control=no-cache\, max-age=0`, map[string]string{"Cache-Control": "no-cache, max-age=0"}, false},
+		{"запятая без экранирования разделяет", "Cache-Control=no-cache, max-age=0", map[string]string{"Cache-Control": "no-cache", "Max-Age": "0"}, false},
+		{"обратная косая черта", `X-Path=C:\dir,X-Slash=a\\\,b`, map[string]string{"X-Path": `C:\dir`, "X-Slash": `a\,b`}, false},
+		{"часть без имени", "Cache-Control=no-cache, private", nil, true},
+		{"пустое значение", "X-Empty=", map[string]string{"X-Empty": ""}, false},
+		{"без имени", "abc", nil, true},
+		{"недопустимое имя", "X Api=1", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseHeaders(test.s)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseHeaders() = %v, ожидалась ошибка: %v", err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseHeaders() = %v, ожидалось %v", got, test.want)
+			}
+		})
+	}
+}
+
+// TestHeaders проверяет, что авторизация важнее заголовка Authorization из списка
+func TestHeaders(t *testing.T) {
+	a, _ := New("", "t0k", "", "")
+	h := Headers(map[string]string{"Authorization": "Basic x", "X-Tenant": "42"}, a)
+	if h.Get("Authorization") != "Bearer t0k" || h.Get("X-Tenant") != "42" {
+		t.Errorf("Headers() = %v", h)
+	}
+	if got := Mask("X-Tenant=42,Authorization=Basic x"); got != "Authorization=***,X-Tenant=***" {
+		t.Errorf("Mask() = %q", got)
+	}
+	for _, value := range []string{"no-cache, max-age=0", `a\,b`, `C:\dir`} {
+		if got, err := ParseHeaders("X-Value=" + Escape(value)); err != nil || got["X-Value"] != value {
+			t.Errorf("ParseHeaders(Escape(%q)) = %q, %v", value, got["X-Value"], err)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package compare
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"poster/internal/jsonpath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Виды изменений
+const (
+	KindAdded   = "added"   // Значение появилось
+	KindRemoved = "removed" // Значение пропало
+	KindChanged = "changed" // Значение изменилось
+)
+
+// Статусы сравнения файла
+const (
+	StatusSame    = "same"    // Ответ совпадает с эталоном
+	StatusChanged = "changed" // Ответ отличается от эталона
+	StatusNew     = "new"     // Эталона нет
+	StatusMissing = "missing" // Эталон есть, а ответа нет
+)
+
+// Change = одно различие между эталоном и ответом
+type Change struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// String возвращает изменение в виде строки: $.a: 1 -> 2
+func (c Change) String() string {
+	switch c.Kind {
+	case KindAdded:
+		return fmt.Sprintf("+ %s: %s", c.Path, encode(c.New))
+	case KindRemoved:
+		return fmt.Sprintf("- %s: %s", c.Path, encode(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, encode(c.Old), encode(c.New))
+	}
+}
+
+// FileDiff = результат сравнения одного ответа с эталоном
+type FileDiff struct {
+	File    string   `json:"file"`
+	Status  string   `json:"status"`
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Changed проверяет, отличается ли ответ от эталона
+func (d FileDiff) Changed() bool {
+	return d.Status != StatusSame
+}
+
+// Comparer сравнивает ответы с эталонной директорией, игнорируя изменчивые поля
+type Comparer struct {
+	Baseline string
+	ignore   []*jsonpath.Path
+}
+
+// New создает Comparer. ignore - шаблоны JSONPath полей, которые не сравниваются: $..timestamp, $.items[*].id
+func New(baseline string, ignore []string) (*Comparer, error) {
+	c := &Comparer{Baseline: baseline}
+	for _, pattern := range ignore {
+		p, err := jsonpath.Parse(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("ignore: %v", err)
+		}
+		c.ignore = append(c.ignore, p)
+	}
+	return c, nil
+}
+
+// File сравнивает ответ с одноименным файлом эталонной директории
+func (c *Comparer) File(name string, response []byte) (FileDiff, error) {
+	baseline, err := os.ReadFile(filepath.Join(c.Baseline, name))
+	if os.IsNotExist(err) {
+		return FileDiff{File: name, Status: StatusNew}, nil
+	}
+	if err != nil {
+		return FileDiff{File: name}, fmt.Errorf("чтение эталона: %v", err)
+	}
+
+	changes := c.Diff(baseline, response)
+	diff := FileDiff{File: name, Status: StatusSame, Changes: changes}
+	if len(changes) > 0 {
+		diff.Status = StatusChanged
+	}
+	return diff, nil
+}
+
+// Missing возвращает результат для файла без ответа: изменение, если эталон существует
+func (c *Comparer) Missing(name string) (FileDiff, bool) {
+	if _, err := os.Stat(filepath.Join(c.Baseline, name)); err != nil {
+		return FileDiff{}, false
+	}
+	return FileDiff{File: name, Status: StatusMissing}, true
+}
+
+// Dir сравнивает все файлы директории ответов с эталоном.
+// Файлы эталона без ответа считаются пропавшими, скрытые (временные) файлы пропускаются.
+func (c *Comparer) Dir(responses string) (*Report, error) {
+	report := &Report{Baseline: c.Baseline}
+	compared := make(map[string]bool)
+	err := walkFiles(responses, func(name, path string) error {
+		response, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		diff, err := c.File(name, response)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		report.Add(diff)
+		compared[name] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("директория ответов: %v", err)
+	}
+
+	err = walkFiles(c.Baseline, func(name, _ string) error {
+		if !compared[name] {
+			report.Add(FileDiff{File: name, Status: StatusMissing})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("директория эталона: %v", err)
+	}
+	report.Sort()
+	return report, nil
+}
+
+// walkFiles обходит файлы директории рекурсивно, name - путь относительно dir
+func walkFiles(dir string, fn func(name, path string) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return fn(name, path)
+	})
+}
+
+// Diff сравнивает два JSON документа по смыслу: порядок ключей и форматирование не важны.
+// Если хотя бы один документ не JSON, документы сравниваются побайтно.
+func (c *Comparer) Diff(baseline, response []byte) []Change {
+	var oldDoc, newDoc any
+	if json.Unmarshal(baseline, &oldDoc) != nil || json.Unmarshal(response, &newDoc) != nil {
+		if bytes.Equal(bytes.TrimSpace(baseline), bytes.TrimSpace(response)) {
+			return nil
+		}
+		return []Change{{Path: "$", Kind: KindChanged, Old: string(baseline), New: string(response)}}
+	}
+
+	var changes []Change
+	c.diff(nil, oldDoc, newDoc, &changes)
+	return changes
+}
+
+// diff рекурсивно сравнивает значения и собирает изменения
+func (c *Comparer) diff(path []any, oldValue, newValue any, changes *[]Change) {
+	if c.ignored(path) {
+		return
+	}
+
+	switch oldTyped := oldValue.(type) {
+	case map[string]any:
+		newTyped, ok := newValue.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(oldTyped)+len(newTyped))
+		for key := range oldTyped {
+			keys = append(keys, key)
+		}
+		for key := range newTyped {
+			if _, ok := oldTyped[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			c.child(append(path, key), oldTyped, newTyped, key, changes)
+		}
+		return
+	case []any:
+		newTyped, ok := newValue.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < max(len(oldTyped), len(newTyped)); i++ {
+			childPath := append(path, i)
+			switch {
+			case i >= len(newTyped):
+				c.add(childPath, KindRemoved, oldTyped[i], nil, changes)
+			case i >= len(oldTyped):
+				c.add(childPath, KindAdded, nil, newTyped[i], changes)
+			default:
+				c.diff(childPath, oldTyped[i], newTyped[i], changes)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(oldValue, newValue) {
+		c.add(path, KindChanged, oldValue, newValue, changes)
+	}
+}
+
+// child сравнивает значения ключа в двух объектах
+func (c *Comparer) child(path []any, oldObj, newObj map[string]any, key string, changes *[]Change) {
+	oldValue, inOld := oldObj[key]
+	newValue, inNew := newObj[key]
+	switch {
+	case !inNew:
+		c.add(path, KindRemoved, oldValue, nil, changes)
+	case !inOld:
+		c.add(path, KindAdded, nil, newValue, changes)
+	default:
+		c.diff(path, oldValue, newValue, changes)
+	}
+}
+
+// add добавляет изменение, если путь не игнорируется
+func (c *Comparer) add(path []any, kind string, oldValue, newValue any, changes *[]Change) {
+	if c.ignored(path) {
+		return
+	}
+	*changes = append(*changes, Change{Path: jsonpath.Format(path), Kind: kind, Old: oldValue, New: newValue})
+}
+
+// ignored проверяет, подходит ли путь под правила игнорирования
+func (c *Comparer) ignored(path []any) bool {
+	for _, p := range c.ignore {
+		if p.Match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// encode записывает значение в компактный JSON для вывода
+func encode(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
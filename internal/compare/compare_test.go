@@ -0,0 +1,154 @@
+package compare
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDiff проверяет смысловое сравнение JSON
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		ignore   []string
+		baseline string
+		response string
+		want     []string
+	}{
+		{"порядок ключей и форматирование", nil, `{"a":1,"b":[1,2]}`, "{\n  \"b\": [1, 2],\n  \"a\": 1.0\n}", nil},
+		{"изменено значение", nil, `{"a":1}`, `{"a":2}`, []string{"~ $.a: 1 -> 2"}},
+		{"добавлен и удален ключ", nil, `{"a":1}`, `{"b":true}`, []string{"- $.a: 1", "+ $.b: true"}},
+		{"элементы массива", nil, `{"x":[1,2,3]}`, `{"x":[1,5]}`, []string{"~ $.x[1]: 2 -> 5", "- $.x[2]: 3"}},
+		{"смена типа", nil, `{"x":{"y":1}}`, `{"x":[1]}`, []string{`~ $.x: {"y":1} -> [1]`}},
+		{"игнорирование рекурсивно", []string{"$..ts"}, `{"ts":1,"u":{"ts":2,"n":"a"}}`, `{"ts":3,"u":{"ts":4,"n":"a"}}`, nil},
+		{"игнорирование поддерева", []string{"$.meta"}, `{"meta":{"id":1}}`, `{"meta":{"id":2,"x":1}}`, nil},
+		{"игнорирование элементов", []string{"$.items[*].id"}, `{"items":[{"id":1,"v":1}]}`, `{"items":[{"id":9,"v":2}]}`, []string{"~ $.items[0].v: 1 -> 2"}},
+		{"не JSON совпадает", nil, "plain text\n", "plain text", nil},
+		{"не JSON отличается", nil, "old", "new", []string{`~ $: "old" -> "new"`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := New("", test.ignore)
+			if err != nil {
+				t.Fatalf("New() вернул ошибку: %v", err)
+			}
+			changes := c.Diff([]byte(test.baseline), []byte(test.response))
+			var got []string
+			for _, change := range changes {
+				got = append(got, change.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("Diff() = %q, ожидалось %q", got, test.want)
+			}
+		})
+	}
+
+	if _, err := New("", []string{"timestamp"}); err == nil {
+		t.Error("ожидалась ошибка для некорректного шаблона")
+	}
+}
+
+// TestFile проверяет сравнение с эталонной директорией и отчет
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"same.json":    `{"a":1,"ts":100}`,
+		"changed.json": `{"a":1}`,
+		"gone.json":    `{"a":1}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, _ := New(dir, []string{"$.ts"})
+	report := &Report{Baseline: dir}
+	for name, response := range map[string]string{"same.json": `{"ts":200,"a":1}`, "changed.json": `{"a":2}`, "new.json": `{}`} {
+		diff, err := c.File(name, []byte(response))
+		if err != nil {
+			t.Fatalf("File(%s) вернул ошибку: %v", name, err)
+		}
+		report.Add(diff)
+	}
+	if diff, ok := c.Missing("gone.json"); ok {
+		report.Add(diff)
+	}
+	if _, ok := c.Missing("never.json"); ok {
+		t.Error("Missing() без эталона не должен считаться изменением")
+	}
+	report.Sort()
+
+	if report.Same != 1 || report.Changed != 1 || report.New != 1 || report.Missing != 1 || !report.HasChanges() {
+		t.Errorf("итог сравнения неверный: %+v", report)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText() вернул ошибку: %v", err)
+	}
+	for _, want := range []string{"~ changed.json\n    ~ $.a: 1 -> 2", "+ new.json", "- gone.json", "совпадает 1"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("отчет не содержит %q:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "same.json") {
+		t.Error("совпадающие файлы не выводятся в текстовом отчете")
+	}
+
+	path := filepath.Join(t.TempDir(), "out", "diff.json")
+	if err := report.Write(path); err != nil {
+		t.Fatalf("Write() вернул ошибку: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	var got Report
+	if err := json.Unmarshal(data, &got); err != nil || len(got.Files) != 4 {
+		t.Errorf("JSON отчет прочитан неверно: %v, %+v", err, got)
+	}
+}
+
+// TestDir проверяет сравнение двух директорий
+func TestDir(t *testing.T) {
+	baseline, responses := t.TempDir(), t.TempDir()
+	write := func(dir, name, content string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(baseline, "same.json", `{"a":1}`)
+	write(baseline, "sub/changed.json", `{"a":1}`)
+	write(baseline, "gone.json", `{}`)
+	write(responses, "same.json", `{ "a": 1 }`)
+	write(responses, "sub/changed.json", `{"a":2}`)
+	write(responses, "new.json", `{}`)
+	write(responses, ".new.json.123.tmp", `{`)
+
+	c, _ := New(baseline, nil)
+	report, err := c.Dir(responses)
+	if err != nil {
+		t.Fatalf("Dir() вернул ошибку: %v", err)
+	}
+	if report.Same != 1 || report.Changed != 1 || report.New != 1 || report.Missing != 1 {
+		t.Errorf("итог сравнения неверный: %+v", report)
+	}
+	var names []string
+	for _, diff := range report.Files {
+		names = append(names, filepath.ToSlash(diff.File)+":"+diff.Status)
+	}
+	want := "gone.json:missing,new.json:new,same.json:same,sub/changed.json:changed"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("файлы = %s, ожидалось %s", got, want)
+	}
+
+	if _, err := c.Dir(filepath.Join(responses, "missing")); err == nil {
+		t.Error("ожидалась ошибка для несуществующей директории")
+	}
+}
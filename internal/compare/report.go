@@ -0,0 +1,96 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Report = отчет о сравнении прогона с эталоном
+type Report struct {
+	Baseline string     `json:"baseline"`
+	Same     int        `json:"same"`
+	Changed  int        `json:"changed"`
+	New      int        `json:"new"`
+	Missing  int        `json:"missing"`
+	Files    []FileDiff `json:"files"`
+}
+
+// Add добавляет результат сравнения файла
+func (r *Report) Add(diff FileDiff) {
+	switch diff.Status {
+	case StatusSame:
+		r.Same++
+	case StatusChanged:
+		r.Changed++
+	case StatusNew:
+		r.New++
+	case StatusMissing:
+		r.Missing++
+	}
+	r.Files = append(r.Files, diff)
+}
+
+// HasChanges проверяет, есть ли отличия от эталона
+func (r *Report) HasChanges() bool {
+	return r.Changed+r.New+r.Missing > 0
+}
+
+// Sort упорядочивает файлы по имени
+func (r *Report) Sort() {
+	sort.Slice(r.Files, func(i, j int) bool { return r.Files[i].File < r.Files[j].File })
+}
+
+// WriteText записывает отчет в текстовом виде: только отличающиеся файлы и итог
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	for _, diff := range r.Files {
+		switch diff.Status {
+		case StatusChanged:
+			fmt.Fprintf(&b, "~ %s\n", diff.File)
+			for _, change := range diff.Changes {
+				fmt.Fprintf(&b, "    %s\n", change)
+			}
+		case StatusNew:
+			fmt.Fprintf(&b, "+ %s (нет в эталоне)\n", diff.File)
+		case StatusMissing:
+			fmt.Fprintf(&b, "- %s (нет ответа)\n", diff.File)
+		}
+	}
+	fmt.Fprintf(&b, "Сравнение с %s: совпадает %d, изменено %d, новых %d, нет ответа %d\n",
+		r.Baseline, r.Same, r.Changed, r.New, r.Missing)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Write записывает отчет в файл: .json - JSON, иначе текст
+func (r *Report) Write(path string) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(r)
+	} else {
+		err = r.WriteText(file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("запись отчета сравнения %s: %v", path, err)
+	}
+	return nil
+}
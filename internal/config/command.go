@@ -0,0 +1,197 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"poster/internal/jsonpath"
+	"poster/internal/report"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Подкоманды poster
+const (
+	CommandRun      = "run"      // Отправка запросов (по умолчанию)
+	CommandValidate = "validate" // Проверка файлов запросов без отправки
+	CommandDiff     = "diff"     // Сравнение двух директорий ответов
+	CommandReport   = "report"   // Отчеты по сохраненным результатам
+	CommandServe    = "serve"    // Тестовый сервер
+	CommandHelp     = "help"     // Справка
+)
+
+// Command = подкоманда: имя, строка использования и назначение для общей справки
+type Command struct {
+	Name    string
+	Usage   string
+	Summary string
+}
+
+// Commands = подкоманды в порядке вывода справки
+var Commands = []Command{
+	{CommandRun, "poster [run] [-config=<файл>] [-profile=S] [-url=<URL>] [-headers=S] [-auth-type=S] [-auth-token=S] [-auth-user=S] [-auth-password=S] [-requests=<имяДиректории|архив>] [-responses=<имяДиректории|архив>] [-timeout=N] [-workers=N] [-drain=D] [-journal=<файл>] [-resume] [-dry-run] [-report=<файлы>] [-log=S] [-retry-attempts=N] [-retry-base=D] [-retry-max=D] [-retry-jitter=F] [-retry-status=S] [-retry-network=B] [-rps=F] [-burst=N] [-host-rps=S] [-duration=D] [-iterations=N] [-order=S] [-stages=S] [-ramp=S] [-open-loop] [-baseline=<имяДиректории>] [-ignore=<пути>] [-diff=<файл>] [-request-schema=<файл>] [-response-schema=<файл>] [-template] [-vars=<файл>] [-scenarios=<путь>] [-include=<шаблоны>] [-exclude=<шаблоны>] [-output-order=S]",
+		"отправить запросы (подкоманда по умолчанию)"},
+	{CommandValidate, "poster validate [-config=<файл>] [-profile=S] [-url=<URL>] [-requests=<имяДиректории|архив>] [-scenarios=<путь>] [-request-schema=<файл>] [-response-schema=<файл>] [-template] [-vars=<файл>] [-include=<шаблоны>] [-exclude=<шаблоны>] [-log=S]",
+		"проверить файлы запросов без отправки"},
+	{CommandDiff, "poster diff [-ignore=<пути>] [-out=<файл>] <эталон> <ответы>",
+		"сравнить две директории ответов"},
+	{CommandReport, "poster report [-out=<файлы>] <результаты.json>",
+		"построить отчеты по JSON отчету прошлого прогона"},
+	{CommandServe, "poster serve [-addr=<адрес>] [-delay=D] [-status=N] [-error-rate=F] [-fixtures=<имяДиректории>]",
+		"запустить тестовый сервер"},
+}
+
+// Split отделяет подкоманду от ее аргументов. Без подкоманды (нет аргументов или первый - флаг) - run,
+// первый флаг справки (-h, -help, --help) - общая справка help. Неизвестная подкоманда возвращается как есть.
+func Split(args []string) (string, []string) {
+	if len(args) > 0 && slices.Contains([]string{"-h", "-help", "--help"}, args[0]) {
+		return CommandHelp, args[1:]
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return CommandRun, args
+	}
+	return args[0], args[1:]
+}
+
+// Usage возвращает строку использования подкоманды
+func Usage(command string) string {
+	for _, c := range Commands {
+		if c.Name == command {
+			return "Использование: " + c.Usage
+		}
+	}
+	return "Использование: poster <подкоманда> [флаги]"
+}
+
+// PrintUsage выводит общую справку по подкомандам
+func PrintUsage(w io.Writer) {
+	fmt.Fprintln(w, "Использование: poster <подкоманда> [флаги]")
+	fmt.Fprintln(w, "\nПодкоманды:")
+	for _, c := range Commands {
+		fmt.Fprintf(w, "  %-9s %s\n", c.Name, c.Summary)
+	}
+	fmt.Fprintln(w, "\nСправка по флагам подкоманды: poster <подкоманда> -h")
+}
+
+// newFlagSet создает набор флагов подкоманды со справкой по ней
+func newFlagSet(command string) *flag.FlagSet {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), Usage(command))
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// DiffConfig = настройки подкоманды diff
+type DiffConfig struct {
+	Baseline  string   `doc:"Директория эталонных ответов"`
+	Responses string   `doc:"Директория сравниваемых ответов"`
+	Ignore    []string `doc:"JSONPath полей, не участвующих в сравнении"`
+	Out       string   `doc:"Файл отчета сравнения"`
+}
+
+// ParseDiff разбирает аргументы подкоманды diff
+func ParseDiff(args []string) (*DiffConfig, error) {
+	fs := newFlagSet(CommandDiff)
+	ignore := fs.String("ignore", "", "JSONPath полей, не участвующих в сравнении, через запятую: $..timestamp,$.items[*].id")
+	out := fs.String("out", "", "Файл отчета сравнения: .json или текст (по умолчанию только stdout)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return nil, fmt.Errorf("ожидалось две директории: эталон и ответы")
+	}
+	ignorePaths := splitList(*ignore)
+	for _, path := range ignorePaths {
+		if _, err := jsonpath.Parse(path); err != nil {
+			return nil, fmt.Errorf("ignore: %v", err)
+		}
+	}
+	return &DiffConfig{
+		Baseline:  fs.Arg(0),
+		Responses: fs.Arg(1),
+		Ignore:    ignorePaths,
+		Out:       *out,
+	}, nil
+}
+
+// ReportConfig = настройки подкоманды report
+type ReportConfig struct {
+	Input   string   `doc:"JSON отчет прошлого прогона"`
+	Outputs []string `doc:"Файлы отчетов"`
+}
+
+// ParseReport разбирает аргументы подкоманды report
+func ParseReport(args []string) (*ReportConfig, error) {
+	fs := newFlagSet(CommandReport)
+	out := fs.String("out", "", "Файлы отчетов через запятую, формат по расширению: .json, .csv, .xml (JUnit), .md, .html (по умолчанию - статистика в stdout)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return nil, fmt.Errorf("ожидался один JSON отчет")
+	}
+	outputs := splitList(*out)
+	for _, path := range outputs {
+		if _, err := report.FormatOf(path); err != nil {
+			return nil, fmt.Errorf("out: %v", err)
+		}
+	}
+	return &ReportConfig{Input: fs.Arg(0), Outputs: outputs}, nil
+}
+
+// ServeConfig = настройки подкоманды serve
+type ServeConfig struct {
+	Addr      string        `doc:"Адрес сервера"`
+	Delay     time.Duration `doc:"Задержка ответа"`
+	Status    int           `doc:"HTTP статус ответа"`
+	ErrorRate float64       `doc:"Доля ответов с ошибкой"`
+	Fixtures  string        `doc:"Директория готовых ответов"`
+}
+
+// ParseServe разбирает аргументы подкоманды serve
+func ParseServe(args []string) (*ServeConfig, error) {
+	fs := newFlagSet(CommandServe)
+	addr := fs.String("addr", "localhost:8080", "Адрес сервера")
+	delay := fs.Duration("delay", 0, "Задержка каждого ответа")
+	status := fs.Int("status", http.StatusOK, "HTTP статус ответа")
+	errorRate := fs.Float64("error-rate", 0, "Доля ответов 500 [0..1]")
+	fixtures := fs.String("fixtures", "", "Директория готовых ответов: запрос /a/b получает файл a/b.json (по умолчанию - эхо запроса)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return nil, fmt.Errorf("лишние аргументы: %v", fs.Args())
+	}
+	if *delay < 0 {
+		return nil, fmt.Errorf("delay=%v должен быть >= 0", *delay)
+	}
+	if *status < 100 || 599 < *status {
+		return nil, fmt.Errorf("status=%v должен быть в диапазоне [100..599]", *status)
+	}
+	if *errorRate < 0 || 1 < *errorRate {
+		return nil, fmt.Errorf("error-rate=%v должен быть в диапазоне [0..1]", *errorRate)
+	}
+	if *fixtures != "" {
+		if info, err := os.Stat(*fixtures); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("fixtures: директория %s недоступна", *fixtures)
+		}
+	}
+	return &ServeConfig{
+		Addr:      *addr,
+		Delay:     *delay,
+		Status:    *status,
+		ErrorRate: *errorRate,
+		Fixtures:  *fixtures,
+	}, nil
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSplit проверяет выделение подкоманды
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		args        []string
+		wantCommand string
+		wantArgs    []string
+	}{
+		{nil, CommandRun, nil},
+		{[]string{"-workers", "2"}, CommandRun, []string{"-workers", "2"}},
+		{[]string{"run", "-workers", "2"}, CommandRun, []string{"-workers", "2"}},
+		{[]string{"-h"}, CommandHelp, []string{}},
+		{[]string{"--help"}, CommandHelp, []string{}},
+		{[]string{"run", "-h"}, CommandRun, []string{"-h"}},
+		{[]string{"diff", "a", "b"}, CommandDiff, []string{"a", "b"}},
+		{[]string{"unknown"}, "unknown", []string{}},
+	}
+	for _, test := range tests {
+		command, args := Split(test.args)
+		if command != test.wantCommand || !reflect.DeepEqual(args, test.wantArgs) {
+			t.Errorf("Split(%v) = %q, %v, ожидалось %q, %v", test.args, command, args, test.wantCommand, test.wantArgs)
+		}
+	}
+}
+
+// TestUsage проверяет справку по подкомандам
+func TestUsage(t *testing.T) {
+	for _, c := range Commands {
+		if usage := Usage(c.Name); !strings.Contains(usage, "poster "+c.Name) && c.Name != CommandRun {
+			t.Errorf("Usage(%s) = %q", c.Name, usage)
+		}
+	}
+	var buf bytes.Buffer
+	PrintUsage(&buf)
+	for _, c := range Commands {
+		if !strings.Contains(buf.String(), c.Name) {
+			t.Errorf("справка не содержит подкоманду %s:\n%s", c.Name, buf.String())
+		}
+	}
+}
+
+// TestParse_Command проверяет флаги run у подкоманд run и validate
+func TestParse_Command(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{"cmd", "run", "--workers", "2"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags, err := parse()
+	if err != nil {
+		t.Fatalf("не ожидалась ошибка, но получена: %v", err)
+	}
+	if flags.Command != CommandRun || flags.Workers != 2 {
+		t.Errorf("Command = %q, Workers = %d", flags.Command, flags.Workers)
+	}
+
+	// У validate свои флаги: источник, схемы, переменные и отбор; флаги прогона - ошибка
+	os.Args = []string{"cmd", "validate", "--requests", "req", "--include", "orders/**", "--vars", "vars.json"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags, err = parse()
+	if err != nil {
+		t.Fatalf("validate: не ожидалась ошибка, но получена: %v", err)
+	}
+	if flags.Command != CommandValidate || flags.RequestsDir != "req" || flags.Vars != "vars.json" || !flags.Template || len(flags.Include) != 1 {
+		t.Errorf("validate: Command = %q, RequestsDir = %q, Vars = %q, Include = %v", flags.Command, flags.RequestsDir, flags.Vars, flags.Include)
+	}
+	if flags.Sources["requests"] != "флаг -requests" {
+		t.Errorf("validate: источник requests = %q", flags.Sources["requests"])
+	}
+	for _, args := range [][]string{{"--workers", "2"}, {"--dry-run"}} {
+		os.Args = append([]string{"cmd", "validate"}, args...)
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+		flag.CommandLine.SetOutput(io.Discard)
+		if _, err := parse(); err == nil {
+			t.Errorf("validate %v: ожидалась ошибка неизвестного флага", args)
+		}
+	}
+
+	// Параметры run в файле конфигурации, общем с run, validate не мешают
+	path := filepath.Join(t.TempDir(), "poster.yaml")
+	if err := os.WriteFile(path, []byte("workers: 8\nurl: http://file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Args = []string{"cmd", "validate", "--config", path}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	if flags, err := parse(); err != nil || flags.URL != "http://file" {
+		t.Errorf("validate с файлом конфигурации: URL = %q, ошибка %v", flags.URL, err)
+	}
+
+	// Пробный прогон равносилен подкоманде validate
+	os.Args = []string{"cmd", "-dry-run"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	if flags, err := parse(); err != nil || flags.Command != CommandValidate {
+		t.Errorf("-dry-run: Command = %q, ошибка %v", flags.Command, err)
+	}
+
+	os.Args = []string{"cmd", "serve"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	if _, err := parse(); err == nil {
+		t.Error("флаги run у подкоманды serve должны быть ошибкой")
+	}
+}
+
+// TestParseDiff проверяет флаги подкоманды diff
+func TestParseDiff(t *testing.T) {
+	cfg, err := ParseDiff([]string{"-ignore", "$..ts,$.id", "-out", "diff.json", "old", "new"})
+	if err != nil {
+		t.Fatalf("ParseDiff() вернул ошибку: %v", err)
+	}
+	want := &DiffConfig{Baseline: "old", Responses: "new", Ignore: []string{"$..ts", "$.id"}, Out: "diff.json"}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("ParseDiff() = %+v, ожидалось %+v", cfg, want)
+	}
+
+	for _, args := range [][]string{{"old"}, {"-ignore", "$[", "old", "new"}, {"-unknown", "old", "new"}} {
+		if _, err := ParseDiff(args); err == nil {
+			t.Errorf("ParseDiff(%v): ожидалась ошибка", args)
+		}
+	}
+}
+
+// TestParseReport проверяет флаги подкоманды report
+func TestParseReport(t *testing.T) {
+	cfg, err := ParseReport([]string{"-out", "r.md,r.html", "results.json"})
+	if err != nil {
+		t.Fatalf("ParseReport() вернул ошибку: %v", err)
+	}
+	if cfg.Input != "results.json" || !reflect.DeepEqual(cfg.Outputs, []string{"r.md", "r.html"}) {
+		t.Errorf("ParseReport() = %+v", cfg)
+	}
+
+	for _, args := range [][]string{{}, {"-out", "r.txt", "results.json"}, {"a.json", "b.json"}} {
+		if _, err := ParseReport(args); err == nil {
+			t.Errorf("ParseReport(%v): ожидалась ошибка", args)
+		}
+	}
+}
+
+// TestParseServe проверяет флаги подкоманды serve
+func TestParseServe(t *testing.T) {
+	cfg, err := ParseServe([]string{"-addr", ":9000", "-delay", "50ms", "-status", "201", "-error-rate", "0.1", "-fixtures", t.TempDir()})
+	if err != nil {
+		t.Fatalf("ParseServe() вернул ошибку: %v", err)
+	}
+	if cfg.Addr != ":9000" || cfg.Delay != 50*time.Millisecond || cfg.Status != 201 || cfg.ErrorRate != 0.1 {
+		t.Errorf("ParseServe() = %+v", cfg)
+	}
+
+	defaults, err := ParseServe(nil)
+	if err != nil || defaults.Addr != "localhost:8080" || defaults.Status != 200 || defaults.Fixtures != "" {
+		t.Errorf("ParseServe() по умолчанию = %+v, %v", defaults, err)
+	}
+
+	for _, args := range [][]string{{"-delay", "-1s"}, {"-status", "700"}, {"-error-rate", "2"}, {"-fixtures", "/nonexistent"}, {"extra"}} {
+		if _, err := ParseServe(args); err == nil {
+			t.Errorf("ParseServe(%v): ожидалась ошибка", args)
+		}
+	}
+}
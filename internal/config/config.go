@@ -1,12 +1,67 @@
 package config
 
+import (
+	"net/http"
+	"poster/internal/auth"
+	"poster/internal/load"
+	"time"
+)
+
 type Config struct {
-	URL          string `doc:"Адрес сервера"`
-	RequestsDir  string `doc:"Директория с запросами json"`
-	ResponsesDir string `doc:"Директория с ответами json"`
-	Timeout      int    `doc:"Max время для ответа"`
-	Workers      int    `doc:"Количество параллельных работников"`
-	Log          string `doc:"Уровень логирования ('', 'stdout', 'debug', 'info', 'warn', 'error')"`
+	Command string `doc:"Подкоманда: run или validate"`
+
+	URL          string            `doc:"Адрес сервера"`
+	Headers      map[string]string `doc:"Общие заголовки запросов"`
+	Auth         auth.Auth         `doc:"Авторизация запросов"`
+	RequestsDir  string            `doc:"Директория с запросами json"`
+	ResponsesDir string            `doc:"Директория с ответами json"`
+	Timeout      int               `doc:"Max время для ответа"`
+	Workers      int               `doc:"Количество параллельных работников"`
+	Log          string            `doc:"Уровень логирования ('', 'stdout', 'debug', 'info', 'warn', 'error')"`
+
+	Drain   time.Duration `doc:"Время на завершение запросов после сигнала остановки"`
+	Journal string        `doc:"Журнал обработанных файлов"`
+	Resume  bool          `doc:"Продолжить прогон по журналу"`
+	Reports []string      `doc:"Файлы отчетов о прогоне"`
+
+	RetryAttempts    int           `doc:"Максимальное число попыток запроса"`
+	RetryBaseDelay   time.Duration `doc:"Задержка перед первым повтором"`
+	RetryMaxDelay    time.Duration `doc:"Максимальная задержка между попытками"`
+	RetryJitter      float64       `doc:"Доля случайного разброса задержки"`
+	RetryStatusCodes []int         `doc:"HTTP статусы для повтора"`
+	RetryNetwork     bool          `doc:"Повторять при сетевых ошибках"`
+
+	RPS     float64            `doc:"Целевая скорость, запросов в секунду"`
+	Burst   int                `doc:"Размер пачки запросов"`
+	HostRPS map[string]float64 `doc:"Скорость по хостам"`
+
+	Duration   time.Duration `doc:"Длительность нагрузочного прогона"`
+	Iterations int           `doc:"Количество проходов по файлам"`
+	Order      string        `doc:"Порядок обхода файлов"`
+	Stages     []load.Stage  `doc:"Этапы нагрузки"`
+	Ramp       string        `doc:"Что наращивается по этапам"`
+	OpenLoop   bool          `doc:"Отправка по расписанию независимо от ответов"`
+
+	Baseline   string   `doc:"Директория эталонных ответов"`
+	Ignore     []string `doc:"JSONPath полей, не участвующих в сравнении"`
+	DiffReport string   `doc:"Файл отчета сравнения с эталоном"`
+
+	RequestSchema  string `doc:"JSON Schema тела запроса"`
+	ResponseSchema string `doc:"JSON Schema тела ответа"`
+
+	Template  bool   `doc:"Подстановка шаблонов в запросы (-template или -vars)"`
+	Vars      string `doc:"Файл переменных шаблонов запросов"`
+	Scenarios string `doc:"Файл или директория сценариев"`
+
+	Include []string `doc:"Шаблоны отбора файлов запросов"`
+	Exclude []string `doc:"Шаблоны исключения файлов запросов"`
+
+	OutputOrder string `doc:"Порядок вывода ответов в stdout"`
+
+	ConfigFile string            `doc:"Файл конфигурации"`
+	Profile    string            `doc:"Профиль файла конфигурации"`
+	Effective  map[string]string `doc:"Итоговые значения всех параметров по именам флагов"`
+	Sources    map[string]string `doc:"Источники значений, заданных не по умолчанию"`
 }
 
 func New() (*Config, error) {
@@ -16,11 +71,84 @@ func New() (*Config, error) {
 	}
 
 	return &Config{
+		Command: flags.Command,
+
 		URL:          flags.URL,
+		Headers:      flags.Headers,
+		Auth:         flags.Auth,
 		RequestsDir:  flags.RequestsDir,
 		ResponsesDir: flags.ResponsesDir,
 		Timeout:      flags.Timeout,
 		Workers:      flags.Workers,
 		Log:          flags.Log,
+
+		Drain:   flags.Drain,
+		Journal: flags.Journal,
+		Resume:  flags.Resume,
+		Reports: flags.Reports,
+
+		RetryAttempts:    flags.RetryAttempts,
+		RetryBaseDelay:   flags.RetryBaseDelay,
+		RetryMaxDelay:    flags.RetryMaxDelay,
+		RetryJitter:      flags.RetryJitter,
+		RetryStatusCodes: flags.RetryStatusCodes,
+		RetryNetwork:     flags.RetryNetwork,
+
+		RPS:     flags.RPS,
+		Burst:   flags.Burst,
+		HostRPS: flags.HostRPS,
+
+		Duration:   flags.Duration,
+		Iterations: flags.Iterations,
+		Order:      flags.Order,
+		Stages:     flags.Stages,
+		Ramp:       flags.Ramp,
+		OpenLoop:   flags.OpenLoop,
+
+		Baseline:   flags.Baseline,
+		Ignore:     flags.Ignore,
+		DiffReport: flags.DiffReport,
+
+		RequestSchema:  flags.RequestSchema,
+		ResponseSchema: flags.ResponseSchema,
+
+		Template:  flags.Template,
+		Vars:      flags.Vars,
+		Scenarios: flags.Scenarios,
+
+		Include: flags.Include,
+		Exclude: flags.Exclude,
+
+		OutputOrder: flags.OutputOrder,
+
+		ConfigFile: flags.ConfigFile,
+		Profile:    flags.Profile,
+		Effective:  flags.Effective,
+		Sources:    flags.Sources,
 	}, nil
 }
+
+// RequestHeaders возвращает общие заголовки каждого запроса: заголовки и авторизацию конфигурации
+func (c *Config) RequestHeaders() http.Header {
+	return auth.Headers(c.Headers, c.Auth)
+}
+
+// LoadMode проверяет, задан ли нагрузочный прогон: по времени, по этапам или несколько проходов
+func (c *Config) LoadMode() bool {
+	return c.Duration > 0 || c.Iterations > 1 || len(c.Stages) > 0
+}
+
+// Plan возвращает план нагрузочного прогона
+func (c *Config) Plan() *load.Plan {
+	iterations := c.Iterations
+	if iterations == 0 && c.Duration == 0 && len(c.Stages) == 0 {
+		iterations = 1
+	}
+	return &load.Plan{
+		Iterations: iterations,
+		Duration:   c.Duration,
+		Order:      c.Order,
+		Ramp:       c.Ramp,
+		Stages:     c.Stages,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"runtime"
 	"strconv"
 	"testing"
+	"time"
 )
 
 // TestNew_DefaultValues тестирует создание конфигурации с значениями по умолчанию
@@ -209,7 +210,8 @@ func TestNew_InvalidWorkers(t *testing.T) {
 		{"Нулевое количество workers", "0", true},
 		{"Отрицательное количество workers", "-1", true},
 		{"Положительное количество workers", "1", false},
-		{"Количество workers больше чем CPU", "1000", true},
+		{"Количество workers больше чем CPU", "1000", false},
+		{"Количество workers больше предела", "10001", true},
 	}
 
 	for _, test := range tests {
@@ -432,3 +434,29 @@ func TestNew_WorkersDefaultCPU(t *testing.T) {
 		t.Errorf("Workers = %d, ожидалось количество CPU: %d", cfg.Workers, expectedWorkers)
 	}
 }
+
+// TestConfig_Plan тестирует план нагрузочного прогона
+func TestConfig_Plan(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            Config
+		wantLoad       bool
+		wantIterations int
+	}{
+		{"один проход", Config{}, false, 1},
+		{"несколько проходов", Config{Iterations: 3}, true, 3},
+		{"по времени", Config{Duration: time.Minute}, true, 0},
+		{"по времени с проходами", Config{Duration: time.Minute, Iterations: 2}, true, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.cfg.LoadMode(); got != test.wantLoad {
+				t.Errorf("LoadMode() = %v, ожидалось %v", got, test.wantLoad)
+			}
+			if got := test.cfg.Plan().Iterations; got != test.wantIterations {
+				t.Errorf("Plan().Iterations = %d, ожидалось %d", got, test.wantIterations)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"poster/internal/auth"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Ключи файла конфигурации, не являющиеся флагами
+const (
+	profilesKey = "profiles" // Секция именованных профилей
+	extendsKey  = "extends"  // Профиль, от которого наследуется профиль
+)
+
+// authFlags = флаги секции auth. Профиль, задающий любой из них, заменяет авторизацию общих значений
+// и предков целиком: токен общего файла не смешивается с пользователем профиля.
+var authFlags = []string{"auth-type", "auth-token", "auth-user", "auth-password"}
+
+// readFile читает файл конфигурации .yaml, .yml, .toml или .json в плоский набор значений флагов.
+// Ключи - имена флагов: "retry-attempts", "retry_attempts" или секция retry: {attempts: 3}.
+// profile - профиль из секции profiles, его значения и значения его предков важнее общих (пусто - без профиля),
+// секция auth профиля заменяет общую целиком.
+// known проверяет, есть ли флаг с таким именем. fromProfile - какой профиль задал значение.
+func readFile(path, profile string, known func(name string) bool) (values, fromProfile map[string]string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("чтение файла: %v", err)
+	}
+
+	var tree map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&tree)
+	default:
+		return nil, nil, fmt.Errorf("неизвестный формат %q: ожидалось .yaml, .yml, .toml или .json", ext)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("разбор файла: %v", err)
+	}
+
+	profiles, err := readProfiles(tree[profilesKey], known)
+	if err != nil {
+		return nil, nil, err
+	}
+	delete(tree, profilesKey)
+
+	values = make(map[string]string)
+	if err := flatten("", tree, known, values); err != nil {
+		return nil, nil, err
+	}
+	fromProfile = make(map[string]string)
+	if profile == "" {
+		return values, fromProfile, nil
+	}
+
+	chain, err := resolveProfile(profiles, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, name := range chain {
+		if slices.ContainsFunc(authFlags, func(key string) bool { _, ok := profiles[name].values[key]; return ok }) {
+			for _, key := range authFlags {
+				delete(values, key)
+				delete(fromProfile, key)
+			}
+		}
+		for key, value := range profiles[name].values {
+			values[key] = value
+			fromProfile[key] = name
+		}
+	}
+	return values, fromProfile, nil
+}
+
+// profileSection = профиль файла конфигурации: значения флагов и профиль-предок
+type profileSection struct {
+	extends string
+	values  map[string]string
+}
+
+// readProfiles разбирает секцию profiles. Проверяются все профили, а не только выбранный,
+// чтобы опечатка в редко используемом профиле находилась сразу.
+func readProfiles(value interface{}, known func(name string) bool) (map[string]profileSection, error) {
+	profiles := make(map[string]profileSection)
+	if value == nil {
+		return profiles, nil
+	}
+	sections, ok := asMap(value)
+	if !ok {
+		return nil, fmt.Errorf("%s: ожидалась секция профилей", profilesKey)
+	}
+	for name, item := range sections {
+		section, ok := asMap(item)
+		if !ok {
+			return nil, fmt.Errorf("профиль %s: ожидалась секция параметров", name)
+		}
+		var profile profileSection
+		if parent, ok := section[extendsKey]; ok {
+			if profile.extends, ok = parent.(string); !ok {
+				return nil, fmt.Errorf("профиль %s: %s должен быть именем профиля", name, extendsKey)
+			}
+			delete(section, extendsKey)
+		}
+		profile.values = make(map[string]string)
+		if err := flatten("", section, known, profile.values); err != nil {
+			return nil, fmt.Errorf("профиль %s: %v", name, err)
+		}
+		profiles[name] = profile
+	}
+	return profiles, nil
+}
+
+// resolveProfile возвращает цепочку наследования профиля от базового до него самого
+func resolveProfile(profiles map[string]profileSection, profile string) ([]string, error) {
+	var chain []string
+	for name := profile; name != ""; name = profiles[name].extends {
+		if _, ok := profiles[name]; !ok {
+			if name == profile {
+				return nil, fmt.Errorf("профиль %q не найден", name)
+			}
+			return nil, fmt.Errorf("профиль %q наследуется от несуществующего %q", chain[len(chain)-1], name)
+		}
+		if slices.Contains(chain, name) {
+			return nil, fmt.Errorf("цикл наследования профилей: %s -> %s", strings.Join(chain, " -> "), name)
+		}
+		chain = append(chain, name)
+	}
+	slices.Reverse(chain)
+	return chain, nil
+}
+
+// flatten раскладывает вложенные секции в значения флагов: секция retry с ключом attempts - флаг retry-attempts.
+// Секция, имя которой совпадает с флагом (host-rps), записывается списком ключ=значение.
+func flatten(prefix string, value interface{}, known func(name string) bool, values map[string]string) error {
+	if section, ok := asMap(value); ok && (prefix == "" || !known(prefix)) {
+		for key, item := range section {
+			name := strings.ReplaceAll(strings.ToLower(key), "_", "-")
+			if prefix != "" {
+				name = prefix + "-" + name
+			}
+			if err := flatten(name, item, known, values); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !known(prefix) {
+		return fmt.Errorf("неизвестный параметр %q", prefix)
+	}
+	s, err := format(value)
+	if err != nil {
+		return fmt.Errorf("%s: %v", prefix, err)
+	}
+	values[prefix] = s
+	return nil
+}
+
+// format приводит значение из файла к строке флага: списки - через запятую, секции - ключ=значение.
+// Значения секций экранируются, как в -headers: запятая внутри значения не разделяет элементы
+func format(value interface{}) (string, error) {
+	if section, ok := asMap(value); ok {
+		parts := make([]string, 0, len(section))
+		for key, item := range section {
+			s, err := scalar(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, key+"="+auth.Escape(s))
+		}
+		slices.Sort(parts)
+		return strings.Join(parts, ","), nil
+	}
+	if list, ok := value.([]interface{}); ok {
+		parts := make([]string, 0, len(list))
+		for _, item := range list {
+			s, err := scalar(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), nil
+	}
+	return scalar(value)
+}
+
+// scalar приводит к строке одиночное значение
+func scalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool, int, int64, uint64, json.Number:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("ожидалось число, строка или список, получено %T", value)
+	}
+}
+
+// asMap возвращает секцию файла: YAML с нестроковыми ключами разбирается в map[interface{}]interface{}
+func asMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case map[interface{}]interface{}:
+		section := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			section[fmt.Sprint(key)] = item
+		}
+		return section, true
+	}
+	return nil, false
+}
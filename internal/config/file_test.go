@@ -0,0 +1,197 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestReadFile проверяет чтение файла конфигурации в значения флагов для всех форматов
+func TestReadFile(t *testing.T) {
+	known := func(name string) bool {
+		switch name {
+		case "url", "workers", "retry-attempts", "retry-status", "rps", "host-rps", "report", "open-loop":
+			return true
+		}
+		return false
+	}
+	want := map[string]string{
+		"url":            "http://api.local/execute",
+		"workers":        "200",
+		"retry-attempts": "3",
+		"retry-status":   "429,503",
+		"rps":            "12.5",
+		"host-rps":       "*=10,api.local=50",
+		"report":         "r.json,r.md",
+		"open-loop":      "true",
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"config.yaml", `
+url: http://api.local/execute
+workers: 200
+retry:
+  attempts: 3
+  status: [429, 503]
+rps: 12.5
+host-rps:
+  api.local: 50
+  "*": 10
+report: [r.json, r.md]
+open_loop: true
+`},
+		{"config.toml", `
+url = "http://api.local/execute"
+workers = 200
+rps = 12.5
+report = ["r.json", "r.md"]
+open_loop = true
+
+[retry]
+attempts = 3
+status = [429, 503]
+
+[host-rps]
+"api.local" = 50
+"*" = 10
+`},
+		{"config.json", `{
+  "url": "http://api.local/execute",
+  "workers": 200,
+  "retry": {"attempts": 3, "status": [429, 503]},
+  "rps": 12.5,
+  "host-rps": {"api.local": 50, "*": 10},
+  "report": ["r.json", "r.md"],
+  "open-loop": true
+}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.name)
+			if err := os.WriteFile(path, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, _, err := readFile(path, "", known)
+			if err != nil {
+				t.Fatalf("readFile() вернул ошибку: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("readFile() = %v, ожидалось %v", got, want)
+			}
+		})
+	}
+}
+
+// TestReadFile_Errors проверяет ошибки файла конфигурации
+func TestReadFile_Errors(t *testing.T) {
+	known := func(name string) bool { return name == "workers" }
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown.ini", "workers=1"},
+		{"key.yaml", "threads: 4"},
+		{"section.yaml", "retry:\n  attempts: 3"},
+		{"broken.json", `{"workers": `},
+		{"nested.json", `{"workers": [{"a": 1}]}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.name)
+			if err := os.WriteFile(path, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := readFile(path, "", known); err == nil {
+				t.Error("ожидалась ошибка, но не получена")
+			}
+		})
+	}
+
+	if _, _, err := readFile(filepath.Join(t.TempDir(), "missing.yaml"), "", known); err == nil {
+		t.Error("ожидалась ошибка чтения отсутствующего файла")
+	}
+}
+
+// TestReadFile_Profiles проверяет профили с наследованием
+func TestReadFile_Profiles(t *testing.T) {
+	known := func(name string) bool { return name == "url" || name == "workers" || name == "timeout" }
+	content := `
+url: http://localhost:8080/execute
+workers: 2
+timeout: 10
+profiles:
+  remote:
+    timeout: 30
+  staging:
+    extends: remote
+    url: https://staging.local/execute
+  prod:
+    extends: staging
+    url: https://prod.local/execute
+    workers: 50
+  loop-a:
+    extends: loop-b
+  loop-b:
+    extends: loop-a
+  orphan:
+    extends: missing
+`
+	path := filepath.Join(t.TempDir(), "poster.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		profile     string
+		want        map[string]string
+		fromProfile map[string]string
+	}{
+		{"", map[string]string{"url": "http://localhost:8080/execute", "workers": "2", "timeout": "10"}, map[string]string{}},
+		{"staging",
+			map[string]string{"url": "https://staging.local/execute", "workers": "2", "timeout": "30"},
+			map[string]string{"url": "staging", "timeout": "remote"}},
+		{"prod",
+			map[string]string{"url": "https://prod.local/execute", "workers": "50", "timeout": "30"},
+			map[string]string{"url": "prod", "workers": "prod", "timeout": "remote"}},
+	}
+	for _, test := range tests {
+		t.Run("профиль "+test.profile, func(t *testing.T) {
+			got, fromProfile, err := readFile(path, test.profile, known)
+			if err != nil {
+				t.Fatalf("readFile() вернул ошибку: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("значения = %v, ожидалось %v", got, test.want)
+			}
+			if !reflect.DeepEqual(fromProfile, test.fromProfile) {
+				t.Errorf("источники = %v, ожидалось %v", fromProfile, test.fromProfile)
+			}
+		})
+	}
+
+	for profile, want := range map[string]string{
+		"dev":    "не найден",
+		"loop-a": "цикл наследования профилей: loop-a -> loop-b -> loop-a",
+		"orphan": "несуществующего",
+	} {
+		if _, _, err := readFile(path, profile, known); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("readFile(%s) = %v, ожидалась ошибка с %q", profile, err, want)
+		}
+	}
+
+	// Опечатка в невыбранном профиле тоже ошибка
+	typo := filepath.Join(t.TempDir(), "typo.json")
+	if err := os.WriteFile(typo, []byte(`{"profiles": {"prod": {"wrokers": 5}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := readFile(typo, "", known); err == nil || !strings.Contains(err.Error(), "профиль prod") {
+		t.Errorf("ожидалась ошибка профиля prod, получено: %v", err)
+	}
+}
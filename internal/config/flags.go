@@ -3,61 +3,418 @@ package config
 import (
 	"flag"
 	"fmt"
+	"os"
+	"poster/internal/archive"
+	"poster/internal/auth"
+	"poster/internal/jsonpath"
+	"poster/internal/load"
+	"poster/internal/ratelimit"
+	"poster/internal/report"
+	"poster/internal/retry"
+	"poster/internal/source"
+	"poster/internal/stream"
 	"runtime"
 	"slices"
+	"strings"
+	"time"
 )
 
-const usage = "Использование: go run poster.go [-url=<URL>] [-requests=<имяДиректории>] [-responses=<имяДиректории>] [-timeout=N] [-workers=N] [-log=S]"
-
 type Flags struct {
-	URL          string `doc:"Адрес сервера"`
-	RequestsDir  string `doc:"Директория с запросами json"`
-	ResponsesDir string `doc:"Директория с ответами json"`
-	Timeout      int    `doc:"Max время для ответа"`
-	Workers      int    `doc:"Количество параллельных работников"`
-	Log          string `doc:"Уровень логирования"`
+	Command string `doc:"Подкоманда: run или validate"`
+
+	URL          string            `doc:"Адрес сервера"`
+	Headers      map[string]string `doc:"Общие заголовки запросов"`
+	Auth         auth.Auth         `doc:"Авторизация запросов"`
+	RequestsDir  string            `doc:"Директория с запросами json"`
+	ResponsesDir string            `doc:"Директория с ответами json"`
+	Timeout      int               `doc:"Max время для ответа"`
+	Workers      int               `doc:"Количество параллельных работников"`
+	Log          string            `doc:"Уровень логирования"`
+
+	Drain   time.Duration `doc:"Время на завершение запросов после сигнала остановки"`
+	Journal string        `doc:"Журнал обработанных файлов"`
+	Resume  bool          `doc:"Продолжить прогон по журналу"`
+	Reports []string      `doc:"Файлы отчетов о прогоне"`
+
+	RetryAttempts    int           `doc:"Максимальное число попыток запроса"`
+	RetryBaseDelay   time.Duration `doc:"Задержка перед первым повтором"`
+	RetryMaxDelay    time.Duration `doc:"Максимальная задержка между попытками"`
+	RetryJitter      float64       `doc:"Доля случайного разброса задержки"`
+	RetryStatusCodes []int         `doc:"HTTP статусы для повтора"`
+	RetryNetwork     bool          `doc:"Повторять при сетевых ошибках"`
+
+	RPS     float64            `doc:"Целевая скорость, запросов в секунду"`
+	Burst   int                `doc:"Размер пачки запросов"`
+	HostRPS map[string]float64 `doc:"Скорость по хостам"`
+
+	Duration   time.Duration `doc:"Длительность нагрузочного прогона"`
+	Iterations int           `doc:"Количество проходов по файлам"`
+	Order      string        `doc:"Порядок обхода файлов"`
+	Stages     []load.Stage  `doc:"Этапы нагрузки"`
+	Ramp       string        `doc:"Что наращивается по этапам"`
+	OpenLoop   bool          `doc:"Отправка по расписанию независимо от ответов"`
+
+	Baseline   string   `doc:"Директория эталонных ответов"`
+	Ignore     []string `doc:"JSONPath полей, не участвующих в сравнении"`
+	DiffReport string   `doc:"Файл отчета сравнения с эталоном"`
+
+	RequestSchema  string `doc:"JSON Schema тела запроса"`
+	ResponseSchema string `doc:"JSON Schema тела ответа"`
+
+	Template  bool   `doc:"Подстановка шаблонов в запросы"`
+	Vars      string `doc:"Файл переменных шаблонов запросов"`
+	Scenarios string `doc:"Файл или директория сценариев"`
+
+	Include []string `doc:"Шаблоны отбора файлов запросов"`
+	Exclude []string `doc:"Шаблоны исключения файлов запросов"`
+
+	OutputOrder string `doc:"Порядок вывода ответов в stdout"`
+
+	ConfigFile string            `doc:"Файл конфигурации"`
+	Profile    string            `doc:"Профиль файла конфигурации"`
+	Effective  map[string]string `doc:"Итоговые значения всех параметров по именам флагов"`
+	Sources    map[string]string `doc:"Источники значений, заданных не по умолчанию"`
 }
 
+// MaxWorkers = предел количества воркеров: работа ограничена сетью, а не CPU,
+// поэтому воркеров может быть намного больше ядер, предел лишь отсекает опечатки
+const MaxWorkers = 10000
+
+// Флаги файла конфигурации и его профиля: сами в файле не задаются
+const (
+	configFlag  = "config"
+	profileFlag = "profile"
+)
+
+// validateFlags = флаги подкоманды validate: источник запросов, схемы, переменные и отбор файлов.
+// Остальные параметры run validate берет только из файла конфигурации и окружения, общих с run.
+var validateFlags = []string{configFlag, profileFlag, "url", "requests", "scenarios", "request-schema", "response-schema", "template", "vars", "include", "exclude", "log"}
+
 func parse() (*Flags, error) {
+	command, args := Split(os.Args[1:])
+	if command != CommandRun && command != CommandValidate {
+		return &Flags{}, fmt.Errorf("подкоманда %q не принимает флаги run", command)
+	}
+	usage := Usage(command)
+	flag.CommandLine.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
 	numCPU := runtime.NumCPU()
 
+	configPath := flag.String(configFlag, "", "Файл конфигурации .yaml, .yml, .toml или .json (флаги и переменные POSTER_* важнее файла)")
+	profile := flag.String(profileFlag, "", "Профиль из секции profiles файла конфигурации, например staging")
 	url := flag.String("url", "http://localhost:8080/execute", "Адрес сервера")
-	requestsDir := flag.String("requests", "requests", "Директория с запросами json")
-	responsesDir := flag.String("responses", "responses", "Директория с ответами json")
+	headers := flag.String("headers", "", "Общие заголовки запросов через запятую: X-Api-Key=abc,X-Tenant=42 (запятая в значении экранируется: \\,; заголовки конверта важнее)")
+	authType := flag.String("auth-type", "", "Тип авторизации ('bearer', 'basic', 'none'; по умолчанию - по заданным auth-token или auth-user)")
+	authToken := flag.String("auth-token", "", "Токен авторизации Bearer (лучше задавать переменной POSTER_AUTH_TOKEN)")
+	authUser := flag.String("auth-user", "", "Пользователь авторизации Basic")
+	authPassword := flag.String("auth-password", "", "Пароль авторизации Basic (лучше задавать переменной POSTER_AUTH_PASSWORD)")
+	requestsDir := flag.String("requests", "requests", "Директория с запросами json, архив .tar, .tar.gz, .tgz, .zip или - (NDJSON из stdin)")
+	responsesDir := flag.String("responses", "responses", "Директория с ответами json, архив .tar, .tar.gz, .tgz, .zip или - (NDJSON в stdout)")
 	timeout := flag.Int("timeout", 30, "Max время для ответа")
-	workers := flag.Int("workers", numCPU, "Количество параллельных работников")
+	workers := flag.Int("workers", numCPU, "Количество параллельных работников (по умолчанию - количество ядер, для медленного сервера можно больше)")
+	drain := flag.Duration("drain", 10*time.Second, "Время на завершение отправленных запросов после SIGINT/SIGTERM")
+	journal := flag.String("journal", "journal.jsonl", "Журнал обработанных файлов (рядом с log.json)")
+	resume := flag.Bool("resume", false, "Продолжить прогон: пропустить успешно обработанные файлы с неизменным содержимым")
+	dryRun := flag.Bool("dry-run", false, "Проверить запросы и показать, что и куда было бы отправлено, без отправки (как подкоманда validate)")
+	reports := flag.String("report", "", "Файлы отчетов через запятую, формат по расширению: .json, .csv, .xml (JUnit), .md, .html")
 	log := flag.String("log", "", "Уровень логирования ('', 'stdout', 'debug', 'info', 'warn', 'error')")
+	retryAttempts := flag.Int("retry-attempts", 1, "Максимальное число попыток запроса (1 = без повторов)")
+	retryBase := flag.Duration("retry-base", 500*time.Millisecond, "Задержка перед первым повтором (удваивается с каждой попыткой)")
+	retryMax := flag.Duration("retry-max", 30*time.Second, "Максимальная задержка между попытками (ограничивает и Retry-After)")
+	retryJitter := flag.Float64("retry-jitter", 0.2, "Доля случайного разброса задержки [0..1]")
+	retryStatus := flag.String("retry-status", retry.DefaultStatusCodes, "HTTP статусы для повтора: список и диапазоны через запятую")
+	retryNetwork := flag.Bool("retry-network", true, "Повторять запрос при сетевых ошибках")
+	rps := flag.Float64("rps", 0, "Целевая скорость, запросов в секунду на все воркеры (0 = без ограничения)")
+	burst := flag.Int("burst", 1, "Размер пачки запросов сверх rps (1 = равномерная отправка)")
+	hostRPS := flag.String("host-rps", "", "Скорость по хостам через запятую: api.local=50,*=10 (* - для каждого хоста)")
+	duration := flag.Duration("duration", 0, "Длительность нагрузочного прогона: файлы отправляются по кругу (0 = один проход)")
+	iterations := flag.Int("iterations", 0, "Количество проходов по файлам (0 = один проход, с -duration или -stages - без ограничения)")
+	order := flag.String("order", load.OrderSeq, "Порядок обхода файлов ('seq', 'random')")
+	stages := flag.String("stages", "", "Этапы нагрузки длительность:цель через запятую: 30s:10,1m:50,30s:0")
+	ramp := flag.String("ramp", load.RampRPS, "Что наращивается по этапам ('rps', 'workers')")
+	openLoop := flag.Bool("open-loop", false, "Отправлять по расписанию -rps или -stages независимо от завершения запросов; задержка считается и от времени по расписанию")
+	baseline := flag.String("baseline", "", "Директория эталонных ответов (например, responses прошлого прогона) для сравнения")
+	ignore := flag.String("ignore", "", "JSONPath полей, не участвующих в сравнении, через запятую: $..timestamp,$.items[*].id")
+	diffReport := flag.String("diff", "", "Файл отчета сравнения с эталоном: .json или текст")
+	requestSchema := flag.String("request-schema", "", "JSON Schema тела запроса (draft 2020-12, если в схеме нет $schema): запрос с нарушениями не отправляется")
+	responseSchema := flag.String("response-schema", "", "JSON Schema тела ответа (draft 2020-12, если в схеме нет $schema)")
+	template := flag.Bool("template", false, "Подставлять в запросы переменные и генераторы: ${имя}, ${env.ИМЯ}, ${uuid}, {{.имя}} (без флага запросы отправляются как есть)")
+	vars := flag.String("vars", "", "Файл переменных шаблонов запросов .json, .yaml или .yml: ${имя} и {{.имя}} (включает -template)")
+	include := flag.String("include", "", "Шаблоны файлов запросов через запятую, путь относительно директории запросов: orders/**, re:^v2/")
+	exclude := flag.String("exclude", "", "Шаблоны исключаемых файлов запросов через запятую: **/*.draft.json")
+	outputOrder := flag.String("output-order", stream.OrderInput, "Порядок ответов NDJSON при -responses - ('input' - как во входных данных, 'arrival' - по мере получения)")
+	scenarios := flag.String("scenarios", "", "Файл сценария (.yaml, .yml, .scenario.json) или директория сценариев: шаги выполняются по порядку, значения из ответов подставляются в следующие шаги")
 
-	flag.Parse()
+	if command == CommandValidate {
+		if err := parseValidate(args, usage); err != nil {
+			return &Flags{}, err
+		}
+	} else if err := flag.CommandLine.Parse(args); err != nil {
+		return &Flags{}, err
+	}
+
+	// Значения, не заданные флагами, берутся из окружения и файла конфигурации
+	origin, err := layer(flag.CommandLine, os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, err
+	}
 
+	requestHeaders, err := auth.ParseHeaders(*headers)
+	if err != nil {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("headers: %v", err), "headers")
+	}
+	requestAuth, err := auth.New(*authType, *authToken, *authUser, *authPassword)
+	if err != nil {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("auth: %v", err), "auth-type", "auth-token", "auth-user", "auth-password")
+	}
 	if *requestsDir == "" {
-		fmt.Println(usage)
-		return &Flags{}, fmt.Errorf("пустая директория запросов: %s", *requestsDir)
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("пустая директория запросов: %s", *requestsDir), "requests")
 	}
 	if *responsesDir == "" {
-		fmt.Println(usage)
-		return &Flags{}, fmt.Errorf("пустая директория ответов: %s", *responsesDir)
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("пустая директория ответов: %s", *responsesDir), "responses")
 	}
 	if *timeout <= 0 {
-		fmt.Println(usage)
-		return &Flags{}, fmt.Errorf("timeout=%v должен быть > 0", *timeout)
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("timeout=%v должен быть > 0", *timeout), "timeout")
 	}
-	if *workers < 1 || numCPU < *workers {
-		fmt.Println(usage)
-		return &Flags{}, fmt.Errorf("workers=%v должен быть в диапазоне [1..%v]", *workers, numCPU)
+	if *workers < 1 || MaxWorkers < *workers {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("workers=%v должен быть в диапазоне [1..%v]", *workers, MaxWorkers), "workers")
+	}
+	if *drain < 0 {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("drain=%v должен быть >= 0", *drain), "drain")
+	}
+	if *journal == "" {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("пустой путь журнала: %s", *journal), "journal")
+	}
+	var reportPaths []string
+	for _, path := range splitList(*reports) {
+		if _, err := report.FormatOf(path); err != nil {
+			fmt.Fprintln(flag.CommandLine.Output(), usage)
+			return &Flags{}, origin.wrap(fmt.Errorf("report: %v", err), "report")
+		}
+		reportPaths = append(reportPaths, path)
 	}
 	levels := []string{"", "stdout", "debug", "info", "warn", "error"}
 	if !slices.Contains(levels, *log) {
-		fmt.Println(usage)
-		return &Flags{}, fmt.Errorf("log=%v must be in %v", *log, levels)
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("log=%v must be in %v", *log, levels), "log")
+	}
+	if *retryAttempts < 1 {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("retry-attempts=%v должен быть >= 1", *retryAttempts), "retry-attempts")
+	}
+	if *retryBase <= 0 || *retryMax < *retryBase {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("должно выполняться 0 < retry-base=%v <= retry-max=%v", *retryBase, *retryMax), "retry-base", "retry-max")
+	}
+	if *retryJitter < 0 || 1 < *retryJitter {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("retry-jitter=%v должен быть в диапазоне [0..1]", *retryJitter), "retry-jitter")
+	}
+	retryStatusCodes, err := retry.ParseStatusCodes(*retryStatus)
+	if err != nil {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("retry-status=%v: %v", *retryStatus, err), "retry-status")
+	}
+	if *rps < 0 {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("rps=%v должен быть >= 0", *rps), "rps")
+	}
+	if *burst < 1 {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("burst=%v должен быть >= 1", *burst), "burst")
+	}
+	hostRates, err := ratelimit.ParseHostRates(*hostRPS)
+	if err != nil {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("host-rps: %v", err), "host-rps")
+	}
+	if *duration < 0 {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("duration=%v должен быть >= 0", *duration), "duration")
+	}
+	if *iterations < 0 {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("iterations=%v должен быть >= 0", *iterations), "iterations")
+	}
+	if orders := []string{load.OrderSeq, load.OrderRandom}; !slices.Contains(orders, *order) {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("order=%v должен быть одним из %v", *order, orders), "order")
+	}
+	if ramps := []string{load.RampRPS, load.RampWorkers}; !slices.Contains(ramps, *ramp) {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("ramp=%v должен быть одним из %v", *ramp, ramps), "ramp")
+	}
+	loadStages, err := load.ParseStages(*stages)
+	if err != nil {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("stages: %v", err), "stages")
+	}
+	if *ramp == load.RampWorkers {
+		// Цели этапов становятся количеством воркеров: тот же предел, что и у -workers
+		for _, stage := range loadStages {
+			if load.Workers(stage.Target) > MaxWorkers {
+				fmt.Fprintln(flag.CommandLine.Output(), usage)
+				return &Flags{}, origin.wrap(fmt.Errorf("stages: цель этапа %v при ramp=workers должна быть не больше %v воркеров", stage, MaxWorkers), "stages", "ramp")
+			}
+		}
+	}
+	if len(loadStages) > 0 && *duration > 0 {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("duration и stages несовместимы: длительность задается этапами"), "duration", "stages")
+	}
+	if *resume && (*duration > 0 || *iterations > 1 || len(loadStages) > 0) {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("resume несовместим с нагрузочным прогоном (duration, iterations, stages)"), "resume", "duration", "iterations", "stages")
+	}
+	if *resume && archive.Is(*responsesDir) {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("resume несовместим с архивом ответов: архив прошлого прогона был бы перезаписан"), "resume", "responses")
+	}
+	if *openLoop && len(loadStages) > 0 && *ramp != load.RampRPS {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("open-loop несовместим с ramp=%v: расписание задается скоростью", *ramp), "open-loop", "ramp")
+	}
+	if *openLoop && *rps == 0 && len(loadStages) == 0 {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("open-loop требует rps или stages"), "open-loop", "rps")
+	}
+	if *scenarios != "" && (*resume || *duration > 0 || *iterations > 1 || len(loadStages) > 0 || *openLoop) {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("scenarios несовместим с resume и нагрузочным прогоном (duration, iterations, stages, open-loop)"), "scenarios", "resume", "duration", "iterations", "stages", "open-loop")
+	}
+	if orders := []string{stream.OrderInput, stream.OrderArrival}; !slices.Contains(orders, *outputOrder) {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("output-order=%v должен быть одним из %v", *outputOrder, orders), "output-order")
+	}
+	if *responsesDir == stream.Stdout && (*resume || *scenarios != "" || *duration > 0 || *iterations > 1 || len(loadStages) > 0 || *openLoop) {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("вывод ответов в stdout несовместим с resume, scenarios и нагрузочным прогоном (duration, iterations, stages, open-loop)"), "responses", "resume", "scenarios", "duration", "iterations", "stages", "open-loop")
+	}
+	includePatterns, excludePatterns := splitList(*include), splitList(*exclude)
+	if _, err := source.NewFilter(includePatterns, excludePatterns); err != nil {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(err, "include", "exclude")
+	}
+	ignorePaths := splitList(*ignore)
+	for _, path := range ignorePaths {
+		if _, err := jsonpath.Parse(path); err != nil {
+			fmt.Fprintln(flag.CommandLine.Output(), usage)
+			return &Flags{}, origin.wrap(fmt.Errorf("ignore: %v", err), "ignore")
+		}
+	}
+	if *baseline == "" && (len(ignorePaths) > 0 || *diffReport != "") {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		return &Flags{}, origin.wrap(fmt.Errorf("ignore и diff требуют baseline"), "ignore", "diff", "baseline")
+	}
+
+	// Пробный прогон - та же проверка, что и подкоманда validate
+	if *dryRun {
+		command = CommandValidate
 	}
 
 	return &Flags{
+		Command: command,
+
 		URL:          *url,
+		Headers:      requestHeaders,
+		Auth:         requestAuth,
 		RequestsDir:  *requestsDir,
 		ResponsesDir: *responsesDir,
 		Timeout:      *timeout,
 		Workers:      *workers,
 		Log:          *log,
+
+		Drain:   *drain,
+		Journal: *journal,
+		Resume:  *resume,
+		Reports: reportPaths,
+
+		RetryAttempts:    *retryAttempts,
+		RetryBaseDelay:   *retryBase,
+		RetryMaxDelay:    *retryMax,
+		RetryJitter:      *retryJitter,
+		RetryStatusCodes: retryStatusCodes,
+		RetryNetwork:     *retryNetwork,
+
+		RPS:     *rps,
+		Burst:   *burst,
+		HostRPS: hostRates,
+
+		Duration:   *duration,
+		Iterations: *iterations,
+		Order:      *order,
+		Stages:     loadStages,
+		Ramp:       *ramp,
+		OpenLoop:   *openLoop,
+
+		Baseline:   *baseline,
+		Ignore:     ignorePaths,
+		DiffReport: *diffReport,
+
+		RequestSchema:  *requestSchema,
+		ResponseSchema: *responseSchema,
+
+		Template:  *template || *vars != "",
+		Vars:      *vars,
+		Scenarios: *scenarios,
+
+		Include: includePatterns,
+		Exclude: excludePatterns,
+
+		OutputOrder: *outputOrder,
+
+		ConfigFile: *configPath,
+		Profile:    *profile,
+		Effective:  effective(flag.CommandLine),
+		Sources:    origin,
 	}, nil
 }
+
+// parseValidate разбирает аргументы validate набором флагов validateFlags. Набор разделяет значения
+// с флагами run, а заданные флаги отмечаются в flag.CommandLine, чтобы они были важнее файла и окружения.
+func parseValidate(args []string, usage string) error {
+	fs := flag.NewFlagSet(CommandValidate, flag.CommandLine.ErrorHandling())
+	fs.SetOutput(flag.CommandLine.Output())
+	for _, name := range validateFlags {
+		f := flag.CommandLine.Lookup(name)
+		fs.Var(f.Value, f.Name, f.Usage)
+	}
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if setErr := flag.CommandLine.Set(f.Name, f.Value.String()); setErr != nil && err == nil {
+			err = setErr
+		}
+	})
+	return err
+}
+
+// splitList разбивает список через запятую, пропуская пустые элементы
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -3,9 +3,14 @@ package config
 import (
 	"flag"
 	"os"
+	"path/filepath"
+	"poster/internal/auth"
+	"reflect"
 	"runtime"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestParseFlags тестирует парсинг флагов с различными входными данными
@@ -94,8 +99,8 @@ func TestParseFlags(t *testing.T) {
 			wantLog:     "",
 			shouldFail:  true,
 		}, {
-			name:        "workers больше чем CPU",
-			args:        []string{"cmd", "--requests", "req", "--responses", "res", "--workers", "1000"},
+			name:        "workers больше предела",
+			args:        []string{"cmd", "--requests", "req", "--responses", "res", "--workers", strconv.Itoa(MaxWorkers + 1)},
 			wantURL:     "",
 			wantReqDir:  "",
 			wantResDir:  "",
@@ -289,13 +294,23 @@ func TestParseWorkersRange(t *testing.T) {
 			want:       1,
 			shouldFail: false,
 		}, {
-			name:       "workers = numCPU (максимум)",
+			name:       "workers = numCPU",
 			args:       []string{"cmd", "--requests", "req", "--responses", "res", "--workers", strconv.Itoa(numCPU)},
 			want:       numCPU,
 			shouldFail: false,
 		}, {
-			name:       "workers = numCPU + 1 (больше максимума)",
-			args:       []string{"cmd", "--requests", "req", "--responses", "res", "--workers", strconv.Itoa(numCPU + 1)},
+			name:       "workers больше количества CPU",
+			args:       []string{"cmd", "--requests", "req", "--responses", "res", "--workers", strconv.Itoa(numCPU + 200)},
+			want:       numCPU + 200,
+			shouldFail: false,
+		}, {
+			name:       "workers = MaxWorkers (максимум)",
+			args:       []string{"cmd", "--requests", "req", "--responses", "res", "--workers", strconv.Itoa(MaxWorkers)},
+			want:       MaxWorkers,
+			shouldFail: false,
+		}, {
+			name:       "workers = MaxWorkers + 1 (больше максимума)",
+			args:       []string{"cmd", "--requests", "req", "--responses", "res", "--workers", strconv.Itoa(MaxWorkers + 1)},
 			want:       0,
 			shouldFail: true,
 		},
@@ -328,3 +343,416 @@ func TestParseWorkersRange(t *testing.T) {
 		})
 	}
 }
+
+// TestParseRetryFlags тестирует флаги политики повторов
+func TestParseRetryFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		shouldFail bool
+	}{
+		{"значения по умолчанию", []string{"cmd"}, false},
+		{"все флаги заданы", []string{"cmd", "--retry-attempts", "5", "--retry-base", "100ms", "--retry-max", "2s", "--retry-jitter", "0.5", "--retry-status", "429,502-504", "--retry-network=false"}, false},
+		{"retry-attempts = 0", []string{"cmd", "--retry-attempts", "0"}, true},
+		{"retry-base = 0", []string{"cmd", "--retry-base", "0s"}, true},
+		{"retry-max меньше retry-base", []string{"cmd", "--retry-base", "2s", "--retry-max", "1s"}, true},
+		{"retry-jitter больше 1", []string{"cmd", "--retry-jitter", "1.5"}, true},
+		{"некорректный статус", []string{"cmd", "--retry-status", "429,abc"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			os.Args = test.args
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+			flags, err := parse()
+
+			if test.shouldFail {
+				if err == nil {
+					t.Error("ожидалась ошибка, но не получена")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("не ожидалась ошибка, но получена: %v", err)
+			}
+
+			if len(test.args) == 1 {
+				if flags.RetryAttempts != 1 || flags.RetryBaseDelay != 500*time.Millisecond || flags.RetryMaxDelay != 30*time.Second {
+					t.Errorf("неверные значения по умолчанию: %+v", flags)
+				}
+				if len(flags.RetryStatusCodes) != 101 || !flags.RetryNetwork {
+					t.Errorf("неверные статусы или сетевые повторы по умолчанию: %v, %v", flags.RetryStatusCodes, flags.RetryNetwork)
+				}
+				return
+			}
+
+			if flags.RetryAttempts != 5 {
+				t.Errorf("RetryAttempts = %d, ожидалось 5", flags.RetryAttempts)
+			}
+			if flags.RetryBaseDelay != 100*time.Millisecond || flags.RetryMaxDelay != 2*time.Second {
+				t.Errorf("задержки = %v..%v, ожидалось 100ms..2s", flags.RetryBaseDelay, flags.RetryMaxDelay)
+			}
+			if flags.RetryJitter != 0.5 {
+				t.Errorf("RetryJitter = %v, ожидалось 0.5", flags.RetryJitter)
+			}
+			if len(flags.RetryStatusCodes) != 4 {
+				t.Errorf("RetryStatusCodes = %v, ожидалось 4 статуса", flags.RetryStatusCodes)
+			}
+			if flags.RetryNetwork {
+				t.Error("RetryNetwork = true, ожидалось false")
+			}
+		})
+	}
+}
+
+// TestParseCompareFlags проверяет флаги сравнения с эталоном
+func TestParseCompareFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		shouldFail bool
+	}{
+		{"без сравнения", []string{"cmd"}, false},
+		{"все флаги заданы", []string{"cmd", "--baseline", "old", "--ignore", "$..timestamp, $.items[*].id", "--diff", "diff.txt"}, false},
+		{"некорректный путь", []string{"cmd", "--baseline", "old", "--ignore", "timestamp"}, true},
+		{"ignore без baseline", []string{"cmd", "--ignore", "$.id"}, true},
+		{"diff без baseline", []string{"cmd", "--diff", "diff.json"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			os.Args = test.args
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+			flags, err := parse()
+
+			if test.shouldFail {
+				if err == nil {
+					t.Error("ожидалась ошибка, но не получена")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("не ожидалась ошибка, но получена: %v", err)
+			}
+
+			if len(test.args) == 1 {
+				if flags.Baseline != "" || flags.Ignore != nil || flags.DiffReport != "" {
+					t.Errorf("сравнение должно быть выключено по умолчанию: %+v", flags)
+				}
+				return
+			}
+
+			if flags.Baseline != "old" || flags.DiffReport != "diff.txt" {
+				t.Errorf("Baseline = %q, DiffReport = %q", flags.Baseline, flags.DiffReport)
+			}
+			if len(flags.Ignore) != 2 || flags.Ignore[1] != "$.items[*].id" {
+				t.Errorf("Ignore = %v, ожидалось 2 пути", flags.Ignore)
+			}
+		})
+	}
+}
+
+// TestParseRateFlags проверяет флаги ограничения скорости
+func TestParseRateFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		shouldFail bool
+	}{
+		{"без ограничения", []string{"cmd"}, false},
+		{"все флаги заданы", []string{"cmd", "--rps", "12.5", "--burst", "5", "--host-rps", "api.local=3,*=1"}, false},
+		{"rps < 0", []string{"cmd", "--rps", "-1"}, true},
+		{"burst = 0", []string{"cmd", "--burst", "0"}, true},
+		{"некорректный host-rps", []string{"cmd", "--host-rps", "api.local"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			os.Args = test.args
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+			flags, err := parse()
+
+			if test.shouldFail {
+				if err == nil {
+					t.Error("ожидалась ошибка, но не получена")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("не ожидалась ошибка, но получена: %v", err)
+			}
+
+			if len(test.args) == 1 {
+				if flags.RPS != 0 || flags.Burst != 1 || len(flags.HostRPS) != 0 {
+					t.Errorf("неверные значения по умолчанию: %+v", flags)
+				}
+				return
+			}
+
+			if flags.RPS != 12.5 || flags.Burst != 5 {
+				t.Errorf("RPS = %v, Burst = %v, ожидалось 12.5 и 5", flags.RPS, flags.Burst)
+			}
+			if flags.HostRPS["api.local"] != 3 || flags.HostRPS["*"] != 1 {
+				t.Errorf("HostRPS = %v", flags.HostRPS)
+			}
+		})
+	}
+}
+
+// TestParseLoadFlags проверяет флаги нагрузочного прогона
+func TestParseLoadFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		shouldFail bool
+	}{
+		{"один проход", []string{"cmd"}, false},
+		{"все флаги заданы", []string{"cmd", "--iterations", "3", "--order", "random", "--stages", "10s:5,20s:10", "--ramp", "workers"}, false},
+		{"duration < 0", []string{"cmd", "--duration", "-1s"}, true},
+		{"iterations < 0", []string{"cmd", "--iterations", "-1"}, true},
+		{"неизвестный порядок", []string{"cmd", "--order", "reverse"}, true},
+		{"неизвестный ramp", []string{"cmd", "--ramp", "vus"}, true},
+		{"некорректные этапы", []string{"cmd", "--stages", "10s"}, true},
+		{"duration и stages", []string{"cmd", "--duration", "1m", "--stages", "10s:5"}, true},
+		{"этап больше MaxWorkers", []string{"cmd", "--stages", "10s:5,10s:" + strconv.Itoa(MaxWorkers+1), "--ramp", "workers"}, true},
+		{"resume с нагрузкой", []string{"cmd", "--resume", "--duration", "1m"}, true},
+		{"open-loop без скорости", []string{"cmd", "--open-loop", "--duration", "1m"}, true},
+		{"open-loop с ramp workers", []string{"cmd", "--open-loop", "--stages", "10s:5", "--ramp", "workers"}, true},
+		{"сценарии с нагрузкой", []string{"cmd", "--scenarios", "flows", "--iterations", "2"}, true},
+		{"сценарии с resume", []string{"cmd", "--scenarios", "flows", "--resume"}, true},
+		{"resume с архивом ответов", []string{"cmd", "--resume", "--responses", "out.tar.gz"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			os.Args = test.args
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+			flags, err := parse()
+
+			if test.shouldFail {
+				if err == nil {
+					t.Error("ожидалась ошибка, но не получена")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("не ожидалась ошибка, но получена: %v", err)
+			}
+
+			if len(test.args) == 1 {
+				if flags.Duration != 0 || flags.Iterations != 0 || flags.Order != "seq" || flags.Ramp != "rps" || flags.Stages != nil {
+					t.Errorf("неверные значения по умолчанию: %+v", flags)
+				}
+				return
+			}
+
+			if flags.Iterations != 3 || flags.Order != "random" || flags.Ramp != "workers" {
+				t.Errorf("Iterations = %d, Order = %q, Ramp = %q", flags.Iterations, flags.Order, flags.Ramp)
+			}
+			if len(flags.Stages) != 2 || flags.Stages[1].Duration != 20*time.Second || flags.Stages[1].Target != 10 {
+				t.Errorf("Stages = %v", flags.Stages)
+			}
+		})
+	}
+}
+
+// TestParseOpenLoopFlags проверяет флаг open-loop с расписанием по rps и по этапам
+func TestParseOpenLoopFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"по rps", []string{"cmd", "--open-loop", "--rps", "100", "--duration", "1m"}},
+		{"по этапам", []string{"cmd", "--open-loop", "--stages", "10s:50,20s:100"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			os.Args = test.args
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+			flags, err := parse()
+			if err != nil {
+				t.Fatalf("не ожидалась ошибка, но получена: %v", err)
+			}
+			if !flags.OpenLoop {
+				t.Error("OpenLoop = false, ожидалось true")
+			}
+		})
+	}
+}
+
+// TestParseFilterFlags проверяет шаблоны отбора файлов запросов
+func TestParseFilterFlags(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{"cmd", "--include", "orders/**, users/*.json", "--exclude", "**/*.draft.json"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags, err := parse()
+	if err != nil {
+		t.Fatalf("не ожидалась ошибка, но получена: %v", err)
+	}
+	if !reflect.DeepEqual(flags.Include, []string{"orders/**", "users/*.json"}) || !reflect.DeepEqual(flags.Exclude, []string{"**/*.draft.json"}) {
+		t.Errorf("Include = %v, Exclude = %v", flags.Include, flags.Exclude)
+	}
+
+	for _, args := range [][]string{{"cmd", "--include", "orders/["}, {"cmd", "--exclude", "re:("}} {
+		os.Args = args
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+		if _, err := parse(); err == nil {
+			t.Errorf("%v: ожидалась ошибка", args[1:])
+		}
+	}
+}
+
+// TestParseStreamFlags проверяет запросы из stdin и ответы в stdout
+func TestParseStreamFlags(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{"cmd", "--requests", "-", "--responses", "-", "--output-order", "arrival"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags, err := parse()
+	if err != nil {
+		t.Fatalf("не ожидалась ошибка, но получена: %v", err)
+	}
+	if flags.RequestsDir != "-" || flags.ResponsesDir != "-" || flags.OutputOrder != "arrival" {
+		t.Errorf("RequestsDir = %q, ResponsesDir = %q, OutputOrder = %q", flags.RequestsDir, flags.ResponsesDir, flags.OutputOrder)
+	}
+
+	for _, args := range [][]string{
+		{"cmd", "--output-order", "random"},
+		{"cmd", "--responses", "-", "--resume"},
+		{"cmd", "--responses", "-", "--iterations", "2"},
+		{"cmd", "--responses", "-", "--scenarios", "flows"},
+	} {
+		os.Args = args
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+		if _, err := parse(); err == nil {
+			t.Errorf("%v: ожидалась ошибка", args[1:])
+		}
+	}
+}
+
+// TestParseConfigFile проверяет файл конфигурации, переменные окружения и их проверку
+func TestParseConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "poster.toml")
+	content := "url = \"http://file\"\nworkers = 300\n\n[retry]\nattempts = 0\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	// Некорректное значение из файла: ошибка называет файл
+	os.Args = []string{"cmd", "--config", path}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	_, err := parse()
+	if err == nil || !strings.Contains(err.Error(), "retry-attempts (файл "+path+")") {
+		t.Fatalf("ожидалась ошибка с источником retry-attempts, получено: %v", err)
+	}
+
+	// Флаг важнее окружения, окружение важнее файла
+	t.Setenv("POSTER_CONFIG", path)
+	t.Setenv("POSTER_RETRY_ATTEMPTS", "2")
+	t.Setenv("POSTER_URL", "http://env")
+	os.Args = []string{"cmd", "--workers", "7"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags, err := parse()
+	if err != nil {
+		t.Fatalf("не ожидалась ошибка, но получена: %v", err)
+	}
+	if flags.URL != "http://env" || flags.Workers != 7 || flags.RetryAttempts != 2 {
+		t.Errorf("URL = %q, Workers = %d, RetryAttempts = %d", flags.URL, flags.Workers, flags.RetryAttempts)
+	}
+}
+
+// TestParseAuthFlags проверяет заголовки и авторизацию из файла, окружения и флагов
+func TestParseAuthFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "poster.yaml")
+	content := "headers:\n  X-Tenant: \"42\"\n  Accept: text/html, application/json\n  Cache-Control: no-cache, max-age=0\nauth:\n  token: file-token\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	tests := []struct {
+		name       string
+		args       []string
+		env        map[string]string
+		wantAuth   string // Заголовок Authorization
+		wantTenant string
+		shouldFail bool
+	}{
+		{"файл", []string{"cmd", "--config", path}, nil, "Bearer file-token", "42", false},
+		{"токен из окружения", []string{"cmd", "--config", path}, map[string]string{"POSTER_AUTH_TOKEN": "env-token"}, "Bearer env-token", "42", false},
+		{"флаги basic", []string{"cmd", "--config", path, "--auth-type", "basic", "--auth-user", "u", "--auth-password", "p"}, nil, "Basic dTpw", "42", false},
+		{"заголовки флагом", []string{"cmd", "--headers", "X-Tenant=7", "--auth-type", "none"}, nil, "", "7", false},
+		{"токен и пользователь без типа", []string{"cmd", "--config", path, "--auth-user", "u"}, nil, "", "", true},
+		{"заголовок без имени", []string{"cmd", "--headers", "=1"}, nil, "", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			os.Args = test.args
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+			flags, err := parse()
+			if (err != nil) != test.shouldFail {
+				t.Fatalf("parse() = %v, ожидалась ошибка: %v", err, test.shouldFail)
+			}
+			if test.shouldFail {
+				return
+			}
+			headers := auth.Headers(flags.Headers, flags.Auth)
+			if headers.Get("Authorization") != test.wantAuth || headers.Get("X-Tenant") != test.wantTenant {
+				t.Errorf("Authorization = %q, X-Tenant = %q", headers.Get("Authorization"), headers.Get("X-Tenant"))
+			}
+			if strings.Contains(flags.Effective["auth-token"]+flags.Effective["auth-password"]+flags.Effective["headers"], "token") ||
+				strings.Contains(flags.Effective["headers"], "42") {
+				t.Errorf("секреты в итоговых значениях: %v", flags.Effective)
+			}
+		})
+	}
+
+	os.Args = []string{"cmd", "--config", path}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags, err := parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accept := flags.Headers["Accept"]; accept != "text/html, application/json" {
+		t.Errorf("Accept = %q, запятая в значении из файла должна сохраняться", accept)
+	}
+	if len(flags.Headers) != 3 || flags.Headers["Cache-Control"] != "no-cache, max-age=0" {
+		t.Errorf("заголовки = %v, max-age=0 из файла - часть значения Cache-Control", flags.Headers)
+	}
+}
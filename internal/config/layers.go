@@ -0,0 +1,122 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"poster/internal/auth"
+	"slices"
+	"strings"
+)
+
+// EnvPrefix = префикс переменных окружения: флаг retry-attempts задается POSTER_RETRY_ATTEMPTS
+const EnvPrefix = "POSTER_"
+
+// EnvName возвращает имя переменной окружения для флага
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// origins = источник значения каждого флага: флаг, переменная окружения или файл конфигурации.
+// Флаги со значением по умолчанию в origins не попадают.
+type origins map[string]string
+
+// wrap дополняет ошибку проверки источниками значений из файла и окружения,
+// чтобы было видно, где исправлять: "workers (переменная POSTER_WORKERS): ..."
+func (o origins) wrap(err error, names ...string) error {
+	var sources []string
+	for _, name := range names {
+		if origin, ok := o[name]; ok && !strings.HasPrefix(origin, "флаг") {
+			sources = append(sources, fmt.Sprintf("%s (%s)", name, origin))
+		}
+	}
+	if len(sources) == 0 {
+		return err
+	}
+	return fmt.Errorf("%s: %v", strings.Join(sources, ", "), err)
+}
+
+// layer дополняет разобранные флаги значениями из окружения и файла конфигурации.
+// Порядок старшинства: по умолчанию < файл < профиль файла < окружение < флаги командной строки.
+// Файл и профиль задаются флагами configFlag и profileFlag или переменными POSTER_CONFIG и POSTER_PROFILE.
+// lookupEnv - как os.LookupEnv.
+func layer(fs *flag.FlagSet, lookupEnv func(string) (string, bool)) (origins, error) {
+	origin := make(origins)
+	fs.Visit(func(f *flag.Flag) {
+		origin[f.Name] = "флаг -" + f.Name
+	})
+
+	// Сначала файл и профиль: от них зависят остальные значения
+	for _, name := range []string{configFlag, profileFlag} {
+		if _, set := origin[name]; set {
+			continue
+		}
+		if value, ok := lookupEnv(EnvName(name)); ok {
+			if err := fs.Set(name, value); err != nil {
+				return nil, fmt.Errorf("переменная %s=%q: %v", EnvName(name), value, err)
+			}
+			origin[name] = "переменная " + EnvName(name)
+		}
+	}
+	path := fs.Lookup(configFlag).Value.String()
+	profile := fs.Lookup(profileFlag).Value.String()
+	if profile != "" && path == "" {
+		return nil, fmt.Errorf("профиль %q задан без файла конфигурации", profile)
+	}
+
+	var file, fromProfile map[string]string
+	if path != "" {
+		known := func(name string) bool { return name != configFlag && name != profileFlag && fs.Lookup(name) != nil }
+		var err error
+		if file, fromProfile, err = readFile(path, profile, known); err != nil {
+			return nil, fmt.Errorf("файл конфигурации %s: %v", path, err)
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, set := origin[f.Name]; set || err != nil || f.Name == configFlag || f.Name == profileFlag {
+			return
+		}
+		if value, ok := lookupEnv(EnvName(f.Name)); ok {
+			if setErr := fs.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("переменная %s=%q: %v", EnvName(f.Name), value, setErr)
+			}
+			origin[f.Name] = "переменная " + EnvName(f.Name)
+			return
+		}
+		if value, ok := file[f.Name]; ok {
+			source := "файл " + path
+			if name, ok := fromProfile[f.Name]; ok {
+				source += ", профиль " + name
+			}
+			if setErr := fs.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("%s: %s=%q: %v", source, f.Name, value, setErr)
+			}
+			origin[f.Name] = source
+		}
+	})
+	return origin, err
+}
+
+// secretFlags = флаги с секретами: их значения скрываются в итоговых значениях, которые пишутся в лог
+var secretFlags = []string{"auth-token", "auth-password"}
+
+// effective возвращает итоговые значения всех флагов, кроме файла и профиля.
+// Секреты и значения заголовков скрываются.
+func effective(fs *flag.FlagSet) map[string]string {
+	values := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == configFlag || f.Name == profileFlag {
+			return
+		}
+		value := f.Value.String()
+		switch {
+		case value != "" && slices.Contains(secretFlags, f.Name):
+			value = "***"
+		case f.Name == "headers":
+			value = auth.Mask(value)
+		}
+		values[f.Name] = value
+	})
+	return values
+}
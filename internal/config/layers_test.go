@@ -0,0 +1,261 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"poster/internal/auth"
+	"strings"
+	"testing"
+)
+
+// TestEnvName проверяет имена переменных окружения
+func TestEnvName(t *testing.T) {
+	for name, want := range map[string]string{"url": "POSTER_URL", "retry-attempts": "POSTER_RETRY_ATTEMPTS", "host-rps": "POSTER_HOST_RPS"} {
+		if got := EnvName(name); got != want {
+			t.Errorf("EnvName(%q) = %q, ожидалось %q", name, got, want)
+		}
+	}
+}
+
+// TestLayer проверяет старшинство источников: по умолчанию < файл < окружение < флаги
+func TestLayer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "poster.yaml")
+	if err := os.WriteFile(path, []byte("url: http://file\nworkers: 3\ntimeout: 60\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"POSTER_WORKERS": "5", "POSTER_URL": "http://env"}
+	lookupEnv := func(name string) (string, bool) { v, ok := env[name]; return v, ok }
+
+	fs := flag.NewFlagSet("cmd", flag.ContinueOnError)
+	fs.String(configFlag, "", "")
+	fs.String(profileFlag, "", "")
+	url := fs.String("url", "http://default", "")
+	workers := fs.Int("workers", 1, "")
+	timeout := fs.Int("timeout", 30, "")
+	log := fs.String("log", "", "")
+	if err := fs.Parse([]string{"-config", path, "-url", "http://flag"}); err != nil {
+		t.Fatal(err)
+	}
+
+	origin, err := layer(fs, lookupEnv)
+	if err != nil {
+		t.Fatalf("layer() вернул ошибку: %v", err)
+	}
+	if *url != "http://flag" || *workers != 5 || *timeout != 60 || *log != "" {
+		t.Errorf("url = %q, workers = %d, timeout = %d, log = %q", *url, *workers, *timeout, *log)
+	}
+	wantOrigins := origins{"url": "флаг -url", "workers": "переменная POSTER_WORKERS", "timeout": "файл " + path}
+	for name, want := range wantOrigins {
+		if origin[name] != want {
+			t.Errorf("источник %s = %q, ожидалось %q", name, origin[name], want)
+		}
+	}
+	if _, ok := origin["log"]; ok {
+		t.Error("значение по умолчанию не должно иметь источника")
+	}
+
+	err = origin.wrap(errors.New("timeout=60 должен быть меньше"), "timeout", "url")
+	if want := "timeout (файл " + path + "): timeout=60 должен быть меньше"; err.Error() != want {
+		t.Errorf("wrap() = %q, ожидалось %q", err, want)
+	}
+	if err := origin.wrap(errors.New("ошибка"), "url"); err.Error() != "ошибка" {
+		t.Errorf("ошибка флага не дополняется источником: %q", err)
+	}
+}
+
+// TestLayer_Errors проверяет, что ошибки значений называют источник
+func TestLayer_Errors(t *testing.T) {
+	dir := t.TempDir()
+	badFile := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(badFile, []byte(`{"workers": "много"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configKey := filepath.Join(dir, "self.json")
+	if err := os.WriteFile(configKey, []byte(`{"config": "other.json"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		env  map[string]string
+		want string
+	}{
+		{"значение из файла", badFile, nil, "файл " + badFile + ": workers="},
+		{"значение из окружения", "", map[string]string{"POSTER_WORKERS": "x"}, "переменная POSTER_WORKERS="},
+		{"файл из окружения", "", map[string]string{"POSTER_CONFIG": badFile}, badFile},
+		{"config в файле", configKey, nil, "неизвестный параметр"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("cmd", flag.ContinueOnError)
+			fs.String(configFlag, test.path, "")
+			fs.String(profileFlag, "", "")
+			fs.Int("workers", 1, "")
+			lookupEnv := func(name string) (string, bool) { v, ok := test.env[name]; return v, ok }
+
+			_, err := layer(fs, lookupEnv)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("layer() = %v, ожидалась ошибка с %q", err, test.want)
+			}
+		})
+	}
+}
+
+// TestLayer_Profile проверяет выбор профиля переменной окружения и источники его значений
+func TestLayer_Profile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "poster.toml")
+	content := "workers = 2\n\n[profiles.staging]\nurl = \"https://staging.local\"\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"POSTER_PROFILE": "staging"}
+	lookupEnv := func(name string) (string, bool) { v, ok := env[name]; return v, ok }
+
+	fs := flag.NewFlagSet("cmd", flag.ContinueOnError)
+	fs.String(configFlag, "", "")
+	profile := fs.String(profileFlag, "", "")
+	url := fs.String("url", "http://default", "")
+	fs.Int("workers", 1, "")
+	if err := fs.Parse([]string{"-config", path}); err != nil {
+		t.Fatal(err)
+	}
+
+	origin, err := layer(fs, lookupEnv)
+	if err != nil {
+		t.Fatalf("layer() вернул ошибку: %v", err)
+	}
+	if *profile != "staging" || *url != "https://staging.local" {
+		t.Errorf("profile = %q, url = %q", *profile, *url)
+	}
+	if want := "файл " + path + ", профиль staging"; origin["url"] != want {
+		t.Errorf("источник url = %q, ожидалось %q", origin["url"], want)
+	}
+	if want := "файл " + path; origin["workers"] != want {
+		t.Errorf("источник workers = %q, ожидалось %q", origin["workers"], want)
+	}
+
+	values := effective(fs)
+	if values["url"] != "https://staging.local" || values["workers"] != "2" {
+		t.Errorf("effective() = %v", values)
+	}
+	if _, ok := values[configFlag]; ok {
+		t.Error("effective() не должен содержать файл конфигурации")
+	}
+
+	fs = flag.NewFlagSet("cmd", flag.ContinueOnError)
+	fs.String(configFlag, "", "")
+	fs.String(profileFlag, "staging", "")
+	if _, err := layer(fs, func(string) (string, bool) { return "", false }); err == nil {
+		t.Error("профиль без файла конфигурации должен быть ошибкой")
+	}
+}
+
+// TestLayer_ProfileAuth проверяет старшинство источников авторизации: секция auth профиля заменяет общую
+// целиком, окружение и флаги важнее профиля по отдельным значениям
+func TestLayer_ProfileAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "poster.yaml")
+	content := `auth:
+  token: file-token
+profiles:
+  staging:
+    auth: {user: staging-user, password: staging-password}
+  prod:
+    extends: staging
+    auth: {type: basic, user: prod-user}
+  local:
+    extends: staging
+    auth: {type: none}
+  inherited:
+    extends: staging
+    url: http://inherited
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		want        auth.Auth
+		wantOrigins map[string]string // Источники значений, "" - значение по умолчанию
+	}{
+		{
+			name:        "без профиля",
+			args:        []string{"-config", path},
+			want:        auth.Auth{Type: auth.TypeBearer, Token: "file-token"},
+			wantOrigins: map[string]string{"auth-token": "файл " + path, "auth-user": ""},
+		}, {
+			name: "профиль заменяет токен пользователем",
+			args: []string{"-config", path, "-profile", "staging"},
+			want: auth.Auth{Type: auth.TypeBasic, User: "staging-user", Password: "staging-password"},
+			wantOrigins: map[string]string{
+				"auth-token": "", "auth-user": "файл " + path + ", профиль staging",
+			},
+		}, {
+			name: "профиль заменяет авторизацию предка",
+			args: []string{"-config", path, "-profile", "prod"},
+			want: auth.Auth{Type: auth.TypeBasic, User: "prod-user"},
+			wantOrigins: map[string]string{
+				"auth-type": "файл " + path + ", профиль prod", "auth-password": "",
+			},
+		}, {
+			name: "профиль без auth наследует авторизацию предка",
+			args: []string{"-config", path, "-profile", "inherited"},
+			want: auth.Auth{Type: auth.TypeBasic, User: "staging-user", Password: "staging-password"},
+			wantOrigins: map[string]string{
+				"auth-user": "файл " + path + ", профиль staging",
+			},
+		}, {
+			name:        "профиль отключает авторизацию",
+			args:        []string{"-config", path, "-profile", "local"},
+			want:        auth.Auth{Type: auth.TypeNone},
+			wantOrigins: map[string]string{"auth-token": "", "auth-user": ""},
+		}, {
+			name: "окружение и флаг важнее профиля",
+			args: []string{"-config", path, "-profile", "prod", "-auth-user", "flag-user"},
+			env:  map[string]string{"POSTER_AUTH_PASSWORD": "env-password", "POSTER_AUTH_USER": "env-user"},
+			want: auth.Auth{Type: auth.TypeBasic, User: "flag-user", Password: "env-password"},
+			wantOrigins: map[string]string{
+				"auth-type":     "файл " + path + ", профиль prod",
+				"auth-user":     "флаг -auth-user",
+				"auth-password": "переменная POSTER_AUTH_PASSWORD",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("cmd", flag.ContinueOnError)
+			fs.String(configFlag, "", "")
+			fs.String(profileFlag, "", "")
+			fs.String("url", "", "")
+			kind, token := fs.String("auth-type", "", ""), fs.String("auth-token", "", "")
+			user, password := fs.String("auth-user", "", ""), fs.String("auth-password", "", "")
+			if err := fs.Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+			lookupEnv := func(name string) (string, bool) { v, ok := test.env[name]; return v, ok }
+
+			origin, err := layer(fs, lookupEnv)
+			if err != nil {
+				t.Fatalf("layer() вернул ошибку: %v", err)
+			}
+			got, err := auth.New(*kind, *token, *user, *password)
+			if err != nil {
+				t.Fatalf("auth.New() вернул ошибку: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("авторизация = %+v, ожидалось %+v", got, test.want)
+			}
+			for name, want := range test.wantOrigins {
+				if origin[name] != want {
+					t.Errorf("источник %s = %q, ожидалось %q", name, origin[name], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package deps
+
+import (
+	"context"
+	"fmt"
+	"poster/internal/envelope"
+	"poster/internal/source"
+	"strings"
+	"sync"
+)
+
+// Graph = зависимости запросов из ключа конверта depends_on: запрос отправляется после того,
+// как все его зависимости выполнены без ошибок
+type Graph struct {
+	deps       map[string][]string // Имя запроса -> его зависимости
+	dependents map[string][]string // Имя запроса -> запросы, которые от него зависят
+}
+
+// Build читает depends_on всех запросов и проверяет граф: неизвестные имена и циклы - ошибка.
+// depends_on читается до подстановки шаблона; файлы, которые не читаются или не являются конвертом, зависимостей не имеют.
+func Build(jobs []source.Job) (*Graph, error) {
+	g := &Graph{deps: make(map[string][]string), dependents: make(map[string][]string)}
+	known := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		known[job.Name] = true
+	}
+
+	for _, job := range jobs {
+		data, err := job.Read()
+		if err != nil {
+			continue
+		}
+		env, err := envelope.Parse(data)
+		if err != nil || len(env.DependsOn) == 0 {
+			continue
+		}
+		seen := make(map[string]bool, len(env.DependsOn))
+		for _, dep := range env.DependsOn {
+			if !known[dep] {
+				return nil, fmt.Errorf("%s: неизвестная зависимость %q", job.Name, dep)
+			}
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			g.deps[job.Name] = append(g.deps[job.Name], dep)
+			g.dependents[dep] = append(g.dependents[dep], job.Name)
+		}
+	}
+
+	if cycle := g.cycle(jobs); cycle != nil {
+		return nil, fmt.Errorf("цикл зависимостей: %s", strings.Join(cycle, " -> "))
+	}
+	return g, nil
+}
+
+// Empty проверяет, что зависимостей нет и запросы можно отправлять в любом порядке
+func (g *Graph) Empty() bool {
+	return len(g.deps) == 0
+}
+
+// Deps возвращает зависимости запроса
+func (g *Graph) Deps(name string) []string {
+	return g.deps[name]
+}
+
+// cycle ищет цикл обходом в глубину и возвращает его путь (первое имя повторяется в конце)
+func (g *Graph) cycle(jobs []source.Job) []string {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(jobs))
+	var path []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			for i, step := range path {
+				if step == name {
+					return append(append([]string(nil), path[i:]...), name)
+				}
+			}
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range g.deps[name] {
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, job := range jobs {
+		if cycle := visit(job.Name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// Skip = запрос, пропущенный из-за невыполненной зависимости
+type Skip struct {
+	Job        source.Job
+	Dependency string // Зависимость, которая завершилась ошибкой или сама пропущена
+}
+
+// Scheduler выдает запросы в порядке зависимостей: запрос готов, когда выполнены все его зависимости.
+// Безопасен для вызова из нескольких горутин.
+type Scheduler struct {
+	mu      sync.Mutex
+	graph   *Graph
+	waiting map[string]int // Имя запроса -> количество невыполненных зависимостей
+	jobs    map[string]source.Job
+	ready   []source.Job
+	skipped map[string]bool
+	left    int           // Запросы, которые еще не выданы и не пропущены
+	changed chan struct{} // Закрывается, когда появились готовые запросы или запросы закончились
+}
+
+// Schedule создает планировщик для jobs. Зависимости, которых нет среди jobs (например, уже выполненные
+// в прошлом прогоне при -resume), считаются выполненными.
+func (g *Graph) Schedule(jobs []source.Job) *Scheduler {
+	s := &Scheduler{
+		graph:   g,
+		waiting: make(map[string]int, len(jobs)),
+		jobs:    make(map[string]source.Job, len(jobs)),
+		skipped: make(map[string]bool),
+		left:    len(jobs),
+		changed: make(chan struct{}),
+	}
+	for _, job := range jobs {
+		s.jobs[job.Name] = job
+	}
+	for _, job := range jobs {
+		for _, dep := range g.deps[job.Name] {
+			if _, ok := s.jobs[dep]; ok {
+				s.waiting[job.Name]++
+			}
+		}
+		if s.waiting[job.Name] == 0 {
+			s.ready = append(s.ready, job)
+		}
+	}
+	return s
+}
+
+// Next ждет готовый запрос. false - запросы закончились или отменен ctx.
+func (s *Scheduler) Next(ctx context.Context) (source.Job, bool) {
+	for {
+		s.mu.Lock()
+		if len(s.ready) > 0 {
+			job := s.ready[0]
+			s.ready = s.ready[1:]
+			s.left--
+			s.mu.Unlock()
+			return job, true
+		}
+		if s.left == 0 {
+			s.mu.Unlock()
+			return source.Job{}, false
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return source.Job{}, false
+		case <-changed:
+		}
+	}
+}
+
+// Done отмечает завершение выданного запроса. После успеха зависимые запросы становятся готовыми,
+// после ошибки все зависимые (и зависимые от них) пропускаются и возвращаются.
+func (s *Scheduler) Done(name string, ok bool) []Skip {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var skips []Skip
+	if ok {
+		for _, dependent := range s.graph.dependents[name] {
+			if _, pending := s.jobs[dependent]; !pending || s.skipped[dependent] {
+				continue
+			}
+			if s.waiting[dependent]--; s.waiting[dependent] == 0 {
+				s.ready = append(s.ready, s.jobs[dependent])
+			}
+		}
+	} else {
+		queue := []string{name}
+		for len(queue) > 0 {
+			failed := queue[0]
+			queue = queue[1:]
+			for _, dependent := range s.graph.dependents[failed] {
+				if _, pending := s.jobs[dependent]; !pending || s.skipped[dependent] {
+					continue
+				}
+				s.skipped[dependent] = true
+				s.left--
+				skips = append(skips, Skip{Job: s.jobs[dependent], Dependency: failed})
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return skips
+}
@@ -0,0 +1,146 @@
+package deps
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"poster/internal/source"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// collect создает файлы запросов и собирает задачи
+func collect(t *testing.T, files map[string]string) []source.Job {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jobs, err := source.Collect(dir, source.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jobs
+}
+
+// TestBuild_Errors проверяет неизвестные зависимости и циклы
+func TestBuild_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"неизвестная зависимость", map[string]string{
+			"a.json": `{"url": "/a", "depends_on": ["missing.json"]}`,
+		}, `a.json: неизвестная зависимость "missing.json"`},
+		{"зависимость от себя", map[string]string{
+			"a.json": `{"url": "/a", "depends_on": ["a.json"]}`,
+		}, "цикл зависимостей: a.json -> a.json"},
+		{"цикл", map[string]string{
+			"a.json": `{"url": "/a", "depends_on": ["c.json"]}`,
+			"b.json": `{"url": "/b", "depends_on": ["a.json"]}`,
+			"c.json": `{"url": "/c", "depends_on": ["b.json"]}`,
+			"d.json": `{"url": "/d"}`,
+		}, "цикл зависимостей: a.json -> c.json -> b.json -> a.json"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Build(collect(t, test.files))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Build() = %v, ожидалась ошибка %q", err, test.want)
+			}
+		})
+	}
+}
+
+// TestScheduler проверяет порядок выдачи и пропуск зависимых от ошибочного запроса
+func TestScheduler(t *testing.T) {
+	jobs := collect(t, map[string]string{
+		"seed.json":    `{"url": "/seed"}`,
+		"users.json":   `{"url": "/users", "depends_on": ["seed.json"]}`,
+		"orders.json":  `{"url": "/orders", "depends_on": ["seed.json", "users.json"]}`,
+		"report.json":  `{"url": "/report", "depends_on": ["orders.json"]}`,
+		"plain.json":   `{"a": 1}`,
+		"broken.json":  `{"url": `,
+		"audit.json":   `{"url": "/audit", "depends_on": ["plain.json"]}`,
+		"summary.json": `{"url": "/summary", "depends_on": ["users.json", "audit.json"]}`,
+	})
+	g, err := Build(jobs)
+	if err != nil {
+		t.Fatalf("Build() вернул ошибку: %v", err)
+	}
+	if g.Empty() || !reflect.DeepEqual(g.Deps("orders.json"), []string{"seed.json", "users.json"}) {
+		t.Fatalf("зависимости orders.json = %v", g.Deps("orders.json"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s := g.Schedule(jobs)
+	next := func() string {
+		job, ok := s.Next(ctx)
+		if !ok {
+			return ""
+		}
+		return job.Name
+	}
+
+	// Сначала готовы запросы без зависимостей, в порядке сбора
+	var first []string
+	for i := 0; i < 3; i++ {
+		first = append(first, next())
+	}
+	if !reflect.DeepEqual(first, []string{"broken.json", "plain.json", "seed.json"}) {
+		t.Fatalf("первые запросы = %v", first)
+	}
+	s.Done("broken.json", false)
+	s.Done("plain.json", true)
+	s.Done("seed.json", true)
+	if got := []string{next(), next()}; !reflect.DeepEqual(got, []string{"audit.json", "users.json"}) {
+		t.Fatalf("после seed и plain = %v", got)
+	}
+	s.Done("audit.json", true)
+
+	// Ошибка users.json пропускает orders.json, а через него report.json; summary.json тоже зависит от users.json
+	skips := s.Done("users.json", false)
+	var skipped []string
+	for _, skip := range skips {
+		skipped = append(skipped, skip.Job.Name+"<-"+skip.Dependency)
+	}
+	want := []string{"orders.json<-users.json", "summary.json<-users.json", "report.json<-orders.json"}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Errorf("пропущены %v, ожидалось %v", skipped, want)
+	}
+	if name := next(); name != "" {
+		t.Errorf("после пропуска выдан %s, ожидалось завершение", name)
+	}
+}
+
+// TestScheduler_Resume проверяет, что зависимости вне списка задач считаются выполненными
+func TestScheduler_Resume(t *testing.T) {
+	jobs := collect(t, map[string]string{
+		"seed.json":  `{"url": "/seed"}`,
+		"users.json": `{"url": "/users", "depends_on": ["seed.json"]}`,
+	})
+	g, err := Build(jobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := g.Schedule(jobs[1:])
+	job, ok := s.Next(context.Background())
+	if !ok || job.Name != "users.json" {
+		t.Errorf("Next() = %s, %v", job.Name, ok)
+	}
+
+	// Ожидание готового запроса прерывается отменой
+	s = g.Schedule(jobs)
+	s.Next(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, ok := s.Next(ctx); ok {
+		t.Error("Next() после отмены должен вернуть false")
+	}
+}
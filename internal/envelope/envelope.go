@@ -0,0 +1,200 @@
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Envelope = описание HTTP запроса: метод, адрес, заголовки, параметры и тело
+type Envelope struct {
+	Method  string            `json:"method,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Query   map[string]any    `json:"query,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+	Expect  json.RawMessage   `json:"expect,omitempty"` // Ожидания к ответу (см. пакет expect)
+
+	DependsOn []string `json:"depends_on,omitempty"` // Запросы, которые должны выполниться раньше (см. пакет deps)
+
+	Plain bool `json:"-"` // Файл содержит только тело запроса, а не конверт
+}
+
+// keys = допустимые ключи верхнего уровня конверта
+var keys = map[string]bool{
+	"method":  true,
+	"url":     true,
+	"headers": true,
+	"query":   true,
+	"body":    true,
+	"expect":  true,
+
+	"depends_on": true,
+}
+
+// Parse разбирает содержимое запроса.
+// Конвертом считается JSON объект, у которого есть "method" или "url",
+// а все ключи верхнего уровня входят в схему конверта.
+// Остальные данные отправляются как тело POST запроса.
+func Parse(data []byte) (*Envelope, error) {
+	if !IsEnvelope(data) {
+		return &Envelope{Method: http.MethodPost, Body: data, Plain: true}, nil
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("разбор конверта: %v", err)
+	}
+
+	env.Method = strings.ToUpper(strings.TrimSpace(env.Method))
+	if env.Method == "" {
+		env.Method = http.MethodPost
+	}
+	if strings.ContainsAny(env.Method, " \t\r\n") {
+		return nil, fmt.Errorf("некорректный метод: %q", env.Method)
+	}
+	if string(env.Body) == "null" {
+		env.Body = nil
+	}
+	for key, value := range env.Query {
+		if _, err := queryValues(value); err != nil {
+			return nil, fmt.Errorf("параметр %q: %v", key, err)
+		}
+	}
+	return &env, nil
+}
+
+// IsEnvelope проверяет, похожи ли данные на конверт запроса
+func IsEnvelope(data []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return false
+	}
+	_, hasMethod := fields["method"]
+	_, hasURL := fields["url"]
+	if !hasMethod && !hasURL {
+		return false
+	}
+	for key := range fields {
+		if !keys[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// ResolveURL возвращает адрес запроса: относительный url разрешается относительно base
+func (e *Envelope) ResolveURL(base string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("базовый адрес %q: %v", base, err)
+	}
+
+	target := baseURL
+	if e.URL != "" {
+		ref, err := url.Parse(e.URL)
+		if err != nil {
+			return "", fmt.Errorf("адрес %q: %v", e.URL, err)
+		}
+		target = baseURL.ResolveReference(ref)
+	}
+
+	if len(e.Query) > 0 {
+		query := target.Query()
+		names := make([]string, 0, len(e.Query))
+		for name := range e.Query {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			values, _ := queryValues(e.Query[name])
+			for _, value := range values {
+				query.Add(name, value)
+			}
+		}
+		target.RawQuery = query.Encode()
+	}
+
+	return target.String(), nil
+}
+
+// NewRequest создает HTTP запрос по конверту. headers - общие заголовки конфигурации:
+// они важнее заголовков по умолчанию, а заголовки конверта важнее их.
+func (e *Envelope) NewRequest(ctx context.Context, base string, headers http.Header) (*http.Request, error) {
+	target, err := e.ResolveURL(base)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if len(e.Body) > 0 {
+		body = bytes.NewReader(e.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, e.Method, target, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Заголовки по умолчанию, конверт может их переопределить
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, values := range headers {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	for name, value := range e.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+
+	return req, nil
+}
+
+// queryValues приводит значение параметра к списку строк
+func queryValues(value any) ([]string, error) {
+	switch v := value.(type) {
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := queryValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	default:
+		s, err := queryValue(v)
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+}
+
+// queryValue приводит скалярное значение параметра к строке
+func queryValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("ожидалась строка, число, bool или массив, получено %T", value)
+	}
+}
@@ -0,0 +1,142 @@
+package envelope
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// TestParse_Detection проверяет распознавание конверта
+func TestParse_Detection(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantPlain bool
+		wantErr   bool
+	}{
+		{"обычное тело", `{"query":"select 1"}`, true, false},
+		{"массив", `[1,2,3]`, true, false},
+		{"конверт с методом", `{"method":"put","body":{"a":1}}`, false, false},
+		{"конверт с адресом", `{"url":"/users"}`, false, false},
+		{"конверт с ожиданиями", `{"url":"/users","expect":{"status":201}}`, false, false},
+		{"конверт с зависимостями", `{"url":"/users","depends_on":["seed.json"]}`, false, false},
+		{"некорректные зависимости", `{"url":"/users","depends_on":"seed.json"}`, false, true},
+		{"посторонние ключи", `{"url":"/users","name":"x"}`, true, false},
+		{"некорректные заголовки", `{"url":"/users","headers":[1]}`, false, true},
+		{"некорректный параметр", `{"url":"/users","query":{"a":{"b":1}}}`, false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env, err := Parse([]byte(test.data))
+			if test.wantErr {
+				if err == nil {
+					t.Error("ожидалась ошибка, но не получена")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("не ожидалась ошибка: %v", err)
+			}
+			if env.Plain != test.wantPlain {
+				t.Errorf("Plain = %v, ожидалось %v", env.Plain, test.wantPlain)
+			}
+			if env.Method == "" {
+				t.Error("метод должен быть заполнен")
+			}
+		})
+	}
+}
+
+// TestResolveURL проверяет построение адреса относительно базового
+func TestResolveURL(t *testing.T) {
+	base := "http://localhost:8080/execute"
+	tests := []struct {
+		name string
+		env  Envelope
+		want string
+	}{
+		{"пустой адрес", Envelope{}, "http://localhost:8080/execute"},
+		{"абсолютный путь", Envelope{URL: "/api/users"}, "http://localhost:8080/api/users"},
+		{"относительный путь", Envelope{URL: "users/1"}, "http://localhost:8080/users/1"},
+		{"полный адрес", Envelope{URL: "https://example.com/x?a=1"}, "https://example.com/x?a=1"},
+		{
+			"параметры",
+			Envelope{URL: "/search?a=1", Query: map[string]any{"q": "go", "n": 10.0, "tag": []any{"x", "y"}}},
+			"http://localhost:8080/search?a=1&n=10&q=go&tag=x&tag=y",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.env.ResolveURL(base)
+			if err != nil {
+				t.Fatalf("не ожидалась ошибка: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("ResolveURL() = %q, ожидалось %q", got, test.want)
+			}
+		})
+	}
+}
+
+// TestNewRequest проверяет метод, заголовки и тело запроса
+func TestNewRequest(t *testing.T) {
+	env, err := Parse([]byte(`{"method":"PUT","url":"/items/7","headers":{"Authorization":"Bearer t","Accept":"text/plain"},"body":{"name":"x"}}`))
+	if err != nil {
+		t.Fatalf("Parse() вернул ошибку: %v", err)
+	}
+
+	headers := http.Header{"Authorization": {"Basic x"}, "Accept": {"application/xml"}, "X-Tenant": {"42"}}
+	req, err := env.NewRequest(context.Background(), "http://localhost:8080/execute", headers)
+	if err != nil {
+		t.Fatalf("NewRequest() вернул ошибку: %v", err)
+	}
+
+	if req.Method != http.MethodPut {
+		t.Errorf("Method = %q, ожидалось %q", req.Method, http.MethodPut)
+	}
+	if req.URL.String() != "http://localhost:8080/items/7" {
+		t.Errorf("URL = %q", req.URL.String())
+	}
+	if req.Header.Get("Authorization") != "Bearer t" {
+		t.Errorf("Authorization = %q, конверт должен переопределять общие заголовки", req.Header.Get("Authorization"))
+	}
+	if req.Header.Get("X-Tenant") != "42" {
+		t.Errorf("общий заголовок X-Tenant не установлен")
+	}
+	if req.Header.Get("Accept") != "text/plain" {
+		t.Errorf("Accept = %q, конверт должен переопределять заголовки по умолчанию", req.Header.Get("Accept"))
+	}
+	if req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q, ожидалось application/json", req.Header.Get("Content-Type"))
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != `{"name":"x"}` {
+		t.Errorf("тело = %q, ожидалось %q", body, `{"name":"x"}`)
+	}
+}
+
+// TestNewRequest_NoBody проверяет запрос без тела
+func TestNewRequest_NoBody(t *testing.T) {
+	env, err := Parse([]byte(`{"method":"GET","url":"/items","body":null}`))
+	if err != nil {
+		t.Fatalf("Parse() вернул ошибку: %v", err)
+	}
+
+	req, err := env.NewRequest(context.Background(), "http://localhost:8080/", http.Header{"Accept": {"text/csv"}})
+	if err != nil {
+		t.Fatalf("NewRequest() вернул ошибку: %v", err)
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		t.Error("запрос без тела не должен содержать body")
+	}
+	if req.Header.Get("Content-Type") != "" {
+		t.Error("Content-Type не должен устанавливаться без тела")
+	}
+	if req.Header.Get("Accept") != "text/csv" {
+		t.Errorf("Accept = %q, общие заголовки должны переопределять заголовки по умолчанию", req.Header.Get("Accept"))
+	}
+}
@@ -0,0 +1,307 @@
+package expect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"poster/internal/jsonpath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SidecarSuffix = суффикс файла ожиданий рядом с запросом: name.json -> name.expect.json
+const SidecarSuffix = ".expect.json"
+
+// Expectation = ожидания к ответу на запрос
+type Expectation struct {
+	Status     Codes             `json:"status,omitempty"`      // Допустимые HTTP статусы (по умолчанию 2xx)
+	JSONPath   []Check           `json:"jsonpath,omitempty"`    // Проверки тела ответа
+	Headers    map[string]string `json:"headers,omitempty"`     // Заголовок -> регулярное выражение
+	MaxLatency Duration          `json:"max_latency,omitempty"` // Максимальное время ответа
+
+	headers  map[string]*regexp.Regexp
+	compiled []compiledCheck
+}
+
+// Check = проверка значения тела ответа по JSONPath
+type Check struct {
+	Path    string          `json:"path"`
+	Equals  json.RawMessage `json:"equals,omitempty"`  // Значение равно JSON значению
+	Matches string          `json:"matches,omitempty"` // Строковое представление подходит под регулярное выражение
+	Exists  *bool           `json:"exists,omitempty"`  // Путь существует (или отсутствует при false)
+}
+
+type compiledCheck struct {
+	Check
+	path    *jsonpath.Path
+	equals  any
+	matches *regexp.Regexp
+}
+
+// Codes = список HTTP статусов, допускает одно число: "status": 201
+type Codes []int
+
+// UnmarshalJSON разбирает число или массив чисел
+func (c *Codes) UnmarshalJSON(data []byte) error {
+	var code int
+	if err := json.Unmarshal(data, &code); err == nil {
+		*c = Codes{code}
+		return nil
+	}
+	var codes []int
+	if err := json.Unmarshal(data, &codes); err != nil {
+		return fmt.Errorf("status: ожидалось число или массив чисел")
+	}
+	*c = codes
+	return nil
+}
+
+// Duration = длительность: строка "500ms" или число миллисекунд
+type Duration time.Duration
+
+// UnmarshalJSON разбирает строку длительности или число миллисекунд
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var ms float64
+	if err := json.Unmarshal(data, &ms); err == nil {
+		*d = Duration(ms * float64(time.Millisecond))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("max_latency: ожидалась строка или число миллисекунд")
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("max_latency: %v", err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Response = ответ сервера для проверки ожиданий
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Duration   time.Duration
+}
+
+// Error = список непройденных проверок
+type Error struct {
+	Failures []string
+}
+
+func (e *Error) Error() string {
+	return "проверки не пройдены: " + strings.Join(e.Failures, "; ")
+}
+
+// Parse разбирает и компилирует ожидания
+func Parse(data []byte) (*Expectation, error) {
+	var e Expectation
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&e); err != nil {
+		return nil, fmt.Errorf("разбор ожиданий: %v", err)
+	}
+	if err := e.compile(); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// compile подготавливает регулярные выражения и пути
+func (e *Expectation) compile() error {
+	e.headers = make(map[string]*regexp.Regexp, len(e.Headers))
+	for name, pattern := range e.Headers {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("заголовок %s: %v", name, err)
+		}
+		e.headers[name] = re
+	}
+
+	e.compiled = make([]compiledCheck, 0, len(e.JSONPath))
+	for _, check := range e.JSONPath {
+		c := compiledCheck{Check: check}
+		path, err := jsonpath.Parse(check.Path)
+		if err != nil {
+			return err
+		}
+		c.path = path
+		if check.Equals != nil {
+			if err := json.Unmarshal(check.Equals, &c.equals); err != nil {
+				return fmt.Errorf("%s: equals: %v", check.Path, err)
+			}
+		}
+		if check.Matches != "" {
+			if c.matches, err = regexp.Compile(check.Matches); err != nil {
+				return fmt.Errorf("%s: matches: %v", check.Path, err)
+			}
+		}
+		if check.Equals == nil && check.Matches == "" && check.Exists == nil {
+			return fmt.Errorf("%s: нужна хотя бы одна проверка: equals, matches или exists", check.Path)
+		}
+		e.compiled = append(e.compiled, c)
+	}
+	return nil
+}
+
+// HasStatus проверяет, заданы ли ожидаемые статусы (иначе успехом считается 2xx)
+func (e *Expectation) HasStatus() bool {
+	return e != nil && len(e.Status) > 0
+}
+
+// Accepts проверяет, что статус явно указан в ожиданиях
+func (e *Expectation) Accepts(statusCode int) bool {
+	return e.HasStatus() && statusCode > 0 && slices.Contains(e.Status, statusCode)
+}
+
+// Verify проверяет ответ и возвращает *Error со всеми непройденными проверками
+func (e *Expectation) Verify(resp Response) error {
+	if e == nil {
+		return nil
+	}
+	var failures []string
+
+	if len(e.Status) > 0 && !slices.Contains(e.Status, resp.StatusCode) {
+		failures = append(failures, fmt.Sprintf("статус %d, ожидалось %v", resp.StatusCode, []int(e.Status)))
+	}
+
+	if e.MaxLatency > 0 && resp.Duration > time.Duration(e.MaxLatency) {
+		failures = append(failures, fmt.Sprintf("время ответа %v больше %v", resp.Duration, time.Duration(e.MaxLatency)))
+	}
+
+	names := make([]string, 0, len(e.headers))
+	for name := range e.headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		value := resp.Header.Get(name)
+		if !e.headers[name].MatchString(value) {
+			failures = append(failures, fmt.Sprintf("заголовок %s=%q не подходит под %q", name, value, e.Headers[name]))
+		}
+	}
+
+	if len(e.compiled) > 0 {
+		var doc any
+		if err := json.Unmarshal(resp.Body, &doc); err != nil {
+			failures = append(failures, fmt.Sprintf("тело ответа не JSON: %v", err))
+		} else {
+			for _, check := range e.compiled {
+				failures = append(failures, check.verify(doc)...)
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return &Error{Failures: failures}
+	}
+	return nil
+}
+
+// verify выполняет проверку JSONPath
+func (c compiledCheck) verify(doc any) []string {
+	value, found := c.path.Get(doc)
+	var failures []string
+
+	if c.Exists != nil && found != *c.Exists {
+		if found {
+			failures = append(failures, fmt.Sprintf("%s существует, ожидалось отсутствие", c.Path))
+		} else {
+			failures = append(failures, fmt.Sprintf("%s не найден", c.Path))
+		}
+	}
+	if c.Equals != nil {
+		if !found {
+			failures = append(failures, fmt.Sprintf("%s не найден, ожидалось %s", c.Path, c.Equals))
+		} else if !reflect.DeepEqual(value, c.equals) {
+			actual, _ := json.Marshal(value)
+			failures = append(failures, fmt.Sprintf("%s = %s, ожидалось %s", c.Path, actual, c.Equals))
+		}
+	}
+	if c.matches != nil {
+		text := stringify(value)
+		if !found {
+			failures = append(failures, fmt.Sprintf("%s не найден, ожидалось совпадение с %q", c.Path, c.Matches))
+		} else if !c.matches.MatchString(text) {
+			failures = append(failures, fmt.Sprintf("%s = %q не подходит под %q", c.Path, text, c.Matches))
+		}
+	}
+	return failures
+}
+
+// stringify приводит значение к строке: строки как есть, остальное в JSON
+func stringify(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// SidecarPath возвращает путь файла ожиданий для файла запроса: dir/name.json -> dir/name.expect.json
+func SidecarPath(requestPath string) string {
+	return strings.TrimSuffix(requestPath, filepath.Ext(requestPath)) + SidecarSuffix
+}
+
+// IsSidecar проверяет, является ли файл файлом ожиданий
+func IsSidecar(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), SidecarSuffix)
+}
+
+// Loader загружает файлы ожиданий с кэшированием (один файл на весь JSONL)
+type Loader struct {
+	mu    sync.Mutex
+	cache map[string]*Expectation
+	errs  map[string]error
+}
+
+// NewLoader создает загрузчик файлов ожиданий
+func NewLoader() *Loader {
+	return &Loader{
+		cache: make(map[string]*Expectation),
+		errs:  make(map[string]error),
+	}
+}
+
+// For возвращает ожидания к ответу: ожидания из конверта (inline) важнее файла name.expect.json
+func (l *Loader) For(inline json.RawMessage, requestPath string) (*Expectation, error) {
+	if inline != nil {
+		return Parse(inline)
+	}
+	return l.Sidecar(requestPath)
+}
+
+// Sidecar загружает ожидания для файла запроса. Отсутствие файла ожиданий - не ошибка (nil, nil).
+func (l *Loader) Sidecar(requestPath string) (*Expectation, error) {
+	path := SidecarPath(requestPath)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.cache[path]; ok {
+		return e, l.errs[path]
+	}
+
+	var e *Expectation
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		err = nil
+	case err == nil:
+		e, err = Parse(data)
+		if err != nil {
+			err = fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	l.cache[path] = e
+	l.errs[path] = err
+	return e, err
+}
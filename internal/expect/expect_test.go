@@ -0,0 +1,154 @@
+package expect
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParse проверяет разбор ожиданий
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"статус числом", `{"status": 201}`, false},
+		{"статус массивом", `{"status": [200, 204]}`, false},
+		{"задержка строкой", `{"max_latency": "500ms"}`, false},
+		{"задержка числом", `{"max_latency": 250}`, false},
+		{"проверки", `{"jsonpath": [{"path": "$.id", "exists": true}]}`, false},
+		{"неизвестное поле", `{"statuses": 200}`, true},
+		{"некорректный статус", `{"status": "ok"}`, true},
+		{"некорректная задержка", `{"max_latency": "soon"}`, true},
+		{"некорректный путь", `{"jsonpath": [{"path": "id", "exists": true}]}`, true},
+		{"проверка без условия", `{"jsonpath": [{"path": "$.id"}]}`, true},
+		{"некорректный regexp", `{"headers": {"X-Id": "("}}`, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(test.data))
+			if (err != nil) != test.wantErr {
+				t.Errorf("Parse() ошибка = %v, ожидалась ошибка %v", err, test.wantErr)
+			}
+		})
+	}
+
+	e, _ := Parse([]byte(`{"status": 201, "max_latency": 250}`))
+	if len(e.Status) != 1 || e.Status[0] != 201 {
+		t.Errorf("Status = %v, ожидалось [201]", e.Status)
+	}
+	if time.Duration(e.MaxLatency) != 250*time.Millisecond {
+		t.Errorf("MaxLatency = %v, ожидалось 250ms", time.Duration(e.MaxLatency))
+	}
+}
+
+// TestVerify проверяет проверку ответа
+func TestVerify(t *testing.T) {
+	e, err := Parse([]byte(`{
+		"status": [200, 201],
+		"max_latency": "100ms",
+		"headers": {"Content-Type": "^application/json"},
+		"jsonpath": [
+			{"path": "$.id", "equals": 7},
+			{"path": "$.user.name", "matches": "^pos"},
+			{"path": "$.items[*].id", "exists": true},
+			{"path": "$.error", "exists": false}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("Parse() вернул ошибку: %v", err)
+	}
+
+	ok := Response{
+		StatusCode: 201,
+		Header:     http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+		Body:       []byte(`{"id": 7, "user": {"name": "poster"}, "items": [{"id": 1}]}`),
+		Duration:   20 * time.Millisecond,
+	}
+	if err := e.Verify(ok); err != nil {
+		t.Errorf("Verify() вернул ошибку для подходящего ответа: %v", err)
+	}
+
+	bad := Response{
+		StatusCode: 500,
+		Header:     http.Header{"Content-Type": {"text/plain"}},
+		Body:       []byte(`{"id": "7", "user": {"name": "other"}, "error": "boom"}`),
+		Duration:   time.Second,
+	}
+	err = e.Verify(bad)
+	var verr *Error
+	if !errors.As(err, &verr) {
+		t.Fatalf("Verify() = %v, ожидалась *Error", err)
+	}
+	if len(verr.Failures) != 7 {
+		t.Errorf("непройденных проверок %d, ожидалось 7: %v", len(verr.Failures), verr.Failures)
+	}
+	for _, want := range []string{"статус 500", "время ответа", "Content-Type", `$.id = "7"`, "$.user.name", "$.items[*].id не найден", "$.error существует"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("ошибка не содержит %q: %v", want, err)
+		}
+	}
+
+	notJSON := ok
+	notJSON.Body = []byte("<html>")
+	if err := e.Verify(notJSON); err == nil || !strings.Contains(err.Error(), "не JSON") {
+		t.Errorf("Verify() = %v, ожидалась ошибка разбора тела", err)
+	}
+
+	var none *Expectation
+	if err := none.Verify(bad); err != nil || none.HasStatus() || none.Accepts(500) {
+		t.Error("отсутствующие ожидания не должны давать ошибок")
+	}
+	if !e.Accepts(201) || e.Accepts(500) || e.Accepts(0) {
+		t.Error("Accepts() должен принимать только статусы из ожиданий")
+	}
+}
+
+// TestSidecar проверяет загрузку файлов ожиданий рядом с запросами
+func TestSidecar(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.expect.json"), []byte(`{"status": 201}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.expect.json"), []byte(`{`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := SidecarPath(filepath.Join(dir, "dump.jsonl")); got != filepath.Join(dir, "dump.expect.json") {
+		t.Errorf("SidecarPath() = %s", got)
+	}
+	if !IsSidecar("a.Expect.json") || IsSidecar("a.json") {
+		t.Error("IsSidecar() определяет файлы ожиданий неверно")
+	}
+
+	loader := NewLoader()
+	e, err := loader.Sidecar(filepath.Join(dir, "a.json"))
+	if err != nil || !e.HasStatus() {
+		t.Errorf("Sidecar(a.json) = %v, %v", e, err)
+	}
+	again, _ := loader.Sidecar(filepath.Join(dir, "a.jsonl"))
+	if again != e {
+		t.Error("повторная загрузка должна брать ожидания из кэша")
+	}
+	if e, err := loader.Sidecar(filepath.Join(dir, "missing.json")); e != nil || err != nil {
+		t.Errorf("отсутствующий файл ожиданий: %v, %v", e, err)
+	}
+	if _, err := loader.Sidecar(filepath.Join(dir, "bad.json")); err == nil {
+		t.Error("ожидалась ошибка для некорректного файла ожиданий")
+	}
+
+	// Ожидания из конверта важнее файла рядом
+	inline, err := loader.For([]byte(`{"status": 204}`), filepath.Join(dir, "a.json"))
+	if err != nil || inline == e {
+		t.Errorf("For(inline) = %v, %v", inline, err)
+	}
+	if fromFile, _ := loader.For(nil, filepath.Join(dir, "a.json")); fromFile != e {
+		t.Error("без ожиданий в конверте For() должен брать файл рядом")
+	}
+}
@@ -0,0 +1,20 @@
+package fdlimit
+
+// Reserve = дескрипторы сверх воркеров: stdio, лог, журнал, отчеты, файлы ожиданий
+const Reserve = 64
+
+// Need возвращает оценку количества открытых файлов для workers воркеров:
+// у каждого воркера одновременно открыты соединение и файл ответа
+func Need(workers int) uint64 {
+	return uint64(max(workers, 0))*2 + Reserve
+}
+
+// Check сравнивает потребность workers воркеров с мягким лимитом открытых файлов.
+// Возвращает лимит и true, если лимит известен и его не хватит.
+func Check(workers int) (soft uint64, exceeded bool) {
+	soft, _, ok := Limit()
+	if !ok {
+		return 0, false
+	}
+	return soft, Need(workers) > soft
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package fdlimit
+
+// Limit на платформах без ulimit лимит не известен
+func Limit() (soft, hard uint64, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,39 @@
+package fdlimit
+
+import "testing"
+
+// TestNeed проверяет оценку количества открытых файлов
+func TestNeed(t *testing.T) {
+	tests := []struct {
+		workers int
+		want    uint64
+	}{
+		{0, Reserve},
+		{1, Reserve + 2},
+		{500, Reserve + 1000},
+		{-1, Reserve},
+	}
+	for _, test := range tests {
+		if got := Need(test.workers); got != test.want {
+			t.Errorf("Need(%d) = %d, ожидалось %d", test.workers, got, test.want)
+		}
+	}
+}
+
+// TestCheck проверяет сравнение с лимитом открытых файлов
+func TestCheck(t *testing.T) {
+	soft, _, ok := Limit()
+	if !ok {
+		t.Skip("лимит открытых файлов не известен на этой платформе")
+	}
+	if _, exceeded := Check(1); exceeded && soft >= Need(1) {
+		t.Errorf("Check(1) превышает лимит %d", soft)
+	}
+	if soft >= 1<<40 {
+		t.Skip("лимит открытых файлов не ограничен")
+	}
+	workers := int(soft) // Каждому воркеру нужно два дескриптора: заведомо больше лимита
+	if got, exceeded := Check(workers); !exceeded || got != soft {
+		t.Errorf("Check(%d) = %d, %v, ожидалось %d, true", workers, got, exceeded, soft)
+	}
+}
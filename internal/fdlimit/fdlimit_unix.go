@@ -0,0 +1,15 @@
+//go:build unix
+
+package fdlimit
+
+import "syscall"
+
+// Limit возвращает мягкий и жесткий лимит открытых файлов (ulimit -n).
+// Go при запуске уже поднимает мягкий лимит до жесткого, поэтому он действует для всего процесса.
+func Limit() (soft, hard uint64, ok bool) {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return 0, 0, false
+	}
+	return uint64(rlimit.Cur), uint64(rlimit.Max), true
+}
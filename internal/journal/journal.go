@@ -0,0 +1,115 @@
+package journal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Status = итог обработки файла в журнале
+type Status string
+
+const (
+	StatusOK     Status = "ok"
+	StatusFailed Status = "failed"
+)
+
+// Entry = запись журнала об обработанном файле
+type Entry struct {
+	Name       string    `json:"name"`
+	Status     Status    `json:"status"`
+	Hash       string    `json:"hash"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// Done проверяет, что файл с таким содержимым уже успешно обработан
+func (e Entry) Done(hash string) bool {
+	return e.Status == StatusOK && e.Hash == hash
+}
+
+// Journal = журнал обработанных файлов, только дозапись (JSON Lines)
+type Journal struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Open открывает журнал. При resume записи дописываются, иначе журнал начинается заново.
+func Open(path string, resume bool) (*Journal, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !resume {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("открытие журнала: %v", err)
+	}
+	return &Journal{file: file}, nil
+}
+
+// Append дописывает запись в журнал
+func (j *Journal) Append(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(data); err != nil {
+		return fmt.Errorf("запись в журнал: %v", err)
+	}
+	return nil
+}
+
+// Close закрывает журнал
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// Load читает журнал и возвращает последнюю запись для каждого файла.
+// Отсутствующий журнал не является ошибкой, оборванная при сбое строка пропускается.
+func Load(path string) (map[string]Entry, error) {
+	entries := make(map[string]Entry)
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("открытие журнала: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Name == "" {
+			continue
+		}
+		entries[entry.Name] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("чтение журнала: %v", err)
+	}
+	return entries, nil
+}
+
+// Hash вычисляет хэш содержимого запроса
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
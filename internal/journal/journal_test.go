@@ -0,0 +1,110 @@
+package journal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestJournal_AppendLoad проверяет запись и чтение журнала
+func TestJournal_AppendLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	j, err := Open(path, false)
+	if err != nil {
+		t.Fatalf("Open() вернул ошибку: %v", err)
+	}
+	entries := []Entry{
+		{Name: "a.json", Status: StatusFailed, Hash: Hash([]byte("a")), StatusCode: 500, Error: "сервер вернул статус: 500"},
+		{Name: "b.json", Status: StatusOK, Hash: Hash([]byte("b")), StatusCode: 200},
+		{Name: "a.json", Status: StatusOK, Hash: Hash([]byte("a")), StatusCode: 200},
+	}
+	for _, entry := range entries {
+		if err := j.Append(entry); err != nil {
+			t.Fatalf("Append() вернул ошибку: %v", err)
+		}
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("Close() вернул ошибку: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() вернул ошибку: %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("загружено %d записей, ожидалось 2", len(loaded))
+	}
+	if loaded["a.json"].Status != StatusOK {
+		t.Errorf("последняя запись должна перекрывать предыдущие: %+v", loaded["a.json"])
+	}
+	if loaded["b.json"].Time.IsZero() {
+		t.Error("время записи должно заполняться автоматически")
+	}
+}
+
+// TestOpen_Resume проверяет дозапись при resume и очистку без него
+func TestOpen_Resume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	for _, resume := range []bool{false, true} {
+		j, err := Open(path, resume)
+		if err != nil {
+			t.Fatalf("Open() вернул ошибку: %v", err)
+		}
+		j.Append(Entry{Name: "a.json", Status: StatusOK})
+		j.Close()
+	}
+	data, _ := os.ReadFile(path)
+	if lines := bytes.Count(data, []byte("\n")); lines != 2 {
+		t.Errorf("при resume ожидалось 2 строки, получено %d", lines)
+	}
+
+	j, _ := Open(path, false)
+	j.Close()
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Error("без resume журнал должен начинаться заново")
+	}
+}
+
+// TestLoad_Broken проверяет пропуск оборванной строки и отсутствие файла
+func TestLoad_Broken(t *testing.T) {
+	dir := t.TempDir()
+
+	entries, err := Load(filepath.Join(dir, "нет.jsonl"))
+	if err != nil || len(entries) != 0 {
+		t.Errorf("отсутствующий журнал должен давать пустой результат: %v, %v", entries, err)
+	}
+
+	path := filepath.Join(dir, "journal.jsonl")
+	os.WriteFile(path, []byte("{\"name\":\"a.json\",\"status\":\"ok\"}\n{\"name\":\"b.js"), 0644)
+	entries, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load() вернул ошибку: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("загружено %d записей, ожидалась 1", len(entries))
+	}
+}
+
+// TestEntry_Done проверяет условие пропуска файла
+func TestEntry_Done(t *testing.T) {
+	hash := Hash([]byte(`{"a":1}`))
+	tests := []struct {
+		name  string
+		entry Entry
+		want  bool
+	}{
+		{"успех с тем же хэшем", Entry{Status: StatusOK, Hash: hash}, true},
+		{"успех, содержимое изменено", Entry{Status: StatusOK, Hash: Hash([]byte(`{"a":2}`))}, false},
+		{"ошибка", Entry{Status: StatusFailed, Hash: hash}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.entry.Done(hash); got != test.want {
+				t.Errorf("Done() = %v, ожидалось %v", got, test.want)
+			}
+		})
+	}
+}
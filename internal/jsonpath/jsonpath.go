@@ -0,0 +1,287 @@
+package jsonpath
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Поддерживаемое подмножество JSONPath:
+//
+//	$            корень документа
+//	.key ['key'] поле объекта
+//	[n] [-n]     элемент массива (отрицательный индекс - с конца)
+//	.* [*]       все поля объекта или элементы массива
+//	..key ..*    рекурсивный спуск
+
+// step = шаг пути
+type step struct {
+	key       string
+	index     int
+	isIndex   bool
+	wildcard  bool
+	recursive bool
+}
+
+// Path = разобранный путь
+type Path struct {
+	raw   string
+	steps []step
+}
+
+// String возвращает исходную запись пути
+func (p *Path) String() string {
+	return p.raw
+}
+
+// Parse разбирает JSONPath выражение
+func Parse(expr string) (*Path, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("путь %q должен начинаться с $", expr)
+	}
+
+	p := &Path{raw: expr}
+	rest := expr[1:]
+	for rest != "" {
+		recursive := false
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			recursive = true
+			rest = rest[2:]
+		case rest[0] == '.':
+			rest = rest[1:]
+		case rest[0] == '[':
+		default:
+			return nil, fmt.Errorf("путь %q: неожиданный символ %q", expr, rest[0])
+		}
+
+		var s step
+		var err error
+		if strings.HasPrefix(rest, "[") {
+			s, rest, err = parseBracket(rest)
+			if err != nil {
+				return nil, fmt.Errorf("путь %q: %v", expr, err)
+			}
+		} else {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			rest = rest[end:]
+			if name == "" {
+				return nil, fmt.Errorf("путь %q: пустое имя поля", expr)
+			}
+			if name == "*" {
+				s.wildcard = true
+			} else {
+				s.key = name
+			}
+		}
+		s.recursive = recursive
+		p.steps = append(p.steps, s)
+	}
+	return p, nil
+}
+
+// parseBracket разбирает шаг в квадратных скобках
+func parseBracket(rest string) (step, string, error) {
+	rest = rest[1:]
+	if len(rest) > 0 && (rest[0] == '\'' || rest[0] == '"') {
+		quote := rest[0]
+		end := strings.IndexByte(rest[1:], quote)
+		if end < 0 || len(rest) < end+3 || rest[end+2] != ']' {
+			return step{}, "", fmt.Errorf("незакрытая строка в скобках")
+		}
+		return step{key: rest[1 : end+1]}, rest[end+3:], nil
+	}
+
+	end := strings.IndexByte(rest, ']')
+	if end < 0 {
+		return step{}, "", fmt.Errorf("незакрытая скобка")
+	}
+	inner := strings.TrimSpace(rest[:end])
+	rest = rest[end+1:]
+	if inner == "*" {
+		return step{wildcard: true}, rest, nil
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return step{}, "", fmt.Errorf("некорректный индекс %q", inner)
+	}
+	return step{index: index, isIndex: true}, rest, nil
+}
+
+// Query возвращает все значения документа по пути.
+// Документ - результат json.Unmarshal в any.
+func (p *Path) Query(doc any) []any {
+	nodes := []any{doc}
+	for _, s := range p.steps {
+		var next []any
+		for _, node := range nodes {
+			if s.recursive {
+				for _, descendant := range descendants(node) {
+					next = append(next, s.apply(descendant)...)
+				}
+				continue
+			}
+			next = append(next, s.apply(node)...)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// Get возвращает первое значение по пути
+func (p *Path) Get(doc any) (any, bool) {
+	values := p.Query(doc)
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+// Query разбирает путь и возвращает значения документа
+func Query(doc any, expr string) ([]any, error) {
+	p, err := Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return p.Query(doc), nil
+}
+
+// Match проверяет, подходит ли конкретный путь к шаблону.
+// Сегменты пути - ключи объектов (string) и индексы массивов (int).
+// Отрицательные индексы шаблона с конкретным путем не совпадают.
+func (p *Path) Match(segments []any) bool {
+	return matchSteps(p.steps, segments)
+}
+
+// matchSteps сопоставляет шаги шаблона с сегментами пути
+func matchSteps(steps []step, segments []any) bool {
+	if len(steps) == 0 {
+		return len(segments) == 0
+	}
+	s := steps[0]
+	if s.recursive {
+		for i := range segments {
+			if s.matches(segments[i]) && matchSteps(steps[1:], segments[i+1:]) {
+				return true
+			}
+		}
+		return false
+	}
+	return len(segments) > 0 && s.matches(segments[0]) && matchSteps(steps[1:], segments[1:])
+}
+
+// matches проверяет, подходит ли сегмент пути к шагу
+func (s step) matches(segment any) bool {
+	if s.wildcard {
+		return true
+	}
+	switch v := segment.(type) {
+	case string:
+		return !s.isIndex && s.key == v
+	case int:
+		return s.isIndex && s.index == v
+	}
+	return false
+}
+
+// Format записывает конкретный путь в нотации JSONPath: $.user['odd key'][0]
+func Format(segments []any) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, segment := range segments {
+		switch v := segment.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", v)
+		case string:
+			switch {
+			case isIdentifier(v):
+				b.WriteString("." + v)
+			case strings.Contains(v, "'"):
+				b.WriteString(`["` + v + `"]`)
+			default:
+				b.WriteString("['" + v + "']")
+			}
+		}
+	}
+	return b.String()
+}
+
+// isIdentifier проверяет, можно ли записать ключ через точку
+func isIdentifier(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if !(r == '_' || r == '-' || '0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// apply применяет шаг к узлу
+func (s step) apply(node any) []any {
+	switch v := node.(type) {
+	case map[string]any:
+		if s.wildcard {
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			values := make([]any, 0, len(v))
+			for _, key := range keys {
+				values = append(values, v[key])
+			}
+			return values
+		}
+		if s.isIndex {
+			return nil
+		}
+		if value, ok := v[s.key]; ok {
+			return []any{value}
+		}
+	case []any:
+		if s.wildcard {
+			return append([]any(nil), v...)
+		}
+		if !s.isIndex {
+			return nil
+		}
+		index := s.index
+		if index < 0 {
+			index += len(v)
+		}
+		if 0 <= index && index < len(v) {
+			return []any{v[index]}
+		}
+	}
+	return nil
+}
+
+// descendants возвращает узел и всех его потомков в порядке обхода в глубину
+func descendants(node any) []any {
+	result := []any{node}
+	switch v := node.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			result = append(result, descendants(v[key])...)
+		}
+	case []any:
+		for _, item := range v {
+			result = append(result, descendants(item)...)
+		}
+	}
+	return result
+}
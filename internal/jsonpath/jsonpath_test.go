@@ -0,0 +1,139 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// testDoc = документ для тестов
+const testDoc = `{
+	"id": 7,
+	"user": {"name": "poster", "tags": ["a", "b", "c"]},
+	"items": [{"id": 1, "price": 10}, {"id": 2, "price": 20}],
+	"odd key": true
+}`
+
+// decode разбирает JSON документ
+func decode(t *testing.T, data string) any {
+	t.Helper()
+	var doc any
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		t.Fatalf("невалидный JSON: %v", err)
+	}
+	return doc
+}
+
+// TestQuery проверяет выборку значений
+func TestQuery(t *testing.T) {
+	doc := decode(t, testDoc)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"$", ""},
+		{"$.id", "[7]"},
+		{"$.user.name", "[poster]"},
+		{"$['user']['tags'][1]", "[b]"},
+		{`$["odd key"]`, "[true]"},
+		{"$.user.tags[-1]", "[c]"},
+		{"$.user.tags[5]", "[]"},
+		{"$.items[*].id", "[1 2]"},
+		{"$.items.*.price", "[10 20]"},
+		{"$..id", "[7 1 2]"},
+		{"$..price", "[10 20]"},
+		{"$.missing.deep", "[]"},
+		{"$.id.nested", "[]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			values, err := Query(doc, test.path)
+			if err != nil {
+				t.Fatalf("Query() вернул ошибку: %v", err)
+			}
+			if test.path == "$" {
+				if len(values) != 1 {
+					t.Errorf("$ должен возвращать корень")
+				}
+				return
+			}
+			if got := fmt.Sprint(values); got != test.want {
+				t.Errorf("Query(%s) = %v, ожидалось %v", test.path, got, test.want)
+			}
+		})
+	}
+}
+
+// TestGet проверяет получение первого значения
+func TestGet(t *testing.T) {
+	doc := decode(t, testDoc)
+
+	p, err := Parse("$.items[0].price")
+	if err != nil {
+		t.Fatalf("Parse() вернул ошибку: %v", err)
+	}
+	value, ok := p.Get(doc)
+	if !ok || value != 10.0 {
+		t.Errorf("Get() = %v, %v, ожидалось 10, true", value, ok)
+	}
+	if p.String() != "$.items[0].price" {
+		t.Errorf("String() = %q", p.String())
+	}
+
+	missing, _ := Parse("$.nope")
+	if _, ok := missing.Get(doc); ok {
+		t.Error("Get() несуществующего пути должен возвращать false")
+	}
+}
+
+// TestParse_Errors проверяет ошибки разбора
+func TestParse_Errors(t *testing.T) {
+	for _, expr := range []string{"id", "$.", "$[", "$['a'", "$[abc]", "$x", "$.a..", "$['a'x]"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("ожидалась ошибка для %q", expr)
+		}
+	}
+}
+
+// TestMatch проверяет сопоставление конкретных путей с шаблонами
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		segments []any
+		want     bool
+	}{
+		{"$.id", []any{"id"}, true},
+		{"$.id", []any{"user", "id"}, false},
+		{"$..id", []any{"user", "id"}, true},
+		{"$..id", []any{"items", 3, "id"}, true},
+		{"$..id", []any{"id", "x"}, false},
+		{"$.items[*].id", []any{"items", 0, "id"}, true},
+		{"$.items[1]", []any{"items", 0}, false},
+		{"$.meta.*", []any{"meta", "ts"}, true},
+		{"$['odd key']", []any{"odd key"}, true},
+		{"$[0]", []any{"0"}, false},
+		{"$", []any{}, true},
+	}
+	for _, test := range tests {
+		p, err := Parse(test.pattern)
+		if err != nil {
+			t.Fatalf("Parse(%s) вернул ошибку: %v", test.pattern, err)
+		}
+		if got := p.Match(test.segments); got != test.want {
+			t.Errorf("%s.Match(%v) = %v, ожидалось %v", test.pattern, test.segments, got, test.want)
+		}
+	}
+}
+
+// TestFormat проверяет запись конкретного пути
+func TestFormat(t *testing.T) {
+	got := Format([]any{"user", "odd key", 0, "id"})
+	if want := "$.user['odd key'][0].id"; got != want {
+		t.Errorf("Format() = %s, ожидалось %s", got, want)
+	}
+	if _, err := Parse(got); err != nil {
+		t.Errorf("Format() должен давать разбираемый путь: %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package load
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// pollInterval = как часто пересчитывается цель при ее изменении во времени
+const pollInterval = 50 * time.Millisecond
+
+// Pacer выдает разрешения на отправку со скоростью, меняющейся во времени.
+// Разрешение выдается, когда с прошлого прошло не меньше 1/rate(сейчас),
+// поэтому рост скорости учитывается сразу, а не после уже назначенного ожидания.
+type Pacer struct {
+	rate  func(elapsed time.Duration) float64
+	start time.Time
+	last  time.Time
+}
+
+// NewPacer создает Pacer, rate - скорость в запросах в секунду от времени с начала
+func NewPacer(start time.Time, rate func(elapsed time.Duration) float64) *Pacer {
+	return &Pacer{rate: rate, start: start}
+}
+
+// Wait ждет разрешения на следующую отправку
+func (p *Pacer) Wait(ctx context.Context) error {
+	for {
+		now := time.Now()
+		wait := pollInterval
+		if rate := p.rate(now.Sub(p.start)); rate > 0 {
+			interval := time.Duration(float64(time.Second) / rate)
+			if p.last.IsZero() || now.Sub(p.last) >= interval {
+				p.last = now
+				return nil
+			}
+			wait = min(wait, interval-now.Sub(p.last))
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// scheduleStep = шаг интегрирования скорости при расчете расписания
+const scheduleStep = 10 * time.Millisecond
+
+// Schedule выдает моменты отправки по расписанию open-loop: моменты следуют
+// из скорости и не зависят от того, когда завершились прошлые запросы.
+// Если отправитель отстал, Wait возвращается сразу с уже прошедшим моментом,
+// чтобы задержка от расписания учитывала время ожидания в очереди.
+type Schedule struct {
+	rate    func(elapsed time.Duration) float64
+	start   time.Time
+	horizon time.Duration // Предел расписания от начала (0 - без предела)
+	next    time.Duration // Момент прошлой отправки от начала
+	sent    bool
+}
+
+// NewSchedule создает Schedule, rate - скорость в запросах в секунду от времени с начала.
+// horizon ограничивает поиск момента отправки, когда скорость падает до нуля (0 - без предела).
+func NewSchedule(start time.Time, rate func(elapsed time.Duration) float64, horizon time.Duration) *Schedule {
+	return &Schedule{rate: rate, start: start, horizon: horizon}
+}
+
+// Next рассчитывает следующий момент отправки от начала: скорость интегрируется
+// от прошлого момента, пока не наберется один запрос. false - момента нет до горизонта.
+// Первый момент - начало расписания, если скорость в нем больше нуля.
+func (s *Schedule) Next() (time.Duration, bool) {
+	if !s.sent {
+		s.sent = true
+		if s.rate(0) > 0 {
+			return 0, true
+		}
+	}
+
+	at, acc := s.next, 0.0
+	for {
+		if s.horizon > 0 && at >= s.horizon {
+			return 0, false
+		}
+		rate := s.rate(at)
+		if rate <= 0 && s.horizon <= 0 {
+			return 0, false
+		}
+		step := scheduleStep
+		if rate > 0 {
+			step = min(step, time.Duration((1-acc)/rate*float64(time.Second))+1)
+		}
+		acc += rate * step.Seconds()
+		at += step
+		if acc >= 1 {
+			s.next = at
+			return at, true
+		}
+	}
+}
+
+// Wait ждет момента следующей отправки и возвращает его
+func (s *Schedule) Wait(ctx context.Context) (time.Time, error) {
+	at, ok := s.Next()
+	if !ok {
+		<-ctx.Done()
+		return time.Time{}, ctx.Err()
+	}
+	intended := s.start.Add(at)
+	if wait := time.Until(intended); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return time.Time{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return intended, nil
+}
+
+// Gate ограничивает количество активных воркеров: работает воркер с номером id < limit
+type Gate struct {
+	mu      sync.Mutex
+	limit   int
+	opened  bool
+	changed chan struct{}
+}
+
+// NewGate создает Gate с начальным лимитом
+func NewGate(limit int) *Gate {
+	return &Gate{limit: limit, changed: make(chan struct{})}
+}
+
+// SetLimit меняет количество активных воркеров
+func (g *Gate) SetLimit(limit int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.opened || limit == g.limit {
+		return
+	}
+	g.limit = limit
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// Open окончательно снимает ограничение: все воркеры активны,
+// например, чтобы они увидели закрытие канала задач. Последующие SetLimit не действуют.
+func (g *Gate) Open() {
+	if g == nil {
+		return
+	}
+	g.SetLimit(math.MaxInt)
+	g.mu.Lock()
+	g.opened = true
+	g.mu.Unlock()
+}
+
+// Limit возвращает текущее количество активных воркеров
+func (g *Gate) Limit() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.limit
+}
+
+// Wait ждет, пока воркер id станет активным
+func (g *Gate) Wait(ctx context.Context, id int) error {
+	if g == nil {
+		return nil
+	}
+	for {
+		g.mu.Lock()
+		if id < g.limit {
+			g.mu.Unlock()
+			return nil
+		}
+		changed := g.changed
+		g.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Follow меняет лимит Gate по плану, пока не отменен ctx
+func (g *Gate) Follow(ctx context.Context, start time.Time, plan *Plan) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		g.SetLimit(Workers(plan.TargetAt(time.Since(start))))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Workers переводит цель этапа в количество воркеров: не меньше одного
+func Workers(target float64) int {
+	return max(1, int(target+0.5))
+}
@@ -0,0 +1,147 @@
+package load
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestPacer проверяет выдачу разрешений с заданной скоростью
+func TestPacer(t *testing.T) {
+	start := time.Now()
+	pacer := NewPacer(start, func(time.Duration) float64 { return 100 })
+	for i := 0; i < 5; i++ {
+		if err := pacer.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() вернул ошибку: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("5 разрешений при 100/с выданы за %v, ожидалось не меньше 40ms", elapsed)
+	}
+
+	stopped := NewPacer(time.Now(), func(time.Duration) float64 { return 0 })
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := stopped.Wait(ctx); err == nil {
+		t.Error("при нулевой скорости Wait() должен ждать до отмены")
+	}
+}
+
+// TestSchedule_Next проверяет расчет моментов отправки по скорости
+func TestSchedule_Next(t *testing.T) {
+	approx := func(got, want time.Duration) bool {
+		return got >= want-time.Millisecond && got <= want+time.Millisecond
+	}
+
+	constant := NewSchedule(time.Now(), func(time.Duration) float64 { return 100 }, 0)
+	for i := 0; i < 5; i++ {
+		at, ok := constant.Next()
+		if want := time.Duration(i) * 10 * time.Millisecond; !ok || !approx(at, want) {
+			t.Errorf("момент %d = %v, %v, ожидалось %v", i, at, ok, want)
+		}
+	}
+
+	// Скорость растет от 0 до 100 за секунду: первый запрос набирается к ~141ms
+	plan := &Plan{Ramp: RampRPS, Stages: []Stage{{Duration: time.Second, Target: 100}}}
+	ramp := NewSchedule(time.Now(), plan.TargetAt, plan.Total())
+	at, ok := ramp.Next()
+	if !ok || at < 130*time.Millisecond || at > 155*time.Millisecond {
+		t.Errorf("первый момент на разгоне = %v, %v, ожидалось ~141ms", at, ok)
+	}
+	count := 1
+	for {
+		if _, ok := ramp.Next(); !ok {
+			break
+		}
+		count++
+	}
+	if count < 49 || count > 51 {
+		t.Errorf("за разгон до 100/с за 1s запланировано %d отправок, ожидалось ~50", count)
+	}
+
+	stopped := NewSchedule(time.Now(), func(time.Duration) float64 { return 0 }, 0)
+	if _, ok := stopped.Next(); ok {
+		t.Error("при нулевой скорости без горизонта момента нет")
+	}
+}
+
+// TestSchedule_Wait проверяет, что расписание не зависит от задержек отправителя
+func TestSchedule_Wait(t *testing.T) {
+	start := time.Now()
+	schedule := NewSchedule(start, func(time.Duration) float64 { return 100 }, 0)
+	if _, err := schedule.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() вернул ошибку: %v", err)
+	}
+	// Отправитель задержался: следующие моменты уже прошли и выдаются сразу
+	time.Sleep(50 * time.Millisecond)
+	for i := 1; i <= 3; i++ {
+		intended, err := schedule.Wait(context.Background())
+		if err != nil {
+			t.Fatalf("Wait() вернул ошибку: %v", err)
+		}
+		if offset := intended.Sub(start); offset > time.Duration(i)*10*time.Millisecond+time.Millisecond {
+			t.Errorf("момент %d = %v от начала, ожидался по расписанию", i, offset)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 70*time.Millisecond {
+		t.Errorf("прошедшие моменты выданы за %v, ожидалось без ожидания", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	stopped := NewSchedule(time.Now(), func(time.Duration) float64 { return 0 }, time.Second)
+	if _, err := stopped.Wait(ctx); err == nil {
+		t.Error("при нулевой скорости Wait() должен ждать до отмены")
+	}
+}
+
+// TestGate проверяет ограничение активных воркеров
+func TestGate(t *testing.T) {
+	gate := NewGate(1)
+	if err := gate.Wait(context.Background(), 0); err != nil {
+		t.Fatalf("воркер 0 должен быть активен: %v", err)
+	}
+
+	released := make(chan error, 1)
+	go func() { released <- gate.Wait(context.Background(), 2) }()
+	select {
+	case <-released:
+		t.Fatal("воркер 2 не должен быть активен при лимите 1")
+	case <-time.After(20 * time.Millisecond):
+	}
+	gate.SetLimit(3)
+	select {
+	case err := <-released:
+		if err != nil {
+			t.Errorf("Wait() вернул ошибку: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("воркер 2 не активирован после увеличения лимита")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	gate.SetLimit(1)
+	if err := gate.Wait(ctx, 5); err == nil {
+		t.Error("ожидалась ошибка отмены")
+	}
+	gate.Open()
+	gate.SetLimit(1)
+	if err := gate.Wait(context.Background(), 1000); err != nil || gate.Limit() < 1000 {
+		t.Error("после Open() все воркеры активны")
+	}
+
+	var none *Gate
+	if err := none.Wait(context.Background(), 100); err != nil {
+		t.Error("без Gate воркеры не ограничиваются")
+	}
+}
+
+// TestWorkers проверяет перевод цели в количество воркеров
+func TestWorkers(t *testing.T) {
+	for target, want := range map[float64]int{0: 1, 0.4: 1, 2.5: 3, 7: 7} {
+		if got := Workers(target); got != want {
+			t.Errorf("Workers(%v) = %d, ожидалось %d", target, got, want)
+		}
+	}
+}
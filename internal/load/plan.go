@@ -0,0 +1,117 @@
+package load
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Порядок обхода файлов запросов
+const (
+	OrderSeq    = "seq"    // По порядку
+	OrderRandom = "random" // Случайно, каждый файл один раз за итерацию
+)
+
+// Что наращивается по этапам
+const (
+	RampRPS     = "rps"     // Скорость отправки, запросов в секунду
+	RampWorkers = "workers" // Количество активных воркеров
+)
+
+// Stage = этап нагрузки: за Duration цель линейно меняется от цели прошлого этапа до Target
+type Stage struct {
+	Duration time.Duration `json:"duration_ns"`
+	Target   float64       `json:"target"`
+}
+
+// String возвращает этап в виде "30s:10"
+func (s Stage) String() string {
+	return s.Duration.String() + ":" + strconv.FormatFloat(s.Target, 'f', -1, 64)
+}
+
+// ParseStages разбирает этапы через запятую: "30s:10,1m:50,30s:0"
+func ParseStages(s string) ([]Stage, error) {
+	var stages []Stage
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		duration, target, found := strings.Cut(part, ":")
+		if !found {
+			return nil, fmt.Errorf("ожидалось длительность:цель: %q", part)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("некорректная длительность этапа %q", duration)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(target), 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("некорректная цель этапа %q", target)
+		}
+		stages = append(stages, Stage{Duration: d, Target: value})
+	}
+	return stages, nil
+}
+
+// Plan = план нагрузочного прогона
+type Plan struct {
+	Iterations int           // Количество проходов по файлам (0 - без ограничения)
+	Duration   time.Duration // Длительность прогона (0 - без ограничения)
+	Order      string        // OrderSeq или OrderRandom
+	Ramp       string        // RampRPS или RampWorkers
+	Stages     []Stage       // Этапы нагрузки, первый начинается с 0
+}
+
+// Total возвращает длительность прогона: сумма этапов или Duration
+func (p *Plan) Total() time.Duration {
+	if len(p.Stages) == 0 {
+		return p.Duration
+	}
+	var total time.Duration
+	for _, s := range p.Stages {
+		total += s.Duration
+	}
+	return total
+}
+
+// StageAt возвращает номер этапа (с 0) на момент elapsed от начала прогона
+func (p *Plan) StageAt(elapsed time.Duration) int {
+	for i, s := range p.Stages {
+		if elapsed < s.Duration {
+			return i
+		}
+		elapsed -= s.Duration
+	}
+	return max(len(p.Stages)-1, 0)
+}
+
+// TargetAt возвращает цель на момент elapsed: линейная интерполяция внутри этапа
+func (p *Plan) TargetAt(elapsed time.Duration) float64 {
+	from := 0.0
+	for _, s := range p.Stages {
+		if elapsed < s.Duration {
+			return from + (s.Target-from)*float64(elapsed)/float64(s.Duration)
+		}
+		elapsed -= s.Duration
+		from = s.Target
+	}
+	return from
+}
+
+// MaxTarget возвращает наибольшую цель среди этапов
+func (p *Plan) MaxTarget() float64 {
+	peak := 0.0
+	for _, s := range p.Stages {
+		peak = math.Max(peak, s.Target)
+	}
+	return peak
+}
+
+// StageName возвращает подпись этапа для статистики: "2 (30s -> 50)"
+func (p *Plan) StageName(i int) string {
+	s := p.Stages[i]
+	return fmt.Sprintf("%d (%v -> %s %s)", i+1, s.Duration, strconv.FormatFloat(s.Target, 'f', -1, 64), p.Ramp)
+}
@@ -0,0 +1,70 @@
+package load
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseStages проверяет разбор этапов
+func TestParseStages(t *testing.T) {
+	stages, err := ParseStages("30s:10, 1m:50,30s:0,")
+	if err != nil {
+		t.Fatalf("ParseStages() вернул ошибку: %v", err)
+	}
+	if len(stages) != 3 || stages[1].Duration != time.Minute || stages[1].Target != 50 {
+		t.Errorf("ParseStages() = %v", stages)
+	}
+	if stages[0].String() != "30s:10" {
+		t.Errorf("String() = %q", stages[0].String())
+	}
+
+	for _, bad := range []string{"30s", "x:10", "0s:10", "30s:-1", "30s:abc"} {
+		if _, err := ParseStages(bad); err == nil {
+			t.Errorf("ожидалась ошибка для %q", bad)
+		}
+	}
+}
+
+// TestPlan проверяет расчет цели и этапа во времени
+func TestPlan(t *testing.T) {
+	plan := &Plan{Ramp: RampRPS, Stages: []Stage{
+		{Duration: 10 * time.Second, Target: 10},
+		{Duration: 20 * time.Second, Target: 10},
+		{Duration: 10 * time.Second, Target: 0},
+	}}
+
+	if plan.Total() != 40*time.Second {
+		t.Errorf("Total() = %v, ожидалось 40s", plan.Total())
+	}
+	if plan.MaxTarget() != 10 {
+		t.Errorf("MaxTarget() = %v, ожидалось 10", plan.MaxTarget())
+	}
+
+	tests := []struct {
+		elapsed time.Duration
+		target  float64
+		stage   int
+	}{
+		{0, 0, 0},
+		{5 * time.Second, 5, 0},
+		{10 * time.Second, 10, 1},
+		{25 * time.Second, 10, 1},
+		{35 * time.Second, 5, 2},
+		{time.Minute, 0, 2},
+	}
+	for _, test := range tests {
+		if got := plan.TargetAt(test.elapsed); got != test.target {
+			t.Errorf("TargetAt(%v) = %v, ожидалось %v", test.elapsed, got, test.target)
+		}
+		if got := plan.StageAt(test.elapsed); got != test.stage {
+			t.Errorf("StageAt(%v) = %v, ожидалось %v", test.elapsed, got, test.stage)
+		}
+	}
+
+	if name := plan.StageName(1); name != "2 (20s -> 10 rps)" {
+		t.Errorf("StageName() = %q", name)
+	}
+	if (&Plan{Duration: time.Minute}).Total() != time.Minute {
+		t.Error("без этапов длительность прогона задается Duration")
+	}
+}
@@ -0,0 +1,56 @@
+package load
+
+import (
+	"math/rand/v2"
+	"poster/internal/source"
+)
+
+// Sequence выдает файлы запросов по кругу: по порядку или перемешивая каждую итерацию
+type Sequence struct {
+	jobs       []source.Job
+	order      []int
+	iterations int // 0 - без ограничения
+	random     bool
+	rand       *rand.Rand
+
+	iteration int // Текущая итерация (с 1)
+	pos       int
+}
+
+// NewSequence создает последовательность задач. iterations = 0 - бесконечно.
+func NewSequence(jobs []source.Job, iterations int, order string, rng *rand.Rand) *Sequence {
+	if rng == nil {
+		rng = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	s := &Sequence{
+		jobs:       jobs,
+		order:      make([]int, len(jobs)),
+		iterations: iterations,
+		random:     order == OrderRandom,
+		rand:       rng,
+	}
+	for i := range s.order {
+		s.order[i] = i
+	}
+	return s
+}
+
+// Next возвращает следующую задачу и номер ее итерации (с 1). false - задачи закончились.
+func (s *Sequence) Next() (source.Job, int, bool) {
+	if len(s.jobs) == 0 {
+		return source.Job{}, 0, false
+	}
+	if s.iteration == 0 || s.pos == len(s.order) {
+		if s.iterations > 0 && s.iteration >= s.iterations {
+			return source.Job{}, 0, false
+		}
+		s.iteration++
+		s.pos = 0
+		if s.random {
+			s.rand.Shuffle(len(s.order), func(i, j int) { s.order[i], s.order[j] = s.order[j], s.order[i] })
+		}
+	}
+	job := s.jobs[s.order[s.pos]]
+	s.pos++
+	return job, s.iteration, true
+}
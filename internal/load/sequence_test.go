@@ -0,0 +1,70 @@
+package load
+
+import (
+	"math/rand/v2"
+	"poster/internal/source"
+	"slices"
+	"testing"
+)
+
+// names выбирает имена задач последовательности
+func names(s *Sequence, limit int) ([]string, []int) {
+	var got []string
+	var iterations []int
+	for len(got) < limit {
+		job, iteration, ok := s.Next()
+		if !ok {
+			break
+		}
+		got = append(got, job.Name)
+		iterations = append(iterations, iteration)
+	}
+	return got, iterations
+}
+
+// TestSequence_Seq проверяет обход по порядку
+func TestSequence_Seq(t *testing.T) {
+	jobs := []source.Job{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+
+	got, iterations := names(NewSequence(jobs, 2, OrderSeq, nil), 100)
+	if want := []string{"a", "b", "c", "a", "b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("последовательность = %v, ожидалось %v", got, want)
+	}
+	if want := []int{1, 1, 1, 2, 2, 2}; !slices.Equal(iterations, want) {
+		t.Errorf("итерации = %v, ожидалось %v", iterations, want)
+	}
+
+	got, _ = names(NewSequence(jobs, 0, OrderSeq, nil), 10)
+	if len(got) != 10 {
+		t.Errorf("без ограничения итераций получено %d задач, ожидалось 10", len(got))
+	}
+
+	if _, _, ok := NewSequence(nil, 0, OrderSeq, nil).Next(); ok {
+		t.Error("пустая последовательность не должна выдавать задачи")
+	}
+}
+
+// TestSequence_Random проверяет, что случайный порядок выдает каждый файл один раз за итерацию
+func TestSequence_Random(t *testing.T) {
+	jobs := []source.Job{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e"}}
+	seq := NewSequence(jobs, 3, OrderRandom, rand.New(rand.NewPCG(1, 2)))
+
+	got, _ := names(seq, 100)
+	if len(got) != 15 {
+		t.Fatalf("получено %d задач, ожидалось 15", len(got))
+	}
+	ordered := true
+	for i := 0; i < 15; i += 5 {
+		iteration := slices.Clone(got[i : i+5])
+		if !slices.Equal(iteration, []string{"a", "b", "c", "d", "e"}) {
+			ordered = false
+		}
+		slices.Sort(iteration)
+		if !slices.Equal(iteration, []string{"a", "b", "c", "d", "e"}) {
+			t.Errorf("итерация %d содержит не все файлы: %v", i/5+1, got[i:i+5])
+		}
+	}
+	if ordered {
+		t.Error("случайный порядок совпал с исходным во всех итерациях")
+	}
+}
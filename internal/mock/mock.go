@@ -0,0 +1,111 @@
+package mock
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Options = поведение тестового сервера
+type Options struct {
+	Delay     time.Duration // Задержка каждого ответа
+	Status    int           // HTTP статус успешного ответа
+	ErrorRate float64       // Доля ответов 500 [0..1]
+	Fixtures  string        // Директория готовых ответов (пусто - эхо запроса)
+}
+
+// Echo = ответ эхо-сервера: полученный запрос
+type Echo struct {
+	Method  string              `json:"method"`
+	Path    string              `json:"path"`
+	Query   map[string][]string `json:"query,omitempty"`
+	Headers map[string]string   `json:"headers,omitempty"`
+	Body    json.RawMessage     `json:"body,omitempty"` // JSON тело как есть, другое - строкой
+}
+
+// Server = тестовый сервер для проверки poster без настоящего сервиса
+type Server struct {
+	opts   Options
+	random func() float64
+}
+
+// New создает тестовый сервер
+func New(opts Options) *Server {
+	if opts.Status == 0 {
+		opts.Status = http.StatusOK
+	}
+	return &Server{opts: opts, random: rand.Float64}
+}
+
+// ServeHTTP отвечает на запрос: готовым файлом из Fixtures или эхом запроса
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Delay > 0 {
+		timer := time.NewTimer(s.opts.Delay)
+		defer timer.Stop()
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timer.C:
+		}
+	}
+
+	if s.opts.ErrorRate > 0 && s.random() < s.opts.ErrorRate {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "случайная ошибка тестового сервера"})
+		return
+	}
+
+	if s.opts.Fixtures != "" {
+		data, err := os.ReadFile(s.fixture(r.URL.Path))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "нет готового ответа для " + r.URL.Path})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(s.opts.Status)
+		w.Write(data)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	echo := Echo{Method: r.Method, Path: r.URL.Path, Headers: make(map[string]string)}
+	if query := r.URL.Query(); len(query) > 0 {
+		echo.Query = query
+	}
+	for name := range r.Header {
+		echo.Headers[name] = r.Header.Get(name)
+	}
+	if len(body) > 0 {
+		if json.Valid(body) {
+			echo.Body = body
+		} else {
+			echo.Body, _ = json.Marshal(string(body))
+		}
+	}
+	writeJSON(w, s.opts.Status, echo)
+}
+
+// fixture возвращает файл готового ответа: /users/1 - users/1.json, / - index.json
+func (s *Server) fixture(urlPath string) string {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		name = "index"
+	}
+	return filepath.Join(s.opts.Fixtures, filepath.FromSlash(name)+".json")
+}
+
+// writeJSON записывает JSON ответ со статусом
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,110 @@
+package mock
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestServer_Echo проверяет эхо запроса
+func TestServer_Echo(t *testing.T) {
+	server := httptest.NewServer(New(Options{Status: http.StatusCreated}))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/users?id=1", "application/json", strings.NewReader(`{"name":"poster"}`))
+	if err != nil {
+		t.Fatalf("запрос вернул ошибку: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("статус = %d, ожидалось 201", resp.StatusCode)
+	}
+
+	var echo Echo
+	if err := json.NewDecoder(resp.Body).Decode(&echo); err != nil {
+		t.Fatalf("ответ не JSON: %v", err)
+	}
+	if echo.Method != "POST" || echo.Path != "/users" || echo.Query["id"][0] != "1" || echo.Headers["Content-Type"] != "application/json" {
+		t.Errorf("эхо = %+v", echo)
+	}
+	if string(echo.Body) != `{"name":"poster"}` {
+		t.Errorf("тело = %s", echo.Body)
+	}
+
+	resp, err = http.Post(server.URL, "text/plain", strings.NewReader("не JSON"))
+	if err != nil {
+		t.Fatalf("запрос вернул ошибку: %v", err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&echo); err != nil {
+		t.Fatalf("ответ не JSON: %v", err)
+	}
+	if string(echo.Body) != `"не JSON"` {
+		t.Errorf("тело не JSON должно быть строкой: %s", echo.Body)
+	}
+}
+
+// TestServer_Fixtures проверяет готовые ответы из директории
+func TestServer_Fixtures(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "users"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"users/1.json": `{"id":1}`, "index.json": `{"ok":true}`} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := httptest.NewServer(New(Options{Fixtures: dir}))
+	defer server.Close()
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/users/1", http.StatusOK, `{"id":1}`},
+		{"/", http.StatusOK, `{"ok":true}`},
+		{"/users/2", http.StatusNotFound, "нет готового ответа"},
+		{"/../../etc/passwd", http.StatusNotFound, "нет готового ответа"},
+	}
+	for _, test := range tests {
+		resp, err := http.Get(server.URL + test.path)
+		if err != nil {
+			t.Fatalf("запрос %s вернул ошибку: %v", test.path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != test.status || !strings.Contains(string(body), test.body) {
+			t.Errorf("%s: статус %d, тело %q, ожидалось %d, %q", test.path, resp.StatusCode, body, test.status, test.body)
+		}
+	}
+}
+
+// TestServer_Errors проверяет случайные ошибки и задержку
+func TestServer_Errors(t *testing.T) {
+	s := New(Options{ErrorRate: 0.5, Delay: 20 * time.Millisecond})
+	values := []float64{0.1, 0.9}
+	s.random = func() float64 { v := values[0]; values = values[1:]; return v }
+
+	var statuses []int
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}")))
+		statuses = append(statuses, rec.Code)
+	}
+	if statuses[0] != http.StatusInternalServerError || statuses[1] != http.StatusOK {
+		t.Errorf("статусы = %v, ожидалось [500 200]", statuses)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("2 ответа с задержкой 20ms получены за %v", elapsed)
+	}
+}
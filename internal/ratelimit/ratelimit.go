@@ -0,0 +1,169 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AnyHost = ключ ограничения для каждого хоста без собственного ограничения
+const AnyHost = "*"
+
+// Limiter = ограничитель скорости по алгоритму token bucket.
+// Корзина вмещает burst токенов и пополняется со скоростью rate токенов в секунду.
+// При burst = 1 запросы идут равномерно с интервалом 1/rate.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// New создает ограничитель на rate запросов в секунду с пачкой до burst запросов
+func New(rate float64, burst int) *Limiter {
+	burst = max(burst, 1)
+	return &Limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// Rate возвращает целевую скорость, запросов в секунду
+func (l *Limiter) Rate() float64 {
+	return l.rate
+}
+
+// reserve забирает токен и возвращает время ожидания до его появления
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel возвращает токен, если ожидание прервано
+func (l *Limiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = math.Min(l.burst, l.tokens+1)
+}
+
+// Wait ждет разрешения на запрос. Возвращает ошибку ctx, если ожидание прервано.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	delay := l.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Set = общий ограничитель и ограничители по хостам
+type Set struct {
+	global *Limiter
+	burst  int
+	rates  map[string]float64 // Хост -> скорость, AnyHost - для остальных хостов
+
+	mu    sync.Mutex
+	hosts map[string]*Limiter
+}
+
+// NewSet создает набор ограничителей.
+// rate = 0 - общий лимит выключен, hostRates - лимиты по хостам (AnyHost - для каждого хоста).
+func NewSet(rate float64, burst int, hostRates map[string]float64) *Set {
+	s := &Set{
+		burst: burst,
+		rates: hostRates,
+		hosts: make(map[string]*Limiter),
+	}
+	if rate > 0 {
+		s.global = New(rate, burst)
+	}
+	return s
+}
+
+// Enabled проверяет, задано ли хоть одно ограничение
+func (s *Set) Enabled() bool {
+	return s != nil && (s.global != nil || len(s.rates) > 0)
+}
+
+// Wait ждет разрешения общего ограничителя и ограничителя хоста
+func (s *Set) Wait(ctx context.Context, host string) error {
+	if !s.Enabled() {
+		return nil
+	}
+	if err := s.host(host).Wait(ctx); err != nil {
+		return err
+	}
+	return s.global.Wait(ctx)
+}
+
+// host возвращает ограничитель хоста (nil - хост не ограничен)
+func (s *Set) host(host string) *Limiter {
+	rate, ok := s.rates[host]
+	key := host
+	if !ok {
+		if rate, ok = s.rates[AnyHost]; !ok {
+			return nil
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.hosts[key]
+	if !ok {
+		l = New(rate, s.burst)
+		s.hosts[key] = l
+	}
+	return l
+}
+
+// ParseHostRates разбирает лимиты по хостам: "api.example.com=50,*=10"
+func ParseHostRates(s string) (map[string]float64, error) {
+	rates := make(map[string]float64)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		host, value, found := strings.Cut(part, "=")
+		host = strings.TrimSpace(host)
+		if !found || host == "" {
+			return nil, fmt.Errorf("ожидалось хост=скорость: %q", part)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("некорректная скорость %q для %s", value, host)
+		}
+		rates[host] = rate
+	}
+	return rates, nil
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeClock = управляемое время для тестов
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// TestLimiter_Reserve проверяет пополнение корзины и расчет ожидания
+func TestLimiter_Reserve(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := New(10, 3)
+	l.now = clock.Now
+
+	// Пачка из burst запросов проходит сразу
+	for i := 0; i < 3; i++ {
+		if delay := l.reserve(); delay != 0 {
+			t.Fatalf("запрос %d пачки ждет %v", i+1, delay)
+		}
+	}
+	// Следующие ждут по 1/rate
+	if delay := l.reserve(); delay != 100*time.Millisecond {
+		t.Errorf("ожидание = %v, ожидалось 100ms", delay)
+	}
+	if delay := l.reserve(); delay != 200*time.Millisecond {
+		t.Errorf("ожидание = %v, ожидалось 200ms", delay)
+	}
+
+	// За секунду корзина наполняется, но не больше burst
+	clock.now = clock.now.Add(10 * time.Second)
+	for i := 0; i < 3; i++ {
+		if delay := l.reserve(); delay != 0 {
+			t.Fatalf("после паузы запрос %d ждет %v", i+1, delay)
+		}
+	}
+	if delay := l.reserve(); delay == 0 {
+		t.Error("корзина не должна вмещать больше burst токенов")
+	}
+}
+
+// TestLimiter_Wait проверяет равномерную отправку и отмену ожидания
+func TestLimiter_Wait(t *testing.T) {
+	l := New(100, 1)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() вернул ошибку: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("5 запросов при 100/с прошли за %v, ожидалось не меньше 40ms", elapsed)
+	}
+
+	slow := New(0.1, 1)
+	slow.Wait(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := slow.Wait(ctx); err == nil {
+		t.Error("ожидалась ошибка отмены ожидания")
+	}
+
+	var none *Limiter
+	if err := none.Wait(context.Background()); err != nil {
+		t.Errorf("отсутствующий ограничитель не должен ждать: %v", err)
+	}
+}
+
+// TestSet проверяет ограничители по хостам
+func TestSet(t *testing.T) {
+	s := NewSet(0, 1, map[string]float64{"slow": 1, AnyHost: 1000})
+	if !s.Enabled() || NewSet(0, 1, nil).Enabled() {
+		t.Error("Enabled() определяет наличие ограничений неверно")
+	}
+	if s.host("slow") == s.host("a") || s.host("a") != s.host("a") || s.host("a") == s.host("b") {
+		t.Error("у каждого хоста должен быть свой ограничитель")
+	}
+	if s.host("slow").Rate() != 1 || s.host("a").Rate() != 1000 {
+		t.Error("неверная скорость ограничителя хоста")
+	}
+	if NewSet(5, 1, map[string]float64{"x": 1}).host("y") != nil {
+		t.Error("хост без ограничения не должен ограничиваться")
+	}
+}
+
+// TestParseHostRates проверяет разбор лимитов по хостам
+func TestParseHostRates(t *testing.T) {
+	rates, err := ParseHostRates(" api.local=50, *=2.5 ,")
+	if err != nil {
+		t.Fatalf("ParseHostRates() вернул ошибку: %v", err)
+	}
+	if len(rates) != 2 || rates["api.local"] != 50 || rates[AnyHost] != 2.5 {
+		t.Errorf("ParseHostRates() = %v", rates)
+	}
+	for _, bad := range []string{"api.local", "=5", "a=0", "a=x"} {
+		if _, err := ParseHostRates(bad); err == nil {
+			t.Errorf("ожидалась ошибка для %q", bad)
+		}
+	}
+}
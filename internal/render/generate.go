@@ -0,0 +1,140 @@
+package render
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math"
+	mathrand "math/rand/v2"
+	"strconv"
+	"time"
+)
+
+// generator возвращает новое значение при каждой подстановке
+type generator func(r *Renderer, args []string) (string, error)
+
+// generators = встроенные генераторы: ${uuid}, ${random_int:1:100}, ...
+var generators = map[string]generator{
+	"uuid":          generateUUID,
+	"timestamp":     generateTimestamp,
+	"unix":          generateUnix,
+	"unix_ms":       generateUnixMs,
+	"seq":           generateSeq,
+	"random_int":    generateRandomInt,
+	"random_string": generateRandomString,
+}
+
+// Параметры генераторов по умолчанию
+const (
+	defaultRandomMax    = 1000000 // Верхняя граница random_int
+	defaultStringLength = 16      // Длина random_string
+	maxStringLength     = 1 << 20 // Предел длины random_string
+)
+
+// alphabet = символы random_string
+const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// now = текущее время, подменяется в тестах
+var now = time.Now
+
+// generateUUID возвращает случайный UUID версии 4
+func generateUUID(_ *Renderer, args []string) (string, error) {
+	if err := noArgs(args); err != nil {
+		return "", err
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// generateTimestamp возвращает текущее время в RFC 3339
+func generateTimestamp(_ *Renderer, args []string) (string, error) {
+	if err := noArgs(args); err != nil {
+		return "", err
+	}
+	return now().Format(time.RFC3339), nil
+}
+
+// generateUnix возвращает текущее время в секундах Unix
+func generateUnix(_ *Renderer, args []string) (string, error) {
+	if err := noArgs(args); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(now().Unix(), 10), nil
+}
+
+// generateUnixMs возвращает текущее время в миллисекундах Unix
+func generateUnixMs(_ *Renderer, args []string) (string, error) {
+	if err := noArgs(args); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(now().UnixMilli(), 10), nil
+}
+
+// generateSeq возвращает следующее значение счетчика (с 1), общего на весь прогон
+func generateSeq(r *Renderer, args []string) (string, error) {
+	if err := noArgs(args); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(r.seq.Add(1), 10), nil
+}
+
+// generateRandomInt возвращает случайное целое из [min..max]: ${random_int}, ${random_int:max}, ${random_int:min:max}
+func generateRandomInt(_ *Renderer, args []string) (string, error) {
+	min, max := int64(0), int64(defaultRandomMax)
+	var err error
+	switch len(args) {
+	case 0:
+	case 1:
+		max, err = strconv.ParseInt(args[0], 10, 64)
+	case 2:
+		if min, err = strconv.ParseInt(args[0], 10, 64); err == nil {
+			max, err = strconv.ParseInt(args[1], 10, 64)
+		}
+	default:
+		return "", fmt.Errorf("ожидалось не больше двух аргументов: min:max")
+	}
+	if err != nil {
+		return "", fmt.Errorf("границы должны быть целыми числами: %v", err)
+	}
+	if min > max {
+		return "", fmt.Errorf("min=%d больше max=%d", min, max)
+	}
+	// Количество значений max-min+1 должно помещаться в int64: иначе разность переполняется
+	span := max - min + 1
+	if span <= 0 {
+		return "", fmt.Errorf("диапазон [%d..%d] шире %d значений", min, max, int64(math.MaxInt64))
+	}
+	return strconv.FormatInt(min+mathrand.Int64N(span), 10), nil
+}
+
+// generateRandomString возвращает случайную строку из латинских букв и цифр: ${random_string}, ${random_string:n}
+func generateRandomString(_ *Renderer, args []string) (string, error) {
+	n := defaultStringLength
+	switch len(args) {
+	case 0:
+	case 1:
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 || n > maxStringLength {
+			return "", fmt.Errorf("длина должна быть в диапазоне [1..%d]", maxStringLength)
+		}
+	default:
+		return "", fmt.Errorf("ожидался один аргумент: длина")
+	}
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphabet[mathrand.IntN(len(alphabet))]
+	}
+	return string(b), nil
+}
+
+// noArgs проверяет, что генератору не переданы аргументы
+func noArgs(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("генератор без аргументов")
+	}
+	return nil
+}
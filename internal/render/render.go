@@ -0,0 +1,182 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// placeholder = подстановка ${имя} или ${генератор:аргументы}; $${...} выводится как есть без одного $
+var placeholder = regexp.MustCompile(`\$?\$\{([^{}]*)\}`)
+
+// envPrefix = пространство имен переменных окружения: ${env.ИМЯ}. Без него окружение не читается,
+// чтобы запрос не мог случайно подставить секрет из окружения
+const envPrefix = "env."
+
+// Renderer подставляет в запросы переменные файла, переменные окружения и значения генераторов.
+// Безопасен при одновременном вызове из нескольких воркеров, счетчик seq общий.
+type Renderer struct {
+	vars      map[string]string
+	lookupEnv func(string) (string, bool)
+	seq       *atomic.Int64
+}
+
+// New создает подстановщик. vars - переменные файла, lookupEnv - как os.LookupEnv (для ${env.ИМЯ}).
+func New(vars map[string]string, lookupEnv func(string) (string, bool)) *Renderer {
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+	return &Renderer{vars: vars, lookupEnv: lookupEnv, seq: new(atomic.Int64)}
+}
+
+// With возвращает подстановщик с дополнительными переменными (важнее переменных файла).
+// Счетчик seq и окружение общие с исходным подстановщиком.
+func (r *Renderer) With(vars map[string]string) *Renderer {
+	merged := make(map[string]string, len(r.vars)+len(vars))
+	for name, value := range r.vars {
+		merged[name] = value
+	}
+	for name, value := range vars {
+		merged[name] = value
+	}
+	return &Renderer{vars: merged, lookupEnv: r.lookupEnv, seq: r.seq}
+}
+
+// IsTemplate проверяет, есть ли в запросе подстановки
+func IsTemplate(data []byte) bool {
+	return bytes.Contains(data, []byte("${")) || bytes.Contains(data, []byte("{{"))
+}
+
+// Render возвращает запрос после подстановки. Запрос без подстановок возвращается как есть.
+// Сначала выполняется Go шаблон ({{.имя}}, {{uuid}}, ...), затем подстановки ${...}.
+// Подстановка текстовая: значение вставляется в JSON без экранирования.
+func (r *Renderer) Render(data []byte) ([]byte, error) {
+	if !IsTemplate(data) {
+		return data, nil
+	}
+
+	if bytes.Contains(data, []byte("{{")) {
+		tmpl, err := template.New("request").Option("missingkey=error").Funcs(r.funcs()).Parse(string(data))
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, r.vars); err != nil {
+			return nil, err
+		}
+		data = b.Bytes()
+	}
+
+	var err error
+	data = placeholder.ReplaceAllFunc(data, func(match []byte) []byte {
+		if err != nil {
+			return match
+		}
+		if bytes.HasPrefix(match, []byte("$$")) {
+			return match[1:]
+		}
+		var value string
+		value, err = r.resolve(string(match[2 : len(match)-1]))
+		return []byte(value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// resolve возвращает значение подстановки: переменная окружения env.ИМЯ, переменная файла или генератор
+func (r *Renderer) resolve(expr string) (string, error) {
+	if name, ok := strings.CutPrefix(strings.TrimSpace(expr), envPrefix); ok {
+		if value, ok := r.lookupEnv(name); ok {
+			return value, nil
+		}
+		return "", fmt.Errorf("переменная окружения %s не задана", name)
+	}
+	name, args, hasArgs := strings.Cut(strings.TrimSpace(expr), ":")
+	if !hasArgs {
+		if value, ok := r.vars[name]; ok {
+			return value, nil
+		}
+	}
+	generate, ok := generators[name]
+	if !ok {
+		return "", fmt.Errorf("неизвестная переменная ${%s}", expr)
+	}
+	var params []string
+	if hasArgs {
+		params = strings.Split(args, ":")
+	}
+	value, err := generate(r, params)
+	if err != nil {
+		return "", fmt.Errorf("${%s}: %v", expr, err)
+	}
+	return value, nil
+}
+
+// funcs возвращает функции Go шаблона: генераторы и env
+func (r *Renderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		"uuid":      func() (string, error) { return generateUUID(r, nil) },
+		"timestamp": func() (string, error) { return generateTimestamp(r, nil) },
+		"unix":      func() (string, error) { return generateUnix(r, nil) },
+		"unixMs":    func() (string, error) { return generateUnixMs(r, nil) },
+		"seq":       func() (string, error) { return generateSeq(r, nil) },
+		"randomInt": func(min, max int) (string, error) {
+			return generateRandomInt(r, []string{fmt.Sprint(min), fmt.Sprint(max)})
+		},
+		"randomString": func(n int) (string, error) { return generateRandomString(r, []string{fmt.Sprint(n)}) },
+		"env": func(name string) (string, error) {
+			if value, ok := r.lookupEnv(name); ok {
+				return value, nil
+			}
+			return "", fmt.Errorf("переменная окружения %s не задана", name)
+		},
+	}
+}
+
+// LoadVars читает файл переменных .json, .yaml или .yml: объект имя -> значение.
+// Строки подставляются как есть, остальные значения (числа, списки, объекты) - в виде JSON.
+func LoadVars(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("чтение файла: %v", err)
+	}
+
+	var tree map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&tree)
+	default:
+		return nil, fmt.Errorf("неизвестный формат %q: ожидалось .json, .yaml или .yml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("разбор файла: %v", err)
+	}
+
+	vars := make(map[string]string, len(tree))
+	for name, value := range tree {
+		if s, ok := value.(string); ok {
+			vars[name] = s
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("переменная %s: %v", name, err)
+		}
+		vars[name] = string(encoded)
+	}
+	return vars, nil
+}
@@ -0,0 +1,161 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// lookup возвращает функцию поиска в заданном окружении
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+// TestRender проверяет подстановку переменных и генераторов
+func TestRender(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	vars := map[string]string{"user": "alice", "HOST": "file", "ids": "[1,2]"}
+	env := map[string]string{"HOST": "env", "TOKEN": "secret"}
+
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"без подстановок", `{"a": "$"}`, `{"a": "$"}`, false},
+		{"переменная файла", `{"u": "${user}"}`, `{"u": "alice"}`, false},
+		{"окружение только через env.", `{"url": "http://${HOST}/${env.HOST}/${env.TOKEN}"}`, `{"url": "http://file/env/secret"}`, false},
+		{"окружение без env.", `{"t": "${TOKEN}"}`, "", true},
+		{"не заданная переменная окружения", `{"t": "${env.NOBODY}"}`, "", true},
+		{"экранирование Go шаблона", `{"s": "{{"{{"}}.user}}"}`, `{"s": "{{.user}}"}`, false},
+		{"значение JSON", `{"ids": ${ids}}`, `{"ids": [1,2]}`, false},
+		{"экранирование", `{"s": "$${user}"}`, `{"s": "${user}"}`, false},
+		{"время", `{"t": "${timestamp}", "u": ${unix}, "ms": ${unix_ms}}`, `{"t": "2024-05-01T12:00:00Z", "u": 1714564800, "ms": 1714564800000}`, false},
+		{"Go шаблон", `{"u": "{{.user}}", "t": "{{env "TOKEN"}}", "d": "{{timestamp}}"}`, `{"u": "alice", "t": "secret", "d": "2024-05-01T12:00:00Z"}`, false},
+		{"неизвестная переменная", `{"u": "${nobody}"}`, "", true},
+		{"неизвестное поле Go шаблона", `{"u": "{{.nobody}}"}`, "", true},
+		{"ошибка Go шаблона", `{"u": "{{.user"}`, "", true},
+		{"неверные аргументы", `{"n": ${random_int:a:b}}`, "", true},
+		{"аргументы генератора без аргументов", `{"n": "${uuid:1}"}`, "", true},
+	}
+
+	r := New(vars, lookup(env))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := r.Render([]byte(test.data))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Render() ошибка = %v, ожидалась ошибка: %v", err, test.wantErr)
+			}
+			if !test.wantErr && string(got) != test.want {
+				t.Errorf("Render() = %s, ожидалось %s", got, test.want)
+			}
+		})
+	}
+}
+
+// TestRender_Generators проверяет формат и новизну значений генераторов
+func TestRender_Generators(t *testing.T) {
+	r := New(nil, lookup(nil))
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	first, _ := r.Render([]byte("${uuid}"))
+	second, _ := r.Render([]byte("{{uuid}}"))
+	if !uuid.Match(first) || !uuid.Match(second) || string(first) == string(second) {
+		t.Errorf("uuid = %s, %s", first, second)
+	}
+
+	// Счетчик общий для ${seq} и {{seq}}
+	got, err := r.Render([]byte("${seq} ${seq} {{seq}}"))
+	if err != nil || string(got) != "2 3 1" {
+		t.Errorf("seq = %s, %v, ожидалось \"2 3 1\" (Go шаблон выполняется раньше)", got, err)
+	}
+
+	for i := 0; i < 100; i++ {
+		got, err := r.Render([]byte(`${random_int:5:7} ${random_string:4} {{randomInt 1 1}}`))
+		if err != nil || !regexp.MustCompile(`^[5-7] [a-zA-Z0-9]{4} 1$`).Match(got) {
+			t.Fatalf("случайные значения = %s, %v", got, err)
+		}
+	}
+	if _, err := r.Render([]byte("${random_int:9:1}")); err == nil {
+		t.Error("ожидалась ошибка для min > max")
+	}
+	// Крайние границы: количество значений не помещается в int64
+	for _, bounds := range []string{"9223372036854775807", "-1:9223372036854775807", "-9223372036854775808:9223372036854775807", "-9223372036854775808:0"} {
+		if _, err := r.Render([]byte("${random_int:" + bounds + "}")); err == nil {
+			t.Errorf("random_int:%s: ожидалась ошибка переполнения диапазона", bounds)
+		}
+	}
+	for bounds, want := range map[string]string{
+		"9223372036854775807:9223372036854775807":   "9223372036854775807",
+		"-9223372036854775808:-9223372036854775808": "-9223372036854775808",
+	} {
+		if got, err := r.Render([]byte("${random_int:" + bounds + "}")); err != nil || string(got) != want {
+			t.Errorf("random_int:%s = %s, %v, ожидалось %s", bounds, got, err, want)
+		}
+	}
+	if got, err := r.Render([]byte("${random_int:-1:9223372036854775805}")); err != nil || len(got) == 0 {
+		t.Errorf("random_int:-1:9223372036854775805 = %s, %v: наибольший допустимый диапазон", got, err)
+	}
+	if _, err := r.Render([]byte("${random_string:0}")); err == nil {
+		t.Error("ожидалась ошибка для нулевой длины")
+	}
+}
+
+// TestLoadVars проверяет чтение файла переменных
+func TestLoadVars(t *testing.T) {
+	dir := t.TempDir()
+	want := map[string]string{"name": "poster", "count": "3", "ratio": "0.5", "tags": `["a","b"]`, "on": "true"}
+	files := map[string]string{
+		"vars.json": `{"name": "poster", "count": 3, "ratio": 0.5, "tags": ["a", "b"], "on": true}`,
+		"vars.yaml": "name: poster\ncount: 3\nratio: 0.5\ntags: [a, b]\n\"on\": true\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		vars, err := LoadVars(path)
+		if err != nil {
+			t.Fatalf("LoadVars(%s) вернул ошибку: %v", name, err)
+		}
+		if !reflect.DeepEqual(vars, want) {
+			t.Errorf("LoadVars(%s) = %v, ожидалось %v", name, vars, want)
+		}
+	}
+
+	for name, content := range map[string]string{"vars.toml": "a = 1", "bad.json": `{"a": `, "list.json": `[1]`} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadVars(path); err == nil {
+			t.Errorf("LoadVars(%s): ожидалась ошибка", name)
+		}
+	}
+}
+
+// TestRender_With проверяет дополнительные переменные поверх переменных файла
+func TestRender_With(t *testing.T) {
+	base := New(map[string]string{"a": "file", "b": "file"}, lookup(nil))
+	child := base.With(map[string]string{"b": "step", "c": "step"})
+
+	got, err := child.Render([]byte("${a} ${b} ${c} ${seq}"))
+	if err != nil || string(got) != "file step step 1" {
+		t.Errorf("With().Render() = %s, %v", got, err)
+	}
+	if _, err := base.Render([]byte("${c}")); err == nil {
+		t.Error("переменные With() не должны попадать в исходный подстановщик")
+	}
+	if got, _ := base.Render([]byte("${seq}")); string(got) != "2" {
+		t.Errorf("счетчик seq должен быть общим: %s", got)
+	}
+}
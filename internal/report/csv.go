@@ -0,0 +1,53 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvHeader = заголовок CSV отчета
+var csvHeader = []string{
+	"file", "line", "offset", "method", "url", "start", "duration_ms", "status_code",
+	"attempts", "file_size", "request_size", "response_size", "error_type", "error", "backoff_ms", "throttle_ms",
+}
+
+// WriteCSV записывает результаты в CSV: одна строка на файл запроса.
+// Итоговая статистика в CSV не попадает, она есть в остальных форматах.
+func WriteCSV(w io.Writer, r *Report) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, rec := range r.Records {
+		row := []string{
+			rec.File,
+			strconv.Itoa(rec.Line),
+			strconv.FormatInt(rec.Offset, 10),
+			rec.Method,
+			rec.URL,
+			rec.Start.Format(time.RFC3339Nano),
+			milliseconds(rec.Duration),
+			strconv.Itoa(rec.StatusCode),
+			strconv.Itoa(rec.Attempts),
+			strconv.FormatInt(rec.FileSize, 10),
+			strconv.Itoa(rec.RequestSize),
+			strconv.Itoa(rec.ResponseSize),
+			rec.ErrorType,
+			rec.Error,
+			milliseconds(rec.Backoff),
+			milliseconds(rec.Throttle),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// milliseconds форматирует длительность в миллисекундах
+func milliseconds(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
@@ -0,0 +1,25 @@
+package report
+
+// Типы ошибок обработки запроса (Record.ErrorType): общие для прогона, статистики и отчетов
+const (
+	ErrRead      = "read"         // Чтение файла
+	ErrJSON      = "invalid_json" // Невалидный JSON
+	ErrEnvelope  = "envelope"     // Некорректный конверт
+	ErrTransport = "transport"    // Сетевая ошибка, ответа нет
+	ErrStatus    = "http_status"  // Сервер вернул не 2xx
+	ErrSave      = "save"         // Сохранение ответа
+	ErrAssert    = "assert"       // Ответ не прошел проверки ожиданий
+	ErrSchema    = "schema"       // Тело запроса или ответа не соответствует JSON Schema
+	ErrTemplate  = "template"     // Ошибка подстановки переменных в шаблон запроса
+	ErrExtract   = "extract"      // Значение для следующих шагов сценария не найдено в ответе
+	ErrSkipped   = "skipped"      // Запрос не отправлен: зависимость из depends_on не выполнена
+)
+
+// IsFailure проверяет, что ошибка - проваленная проверка ответа, а не ошибка выполнения
+func IsFailure(errType string) bool {
+	switch errType {
+	case ErrStatus, ErrAssert, ErrSchema, ErrExtract:
+		return true
+	}
+	return false
+}
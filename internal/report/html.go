@@ -0,0 +1,236 @@
+package report
+
+import (
+	"cmp"
+	_ "embed"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"poster/internal/stats"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FormatHTML = автономный HTML отчет с графиками
+const FormatHTML = "html"
+
+// Параметры HTML отчета
+const (
+	slowestCount   = 20   // Количество самых медленных запросов
+	timelinePoints = 5000 // Максимум точек на графике задержки во времени
+	previewSize    = 1024 // Максимальный размер превью тела ответа
+)
+
+// Размеры графиков SVG
+const (
+	chartWidth  = 1000
+	chartHeight = 280
+	chartMargin = 50
+)
+
+//go:embed assets/report.html.tmpl
+var htmlTemplate string
+
+//go:embed assets/style.css
+var htmlStyle string
+
+// page = данные для шаблона HTML отчета
+type page struct {
+	Report    *Report
+	Summary   stats.Summary
+	Started   string
+	Style     template.CSS
+	Timeline  template.HTML
+	Histogram template.HTML
+	Statuses  template.HTML
+	Slowest   []Record
+	Failed    []Record
+}
+
+var pageTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": formatDuration,
+}).Parse(htmlTemplate))
+
+// WriteHTML записывает автономный HTML отчет: все стили и графики встроены, сеть не нужна
+func WriteHTML(w io.Writer, r *Report) error {
+	p := page{
+		Report:    r,
+		Summary:   r.Summary,
+		Style:     template.CSS(htmlStyle),
+		Timeline:  timelineChart(r.Records),
+		Histogram: histogramChart(r),
+		Statuses:  statusChart(r),
+	}
+	if !r.Started.IsZero() {
+		p.Started = r.Started.Format(time.RFC3339)
+	}
+
+	slowest := slices.Clone(r.Records)
+	slices.SortStableFunc(slowest, func(a, b Record) int { return cmp.Compare(b.Duration, a.Duration) })
+	p.Slowest = slowest[:min(slowestCount, len(slowest))]
+
+	for _, rec := range r.Records {
+		if rec.Failed() {
+			p.Failed = append(p.Failed, rec)
+		}
+	}
+
+	return pageTemplate.Execute(w, p)
+}
+
+// Preview обрезает тело ответа для отчета
+func Preview(body []byte) string {
+	if len(body) <= previewSize {
+		return string(body)
+	}
+	return strings.ToValidUTF8(string(body[:previewSize]), "") + "..."
+}
+
+// timelineChart строит график задержки от времени начала запроса
+func timelineChart(records []Record) template.HTML {
+	if len(records) == 0 {
+		return emptyChart()
+	}
+
+	begin := records[0].Start
+	var end time.Time
+	var peak time.Duration
+	for _, rec := range records {
+		if rec.Start.Before(begin) {
+			begin = rec.Start
+		}
+		if rec.Start.After(end) {
+			end = rec.Start
+		}
+		peak = max(peak, rec.Duration)
+	}
+	span := max(end.Sub(begin), time.Millisecond)
+	peak = max(peak, time.Millisecond)
+
+	// Прореживаем точки, чтобы отчет оставался компактным
+	step := max(1, len(records)/timelinePoints)
+
+	var b strings.Builder
+	openChart(&b)
+	axes(&b, "0", formatDuration(span), formatDuration(peak))
+	plotWidth, plotHeight := float64(chartWidth-2*chartMargin), float64(chartHeight-2*chartMargin)
+	for i := 0; i < len(records); i += step {
+		rec := records[i]
+		x := chartMargin + plotWidth*float64(rec.Start.Sub(begin))/float64(span)
+		y := chartHeight - chartMargin - plotHeight*float64(rec.Duration)/float64(peak)
+		class := "ok"
+		if rec.Failed() {
+			class = "fail"
+		}
+		fmt.Fprintf(&b, `<circle class="%s" cx="%.1f" cy="%.1f" r="2"><title>%s: %s</title></circle>`,
+			class, x, y, html.EscapeString(rec.File), formatDuration(rec.Duration))
+	}
+	b.WriteString("</svg>")
+	return template.HTML(b.String())
+}
+
+// histogramChart строит гистограмму задержек успешных запросов
+func histogramChart(r *Report) template.HTML {
+	buckets := r.Summary.Histogram
+	// Отбрасываем пустые столбцы по краям
+	first, last := -1, -1
+	for i, bucket := range buckets {
+		if bucket.Count > 0 {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return emptyChart()
+	}
+
+	labels := make([]string, 0, last-first+1)
+	values := make([]int, 0, last-first+1)
+	for _, bucket := range buckets[first : last+1] {
+		labels = append(labels, bucket.Label())
+		values = append(values, bucket.Count)
+	}
+	return barChart(labels, values)
+}
+
+// statusChart строит распределение HTTP статусов
+func statusChart(r *Report) template.HTML {
+	codes := make([]int, 0, len(r.Summary.StatusCodes))
+	for code := range r.Summary.StatusCodes {
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return emptyChart()
+	}
+	slices.Sort(codes)
+
+	labels := make([]string, 0, len(codes))
+	values := make([]int, 0, len(codes))
+	for _, code := range codes {
+		labels = append(labels, fmt.Sprint(code))
+		values = append(values, r.Summary.StatusCodes[code])
+	}
+	return barChart(labels, values)
+}
+
+// barChart строит столбчатую диаграмму
+func barChart(labels []string, values []int) template.HTML {
+	peak := 1
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+
+	var b strings.Builder
+	openChart(&b)
+	axes(&b, "", "", fmt.Sprint(peak))
+	plotWidth, plotHeight := float64(chartWidth-2*chartMargin), float64(chartHeight-2*chartMargin)
+	slot := plotWidth / float64(len(values))
+	for i, v := range values {
+		height := plotHeight * float64(v) / float64(peak)
+		x := chartMargin + slot*float64(i) + slot*0.1
+		y := chartHeight - chartMargin - height
+		fmt.Fprintf(&b, `<rect class="bar" x="%.1f" y="%.1f" width="%.1f" height="%.1f"><title>%s: %d</title></rect>`,
+			x, y, slot*0.8, height, html.EscapeString(labels[i]), v)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`,
+			x+slot*0.4, chartHeight-chartMargin+15, html.EscapeString(labels[i]))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">%d</text>`, x+slot*0.4, y-4, v)
+	}
+	b.WriteString("</svg>")
+	return template.HTML(b.String())
+}
+
+// openChart открывает SVG элемент графика
+func openChart(b *strings.Builder) {
+	fmt.Fprintf(b, `<svg viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">`, chartWidth, chartHeight)
+}
+
+// axes рисует оси с подписями начала и конца оси X и максимума оси Y
+func axes(b *strings.Builder, xFrom, xTo, yMax string) {
+	bottom, right := chartHeight-chartMargin, chartWidth-chartMargin
+	fmt.Fprintf(b, `<line class="axis" x1="%d" y1="%d" x2="%d" y2="%d"/>`, chartMargin, bottom, right, bottom)
+	fmt.Fprintf(b, `<line class="axis" x1="%d" y1="%d" x2="%d" y2="%d"/>`, chartMargin, chartMargin, chartMargin, bottom)
+	fmt.Fprintf(b, `<text x="%d" y="%d" text-anchor="end">%s</text>`, chartMargin-4, chartMargin+4, html.EscapeString(yMax))
+	fmt.Fprintf(b, `<text x="%d" y="%d">%s</text>`, chartMargin, bottom+15, html.EscapeString(xFrom))
+	fmt.Fprintf(b, `<text x="%d" y="%d" text-anchor="end">%s</text>`, right, bottom+15, html.EscapeString(xTo))
+}
+
+// emptyChart возвращает заглушку для графика без данных
+func emptyChart() template.HTML {
+	return template.HTML(`<p class="muted">Нет данных</p>`)
+}
+
+// formatDuration округляет длительность для отчета
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.String()
+	}
+}
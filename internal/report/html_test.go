@@ -0,0 +1,57 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestWriteHTML проверяет автономный HTML отчет
+func TestWriteHTML(t *testing.T) {
+	r := testReport()
+	r.Records[1].BodyPreview = `{"error":"<boom>"}`
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, r); err != nil {
+		t.Fatalf("WriteHTML() вернул ошибку: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{"<svg", "<circle", `class="bar"`, "dump.2.json", "&lt;boom&gt;", "p99"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("отчет не содержит %q", want)
+		}
+	}
+	for _, external := range []string{"<script src", "<link", "http://cdn", "https://"} {
+		if strings.Contains(out, external) {
+			t.Errorf("отчет не должен ссылаться на внешние ресурсы: %q", external)
+		}
+	}
+}
+
+// TestWriteHTML_Empty проверяет отчет без результатов
+func TestWriteHTML_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, &Report{}); err != nil {
+		t.Fatalf("WriteHTML() вернул ошибку: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Нет данных") {
+		t.Error("пустой отчет должен содержать заглушки графиков")
+	}
+}
+
+// TestPreview проверяет обрезку тела ответа
+func TestPreview(t *testing.T) {
+	if got := Preview([]byte("short")); got != "short" {
+		t.Errorf("Preview() = %q, ожидалось %q", got, "short")
+	}
+
+	long := strings.Repeat("ж", previewSize) // 2 байта на символ
+	got := Preview([]byte(long))
+	if !strings.HasSuffix(got, "...") || len(got) > previewSize+3 {
+		t.Errorf("длинное тело обрезано неверно: %d байт", len(got))
+	}
+	if !strings.HasPrefix(got, "жж") || strings.ContainsRune(got, '�') {
+		t.Error("обрезка не должна ломать UTF-8")
+	}
+}
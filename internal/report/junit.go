@@ -0,0 +1,131 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitProblem `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit записывает отчет в формате JUnit XML: один testcase на файл запроса.
+// Неожиданный HTTP статус - failure, пропуск из-за зависимости - skipped, остальные ошибки (сеть, чтение, сохранение) - error.
+func WriteJUnit(w io.Writer, r *Report) error {
+	suite := junitTestSuite{
+		Name:  "poster",
+		Tests: len(r.Records),
+		Time:  seconds(r.Summary.WallTime),
+		Properties: []junitProperty{
+			{Name: "url", Value: r.URL},
+			{Name: "throughput", Value: fmt.Sprintf("%.2f", r.Summary.Throughput)},
+			{Name: "p50", Value: r.Summary.P50.String()},
+			{Name: "p95", Value: r.Summary.P95.String()},
+			{Name: "p99", Value: r.Summary.P99.String()},
+		},
+	}
+	if !r.Started.IsZero() {
+		suite.Timestamp = r.Started.Format("2006-01-02T15:04:05")
+	}
+
+	for _, rec := range r.Records {
+		testCase := junitTestCase{
+			Name:      rec.File,
+			Classname: "poster",
+			Time:      seconds(rec.Duration),
+		}
+		if rec.Method != "" {
+			testCase.SystemOut = fmt.Sprintf("%s %s -> %d, попыток: %d", rec.Method, rec.URL, rec.StatusCode, rec.Attempts)
+		}
+		if rec.Failed() {
+			problem := &junitProblem{Message: rec.Error, Type: rec.ErrorType, Text: problemText(rec)}
+			if rec.ErrorType == ErrSkipped {
+				testCase.Skipped = problem
+				suite.Skipped++
+			} else if IsFailure(rec.ErrorType) {
+				testCase.Failure = problem
+				suite.Failures++
+			} else {
+				testCase.Error = problem
+				suite.Errors++
+			}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	suites := junitTestSuites{
+		Name:     "poster",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// problemText описывает ошибку с позицией во входных данных
+func problemText(rec Record) string {
+	if rec.Line > 0 {
+		return fmt.Sprintf("%s (строка %d, смещение %d): %s", rec.File, rec.Line, rec.Offset, rec.Error)
+	}
+	return fmt.Sprintf("%s: %s", rec.File, rec.Error)
+}
+
+// seconds форматирует длительность в секундах для JUnit
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
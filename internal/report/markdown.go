@@ -0,0 +1,106 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// WriteMarkdown записывает итоговую сводку прогона в Markdown
+func WriteMarkdown(w io.Writer, r *Report) error {
+	s := r.Summary
+	var b strings.Builder
+
+	b.WriteString("# Poster: отчет о прогоне\n\n")
+	if !r.Started.IsZero() {
+		fmt.Fprintf(&b, "Запуск: %s, адрес: `%s`\n\n", r.Started.Format(time.RFC3339), r.URL)
+	}
+
+	b.WriteString("| Показатель | Значение |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Всего | %d |\n", s.Total)
+	fmt.Fprintf(&b, "| Успешно | %d (%.2f%%) |\n", s.Successful, s.SuccessRate)
+	fmt.Fprintf(&b, "| Ошибок | %d |\n", s.Failed)
+	fmt.Fprintf(&b, "| Время прогона | %v |\n", s.WallTime.Round(time.Millisecond))
+	fmt.Fprintf(&b, "| Пропускная способность | %.2f запросов/с |\n\n", s.Throughput)
+
+	if s.Successful > 0 {
+		b.WriteString("## Задержка\n\n| min | avg | p50 | p90 | p95 | p99 | max |\n|---|---|---|---|---|---|---|\n")
+		fmt.Fprintf(&b, "| %v | %v | %v | %v | %v | %v | %v |\n\n", s.Min, s.Avg, s.P50, s.P90, s.P95, s.P99, s.Max)
+	}
+	if c := s.Corrected; c != nil {
+		b.WriteString("## Задержка от расписания\n\n| min | avg | p50 | p90 | p95 | p99 | max |\n|---|---|---|---|---|---|---|\n")
+		fmt.Fprintf(&b, "| %v | %v | %v | %v | %v | %v | %v |\n\n", c.Min, c.Avg, c.P50, c.P90, c.P95, c.P99, c.Max)
+	}
+
+	if len(s.StatusCodes) > 0 {
+		b.WriteString("## HTTP статусы\n\n| Статус | Запросов |\n|---|---|\n")
+		codes := make([]int, 0, len(s.StatusCodes))
+		for code := range s.StatusCodes {
+			codes = append(codes, code)
+		}
+		slices.Sort(codes)
+		for _, code := range codes {
+			fmt.Fprintf(&b, "| %d | %d |\n", code, s.StatusCodes[code])
+		}
+		b.WriteString("\n")
+	}
+
+	if len(s.ErrorTypes) > 0 {
+		b.WriteString("## Типы ошибок\n\n| Тип | Запросов |\n|---|---|\n")
+		types := make([]string, 0, len(s.ErrorTypes))
+		for errType := range s.ErrorTypes {
+			types = append(types, errType)
+		}
+		slices.Sort(types)
+		for _, errType := range types {
+			fmt.Fprintf(&b, "| %s | %d |\n", errType, s.ErrorTypes[errType])
+		}
+		b.WriteString("\n")
+	}
+
+	if len(r.Scenarios) > 0 {
+		b.WriteString("## Сценарии\n\n| Сценарий | Итог | Шаги | Время | Ошибка |\n|---|---|---|---|---|\n")
+		for _, sc := range r.Scenarios {
+			outcome := "пройден"
+			if !sc.Passed {
+				outcome = "не пройден: " + escape(sc.FailedStep)
+			}
+			fmt.Fprintf(&b, "| %s | %s | %d/%d | %v | %s |\n",
+				escape(sc.Name), outcome, sc.Steps, sc.Total, sc.Duration.Round(time.Millisecond), escape(sc.Error))
+		}
+		b.WriteString("\n")
+	}
+
+	var failed []Record
+	for _, rec := range r.Records {
+		if rec.Failed() {
+			failed = append(failed, rec)
+		}
+	}
+	if len(failed) > 0 {
+		b.WriteString("## Ошибки\n\n| Файл | Строка | Статус | Тип | Ошибка |\n|---|---|---|---|---|\n")
+		for _, rec := range failed {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+				escape(rec.File), optional(rec.Line), optional(rec.StatusCode), rec.ErrorType, escape(rec.Error))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// optional печатает число или прочерк для нуля
+func optional(n int) string {
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprint(n)
+}
+
+// escape экранирует текст для ячейки таблицы Markdown
+func escape(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
@@ -0,0 +1,148 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"poster/internal/stats"
+	"strings"
+	"time"
+)
+
+// Форматы отчетов
+const (
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+	FormatJUnit    = "junit"
+	FormatMarkdown = "markdown"
+)
+
+// Record = результат обработки одного файла
+type Record struct {
+	File         string        `json:"file"`
+	Line         int           `json:"line,omitempty"`
+	Offset       int64         `json:"offset,omitempty"`
+	Method       string        `json:"method,omitempty"`
+	URL          string        `json:"url,omitempty"`
+	Start        time.Time     `json:"start"`
+	Duration     time.Duration `json:"duration_ns"`
+	StatusCode   int           `json:"status_code,omitempty"`
+	Attempts     int           `json:"attempts,omitempty"`
+	Backoff      time.Duration `json:"backoff_ns,omitempty"`  // Паузы перед повторами
+	Throttle     time.Duration `json:"throttle_ns,omitempty"` // Ожидание ограничителя скорости
+	FileSize     int64         `json:"file_size"`
+	RequestSize  int           `json:"request_size"`
+	ResponseSize int           `json:"response_size"`
+	Error        string        `json:"error,omitempty"`
+	ErrorType    string        `json:"error_type,omitempty"`   // Тип ошибки: ErrStatus, ErrTransport, ...
+	BodyPreview  string        `json:"body_preview,omitempty"` // Начало тела ответа для ошибочных запросов
+	Rendered     string        `json:"rendered,omitempty"`     // Начало запроса после подстановки шаблона
+}
+
+// Failed проверяет, завершилась ли обработка ошибкой
+func (r Record) Failed() bool {
+	return r.ErrorType != "" || r.Error != ""
+}
+
+// Report = отчет о прогоне: все результаты и итоговая статистика
+type Report struct {
+	Started   time.Time     `json:"started"`
+	Finished  time.Time     `json:"finished"`
+	URL       string        `json:"url"`
+	Summary   stats.Summary `json:"summary"`
+	Stages    []stats.Row   `json:"stages,omitempty"`    // Статистика по этапам нагрузки
+	Scenarios []Scenario    `json:"scenarios,omitempty"` // Итоги сценариев
+	Records   []Record      `json:"records"`
+}
+
+// Scenario = итог сценария: пройден, если все шаги выполнены без ошибок
+type Scenario struct {
+	Name       string        `json:"name"`
+	Passed     bool          `json:"passed"`
+	Steps      int           `json:"steps"`       // Выполнено шагов
+	Total      int           `json:"total_steps"` // Шагов в сценарии
+	FailedStep string        `json:"failed_step,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration_ns"`
+}
+
+// FormatOf определяет формат отчета по расширению файла
+func FormatOf(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".csv":
+		return FormatCSV, nil
+	case ".xml":
+		return FormatJUnit, nil
+	case ".md":
+		return FormatMarkdown, nil
+	case ".html", ".htm":
+		return FormatHTML, nil
+	default:
+		return "", fmt.Errorf("неизвестный формат отчета %q: ожидалось .json, .csv, .xml (JUnit), .md или .html", path)
+	}
+}
+
+// Write записывает отчет в файл, формат определяется по расширению
+func Write(path string, r *Report) error {
+	format, err := FormatOf(path)
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatJSON:
+		err = WriteJSON(file, r)
+	case FormatCSV:
+		err = WriteCSV(file, r)
+	case FormatJUnit:
+		err = WriteJUnit(file, r)
+	case FormatMarkdown:
+		err = WriteMarkdown(file, r)
+	case FormatHTML:
+		err = WriteHTML(file, r)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("запись отчета %s: %v", path, err)
+	}
+	return nil
+}
+
+// WriteJSON записывает отчет в JSON
+func WriteJSON(w io.Writer, r *Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// Read читает JSON отчет прошлого прогона, чтобы построить по нему другие отчеты
+func Read(path string) (*Report, error) {
+	if format, err := FormatOf(path); err != nil || format != FormatJSON {
+		return nil, fmt.Errorf("чтение отчета %s: ожидался JSON отчет", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("чтение отчета %s: %v", path, err)
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("чтение отчета %s: %v", path, err)
+	}
+	return &r, nil
+}
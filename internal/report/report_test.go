@@ -0,0 +1,199 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"poster/internal/stats"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testReport создает отчет с успешным запросом, HTTP ошибкой и сетевой ошибкой
+func testReport() *Report {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{File: "a.json", Method: "POST", URL: "http://x/a", Start: start, Duration: 20 * time.Millisecond, StatusCode: 200, Attempts: 1, RequestSize: 7, ResponseSize: 42},
+		{File: "dump.2.json", Line: 2, Offset: 8, Method: "PUT", URL: "http://x/b", Start: start, Duration: 30 * time.Millisecond, StatusCode: 500, Attempts: 3, Error: "сервер вернул статус: 500", ErrorType: "http_status"},
+		{File: "c|d.json", Start: start, Duration: time.Second, Attempts: 1, Error: "connection refused", ErrorType: "transport"},
+	}
+
+	agg := stats.New()
+	for _, rec := range records {
+		agg.Add(stats.Sample{Start: rec.Start, Duration: rec.Duration, StatusCode: rec.StatusCode, ErrType: rec.ErrorType})
+	}
+	return &Report{Started: start, Finished: start.Add(time.Second), URL: "http://x", Summary: agg.Summary(), Records: records}
+}
+
+// TestFormatOf проверяет определение формата по расширению
+func TestFormatOf(t *testing.T) {
+	tests := map[string]string{
+		"out/report.json": FormatJSON,
+		"report.CSV":      FormatCSV,
+		"junit.xml":       FormatJUnit,
+		"summary.md":      FormatMarkdown,
+	}
+	for path, want := range tests {
+		got, err := FormatOf(path)
+		if err != nil || got != want {
+			t.Errorf("FormatOf(%q) = %q, %v, ожидалось %q", path, got, err, want)
+		}
+	}
+	if _, err := FormatOf("report.txt"); err == nil {
+		t.Error("ожидалась ошибка для неизвестного расширения")
+	}
+}
+
+// TestWriteJSON проверяет, что JSON отчет читается обратно
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, testReport()); err != nil {
+		t.Fatalf("WriteJSON() вернул ошибку: %v", err)
+	}
+
+	var got Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("невалидный JSON: %v", err)
+	}
+	if len(got.Records) != 3 || got.Summary.Failed != 2 {
+		t.Errorf("прочитано записей %d, ошибок %d", len(got.Records), got.Summary.Failed)
+	}
+	if got.Records[1].Line != 2 || got.Records[1].Attempts != 3 {
+		t.Errorf("запись прочитана неверно: %+v", got.Records[1])
+	}
+}
+
+// TestWriteCSV проверяет CSV отчет
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testReport()); err != nil {
+		t.Fatalf("WriteCSV() вернул ошибку: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("невалидный CSV: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("строк %d, ожидалось 4 (заголовок + 3)", len(rows))
+	}
+	if rows[1][6] != "20.000" {
+		t.Errorf("duration_ms = %q, ожидалось 20.000", rows[1][6])
+	}
+	if rows[2][12] != "http_status" {
+		t.Errorf("error_type = %q, ожидалось http_status", rows[2][12])
+	}
+}
+
+// TestWriteJUnit проверяет JUnit XML отчет
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, testReport()); err != nil {
+		t.Fatalf("WriteJUnit() вернул ошибку: %v", err)
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("невалидный XML: %v", err)
+	}
+	if suites.Tests != 3 || suites.Failures != 1 || suites.Errors != 1 {
+		t.Errorf("tests/failures/errors = %d/%d/%d, ожидалось 3/1/1", suites.Tests, suites.Failures, suites.Errors)
+	}
+	cases := suites.Suites[0].Cases
+	if cases[0].Failure != nil || cases[0].Error != nil {
+		t.Error("успешный запрос не должен содержать failure/error")
+	}
+	if cases[1].Failure == nil || !strings.Contains(cases[1].Failure.Text, "строка 2") {
+		t.Errorf("HTTP ошибка должна быть failure с номером строки: %+v", cases[1].Failure)
+	}
+	if cases[2].Error == nil || cases[2].Error.Type != "transport" {
+		t.Errorf("сетевая ошибка должна быть error: %+v", cases[2].Error)
+	}
+
+	// Пропущенный из-за зависимости запрос - skipped, а не ошибка
+	r := testReport()
+	r.Records = append(r.Records, Record{File: "e.json", Error: "зависимость a.json не выполнена", ErrorType: "skipped"})
+	buf.Reset()
+	if err := WriteJUnit(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	var withSkipped junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &withSkipped); err != nil {
+		t.Fatalf("невалидный XML: %v", err)
+	}
+	if withSkipped.Skipped != 1 || withSkipped.Errors != 1 || withSkipped.Suites[0].Cases[3].Skipped == nil {
+		t.Errorf("skipped/errors = %d/%d, ожидалось 1/1", withSkipped.Skipped, withSkipped.Errors)
+	}
+}
+
+// TestWriteMarkdown проверяет Markdown сводку
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	r := testReport()
+	r.Scenarios = []Scenario{
+		{Name: "orders", Passed: true, Steps: 2, Total: 2},
+		{Name: "users", Steps: 1, Total: 3, FailedStep: "get", Error: "HTTP 500"},
+	}
+	if err := WriteMarkdown(&buf, r); err != nil {
+		t.Fatalf("WriteMarkdown() вернул ошибку: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"| Всего | 3 |", "| 500 | 1 |", "| transport | 1 |", `c\|d.json`, "p99",
+		"| orders | пройден | 2/2 |", "| users | не пройден: get | 1/3 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("сводка не содержит %q:\n%s", want, out)
+		}
+	}
+}
+
+// TestWrite проверяет запись в файлы по расширению
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"r.json", "r.csv", "sub/r.xml", "r.md", "r.html"} {
+		path := filepath.Join(dir, name)
+		if err := Write(path, testReport()); err != nil {
+			t.Fatalf("Write(%s) вернул ошибку: %v", name, err)
+		}
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("отчет %s не записан", name)
+		}
+	}
+	if err := Write(filepath.Join(dir, "r.txt"), testReport()); err == nil {
+		t.Error("ожидалась ошибка для неизвестного формата")
+	}
+}
+
+// TestRead проверяет чтение JSON отчета
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "results.json")
+	if err := Write(path, testReport()); err != nil {
+		t.Fatalf("Write() вернул ошибку: %v", err)
+	}
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() вернул ошибку: %v", err)
+	}
+	want := testReport()
+	if got.Summary.Total != want.Summary.Total || len(got.Records) != len(want.Records) || got.Records[0].File != want.Records[0].File {
+		t.Errorf("Read() = %+v, ожидалось %+v", got, want)
+	}
+	if got.Summary.P99 != want.Summary.P99 || got.Summary.StatusCodes[500] != want.Summary.StatusCodes[500] {
+		t.Errorf("статистика прочитана неверно: %+v", got.Summary)
+	}
+
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{broken, filepath.Join(dir, "missing.json"), filepath.Join(dir, "r.csv")} {
+		if _, err := Read(path); err == nil {
+			t.Errorf("Read(%s): ожидалась ошибка", path)
+		}
+	}
+}
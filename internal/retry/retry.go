@@ -0,0 +1,148 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultStatusCodes = статусы, при которых запрос повторяется по умолчанию
+const DefaultStatusCodes = "429,500-599"
+
+// Policy = политика повторов запроса
+type Policy struct {
+	MaxAttempts int           // Максимальное число попыток (1 = без повторов)
+	BaseDelay   time.Duration // Задержка перед первым повтором
+	MaxDelay    time.Duration // Максимальная задержка между попытками
+	Jitter      float64       // Доля случайного разброса задержки [0..1]
+	StatusCodes []int         // HTTP статусы, при которых запрос повторяется
+	Network     bool          // Повторять запрос при сетевых ошибках
+}
+
+// Retryable проверяет, нужно ли повторить запрос с таким результатом
+func (p Policy) Retryable(statusCode int, err error) bool {
+	if statusCode > 0 {
+		return slices.Contains(p.StatusCodes, statusCode)
+	}
+	if err == nil || !p.Network {
+		return false
+	}
+	return IsNetworkError(err)
+}
+
+// Delay вычисляет задержку перед следующей попыткой.
+// attempt - номер завершившейся попытки (с 1).
+// Заголовок Retry-After имеет приоритет над экспоненциальной задержкой, но не превышает MaxDelay.
+func (p Policy) Delay(attempt int, header http.Header) time.Duration {
+	if after, ok := RetryAfter(header, time.Now()); ok {
+		return min(after, p.MaxDelay)
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+
+	if p.Jitter > 0 {
+		// Равномерный разброс в пределах ±Jitter от задержки
+		delta := float64(delay) * p.Jitter * (2*rand.Float64() - 1)
+		delay = min(time.Duration(float64(delay)+delta), p.MaxDelay)
+	}
+	return max(delay, 0)
+}
+
+// RetryAfter разбирает заголовок Retry-After: число секунд или HTTP дата
+func RetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// IsNetworkError проверяет, является ли ошибка временной сетевой ошибкой: таймаут, обрыв или отказ
+// в соединении. Неверный URL, неподдерживаемая схема и ошибки сертификата повтором не исправить.
+func IsNetworkError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	// Все ошибки client.Do обернуты в *url.Error, а он сам реализует net.Error: смотрим на причину
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// ParseStatusCodes разбирает список статусов: "429,500-599"
+func ParseStatusCodes(s string) ([]int, error) {
+	var codes []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := parseStatusCode(from)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = parseStatusCode(to); err != nil {
+				return nil, err
+			}
+			if last < first {
+				return nil, fmt.Errorf("некорректный диапазон статусов: %s", part)
+			}
+		}
+		for code := first; code <= last; code++ {
+			if !slices.Contains(codes, code) {
+				codes = append(codes, code)
+			}
+		}
+	}
+	return codes, nil
+}
+
+// parseStatusCode разбирает один HTTP статус
+func parseStatusCode(s string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || code < 100 || code > 599 {
+		return 0, fmt.Errorf("некорректный HTTP статус: %q", s)
+	}
+	return code, nil
+}
@@ -0,0 +1,183 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestRetryable проверяет, какие результаты считаются повторяемыми
+func TestRetryable(t *testing.T) {
+	policy := Policy{StatusCodes: []int{429, 503}, Network: true}
+
+	tests := []struct {
+		name   string
+		status int
+		err    error
+		want   bool
+	}{
+		{"успех", 200, nil, false},
+		{"429", 429, fmt.Errorf("сервер вернул статус: 429"), true},
+		{"503", 503, fmt.Errorf("сервер вернул статус: 503"), true},
+		{"400", 400, fmt.Errorf("сервер вернул статус: 400"), false},
+		{"отказ в соединении", 0, &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{"таймаут", 0, context.DeadlineExceeded, true},
+		{"отмена", 0, context.Canceled, false},
+		{"прочая ошибка", 0, errors.New("unsupported protocol scheme"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := policy.Retryable(test.status, test.err); got != test.want {
+				t.Errorf("Retryable(%d, %v) = %v, ожидалось %v", test.status, test.err, got, test.want)
+			}
+		})
+	}
+
+	policy.Network = false
+	if policy.Retryable(0, syscall.ECONNRESET) {
+		t.Error("сетевые ошибки не должны повторяться при Network = false")
+	}
+}
+
+// TestIsNetworkError проверяет ошибки client.Do: *url.Error повторяется только по сетевой причине
+func TestIsNetworkError(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	requestErr := func(target string) error {
+		_, err := http.Get(target) // Клиент без сертификата тестового сервера
+		return err
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"обрыв соединения", &url.Error{Op: "Post", URL: "http://a", Err: io.ErrUnexpectedEOF}, true},
+		{"сброс соединения", &url.Error{Op: "Post", URL: "http://a", Err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}}, true},
+		{"таймаут клиента", &url.Error{Op: "Post", URL: "http://a", Err: &net.DNSError{IsTimeout: true}}, true},
+		{"неподдерживаемая схема", requestErr("ftp://localhost/"), false},
+		{"неверный URL", requestErr("http://[::1"), false},
+		{"сертификат x509", requestErr(server.URL), false},
+		{"отмена", &url.Error{Op: "Post", URL: "http://a", Err: context.Canceled}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.err == nil {
+				t.Fatal("запрос неожиданно выполнен")
+			}
+			if got := IsNetworkError(test.err); got != test.want {
+				t.Errorf("IsNetworkError(%v) = %v, ожидалось %v", test.err, got, test.want)
+			}
+		})
+	}
+}
+
+// TestDelay_Exponential проверяет экспоненциальный рост и ограничение задержки
+func TestDelay_Exponential(t *testing.T) {
+	policy := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, w := range want {
+		if got := policy.Delay(i+1, http.Header{}); got != w {
+			t.Errorf("Delay(%d) = %v, ожидалось %v", i+1, got, w)
+		}
+	}
+}
+
+// TestDelay_Jitter проверяет границы случайного разброса
+func TestDelay_Jitter(t *testing.T) {
+	policy := Policy{BaseDelay: time.Second, MaxDelay: time.Minute, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		got := policy.Delay(1, http.Header{})
+		if got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatalf("Delay() = %v вне диапазона [500ms..1.5s]", got)
+		}
+	}
+}
+
+// TestDelay_RetryAfter проверяет приоритет заголовка Retry-After
+func TestDelay_RetryAfter(t *testing.T) {
+	policy := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second, Jitter: 0.5}
+
+	header := http.Header{}
+	header.Set("Retry-After", "3")
+	if got := policy.Delay(1, header); got != 3*time.Second {
+		t.Errorf("Delay() = %v, ожидалось 3s", got)
+	}
+
+	header.Set("Retry-After", "120")
+	if got := policy.Delay(1, header); got != 5*time.Second {
+		t.Errorf("Delay() = %v, Retry-After должен ограничиваться MaxDelay", got)
+	}
+}
+
+// TestRetryAfter проверяет разбор заголовка Retry-After
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"нет заголовка", "", 0, false},
+		{"секунды", "7", 7 * time.Second, true},
+		{"дата", now.Add(10 * time.Second).Format(http.TimeFormat), 10 * time.Second, true},
+		{"дата в прошлом", now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"мусор", "скоро", 0, false},
+		{"отрицательное", "-5", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := http.Header{}
+			if test.value != "" {
+				header.Set("Retry-After", test.value)
+			}
+			got, ok := RetryAfter(header, now)
+			if ok != test.wantOK || got != test.want {
+				t.Errorf("RetryAfter() = %v, %v, ожидалось %v, %v", got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
+
+// TestParseStatusCodes проверяет разбор списка статусов
+func TestParseStatusCodes(t *testing.T) {
+	codes, err := ParseStatusCodes("429, 502-504,503")
+	if err != nil {
+		t.Fatalf("ParseStatusCodes() вернул ошибку: %v", err)
+	}
+	want := []int{429, 502, 503, 504}
+	if fmt.Sprint(codes) != fmt.Sprint(want) {
+		t.Errorf("ParseStatusCodes() = %v, ожидалось %v", codes, want)
+	}
+
+	codes, err = ParseStatusCodes(DefaultStatusCodes)
+	if err != nil || len(codes) != 101 {
+		t.Errorf("DefaultStatusCodes разобран неверно: %d кодов, ошибка %v", len(codes), err)
+	}
+
+	for _, bad := range []string{"abc", "99", "600", "504-502"} {
+		if _, err := ParseStatusCodes(bad); err == nil {
+			t.Errorf("ожидалась ошибка для %q", bad)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"poster/internal/jsonpath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// headerPrefix = префикс правила извлечения из заголовка ответа: "header:Location"
+const headerPrefix = "header:"
+
+// Scenario = сценарий: шаги выполняются по порядку, переменные из ответов подставляются в следующие шаги
+type Scenario struct {
+	Name  string            // Имя сценария (по умолчанию - имя файла без расширения)
+	Path  string            // Файл сценария
+	Vars  map[string]string // Начальные переменные сценария
+	Steps []Step
+}
+
+// Step = шаг сценария: файл запроса и правила извлечения переменных из ответа
+type Step struct {
+	Name    string // Имя шага (по умолчанию - имя файла запроса без расширения)
+	Request string // Путь к файлу запроса
+	Rules   []Rule // Правила извлечения в порядке имен переменных
+}
+
+// Rule = правило извлечения: переменная получает значение по JSONPath из тела или из заголовка ответа
+type Rule struct {
+	Var    string
+	Expr   string         // Исходное выражение правила
+	Header string         // Заголовок ответа (пусто - JSONPath)
+	path   *jsonpath.Path // JSONPath в теле ответа
+}
+
+// file = файл сценария
+type file struct {
+	Name  string         `json:"name" yaml:"name"`
+	Vars  map[string]any `json:"vars" yaml:"vars"`
+	Steps []struct {
+		Name    string            `json:"name" yaml:"name"`
+		Request string            `json:"request" yaml:"request"`
+		Extract map[string]string `json:"extract" yaml:"extract"`
+	} `json:"steps" yaml:"steps"`
+}
+
+// jsonSuffix = окончание JSON файла сценария: обычные .json рядом со сценариями - файлы запросов
+const jsonSuffix = ".scenario.json"
+
+// IsScenario проверяет имя файла сценария: .yaml, .yml или .scenario.json
+func IsScenario(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return strings.HasSuffix(strings.ToLower(path), jsonSuffix)
+}
+
+// Collect загружает сценарий из файла или все сценарии директории (по имени файла).
+// Имена сценариев должны быть уникальны: по ним называются директории ответов.
+func Collect(path string) ([]*Scenario, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		s, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		return []*Scenario{s}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var scenarios []*Scenario
+	names := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !IsScenario(entry.Name()) {
+			continue
+		}
+		s, err := Load(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := names[s.Name]; ok {
+			return nil, fmt.Errorf("сценарий %q задан в %s и %s", s.Name, other, s.Path)
+		}
+		names[s.Name] = s.Path
+		scenarios = append(scenarios, s)
+	}
+	return scenarios, nil
+}
+
+// Load читает сценарий .yaml, .yml или .scenario.json. Пути запросов - относительно файла сценария.
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&f)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		decoder.UseNumber()
+		err = decoder.Decode(&f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("сценарий %s: %v", path, err)
+	}
+
+	s := &Scenario{Name: f.Name, Path: path, Vars: make(map[string]string, len(f.Vars))}
+	if s.Name == "" {
+		s.Name = filepath.Base(path)
+		if strings.HasSuffix(strings.ToLower(s.Name), jsonSuffix) {
+			s.Name = s.Name[:len(s.Name)-len(jsonSuffix)]
+		} else {
+			s.Name = strings.TrimSuffix(s.Name, filepath.Ext(s.Name))
+		}
+	}
+	if strings.ContainsAny(s.Name, `/\`) || s.Name == "." || s.Name == ".." {
+		return nil, fmt.Errorf("сценарий %s: имя %q не может быть именем директории ответов", path, s.Name)
+	}
+	for name, value := range f.Vars {
+		if s.Vars[name], err = format(value); err != nil {
+			return nil, fmt.Errorf("сценарий %s: переменная %s: %v", path, name, err)
+		}
+	}
+	if len(f.Steps) == 0 {
+		return nil, fmt.Errorf("сценарий %s: нет шагов", path)
+	}
+
+	stepNames := make(map[string]bool)
+	for i, item := range f.Steps {
+		if item.Request == "" {
+			return nil, fmt.Errorf("сценарий %s: шаг %d: не задан request", path, i+1)
+		}
+		step := Step{Name: item.Name, Request: item.Request}
+		if !filepath.IsAbs(step.Request) {
+			step.Request = filepath.Join(filepath.Dir(path), step.Request)
+		}
+		if _, err := os.Stat(step.Request); err != nil {
+			return nil, fmt.Errorf("сценарий %s: шаг %d: %v", path, i+1, err)
+		}
+		if step.Name == "" {
+			step.Name = strings.TrimSuffix(filepath.Base(step.Request), filepath.Ext(step.Request))
+		}
+		if stepNames[step.Name] {
+			return nil, fmt.Errorf("сценарий %s: шаг %q повторяется, задайте name", path, step.Name)
+		}
+		stepNames[step.Name] = true
+
+		for name, expr := range item.Extract {
+			rule, err := ParseRule(name, expr)
+			if err != nil {
+				return nil, fmt.Errorf("сценарий %s: шаг %s: %v", path, step.Name, err)
+			}
+			step.Rules = append(step.Rules, rule)
+		}
+		sort.Slice(step.Rules, func(i, j int) bool { return step.Rules[i].Var < step.Rules[j].Var })
+		s.Steps = append(s.Steps, step)
+	}
+	return s, nil
+}
+
+// ResponseName возвращает имя ответа шага: директория сценария и номер шага, чтобы ответы шли по порядку
+func (s *Scenario) ResponseName(i int) string {
+	return fmt.Sprintf("%s/%02d-%s.json", s.Name, i+1, s.Steps[i].Name)
+}
+
+// ParseRule разбирает правило извлечения: "$.id" (JSONPath в теле) или "header:Location"
+func ParseRule(name, expr string) (Rule, error) {
+	rule := Rule{Var: name, Expr: expr}
+	if name == "" {
+		return rule, fmt.Errorf("пустое имя переменной")
+	}
+	if header, ok := strings.CutPrefix(expr, headerPrefix); ok {
+		if rule.Header = strings.TrimSpace(header); rule.Header == "" {
+			return rule, fmt.Errorf("%s: пустое имя заголовка", name)
+		}
+		return rule, nil
+	}
+	path, err := jsonpath.Parse(expr)
+	if err != nil {
+		return rule, fmt.Errorf("%s: %v", name, err)
+	}
+	rule.path = path
+	return rule, nil
+}
+
+// Extract возвращает переменные из ответа шага. Не найденное значение - ошибка: следующие шаги без него бессмысленны.
+func (s Step) Extract(body []byte, header http.Header) (map[string]string, error) {
+	vars := make(map[string]string, len(s.Rules))
+	var doc any
+	parsed := false
+	for _, rule := range s.Rules {
+		if rule.Header != "" {
+			value := header.Get(rule.Header)
+			if value == "" {
+				return nil, fmt.Errorf("%s: нет заголовка %s", rule.Var, rule.Header)
+			}
+			vars[rule.Var] = value
+			continue
+		}
+
+		if !parsed {
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.UseNumber()
+			if err := decoder.Decode(&doc); err != nil {
+				return nil, fmt.Errorf("%s: ответ не JSON: %v", rule.Var, err)
+			}
+			parsed = true
+		}
+		value, ok := rule.path.Get(doc)
+		if !ok {
+			return nil, fmt.Errorf("%s: нет значения по пути %s", rule.Var, rule.Expr)
+		}
+		text, err := format(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", rule.Var, err)
+		}
+		vars[rule.Var] = text
+	}
+	return vars, nil
+}
+
+// format приводит значение к строке подстановки: строки как есть, остальное - JSON
+func format(value any) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
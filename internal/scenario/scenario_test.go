@@ -0,0 +1,144 @@
+package scenario
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles создает файлы во временной директории
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// TestCollect проверяет загрузку сценариев из директории
+func TestCollect(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"create.json": `{"url": "/orders"}`,
+		"get.json":    `{"method": "GET", "url": "/orders/${order_id}"}`,
+		"orders.yaml": `
+vars:
+  sku: A-1
+  qty: 2
+steps:
+  - request: create.json
+    extract:
+      order_id: $.id
+      location: "header:Location"
+  - request: get.json
+  - name: get-again
+    request: get.json
+`,
+		"users.scenario.json": `{"name": "users", "steps": [{"request": "get.json"}]}`,
+		"notes.txt":           "не сценарий",
+	})
+
+	scenarios, err := Collect(dir)
+	if err != nil {
+		t.Fatalf("Collect() вернул ошибку: %v", err)
+	}
+	if len(scenarios) != 2 {
+		t.Fatalf("сценариев = %d, ожидалось 2", len(scenarios))
+	}
+
+	orders := scenarios[0]
+	if orders.Name != "orders" || !reflect.DeepEqual(orders.Vars, map[string]string{"sku": "A-1", "qty": "2"}) {
+		t.Errorf("сценарий = %s, переменные %v", orders.Name, orders.Vars)
+	}
+	var names []string
+	for _, step := range orders.Steps {
+		names = append(names, step.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"create", "get", "get-again"}) {
+		t.Errorf("шаги = %v", names)
+	}
+	if orders.Steps[0].Request != filepath.Join(dir, "create.json") {
+		t.Errorf("путь запроса = %s, ожидался относительно файла сценария", orders.Steps[0].Request)
+	}
+	if rules := orders.Steps[0].Rules; len(rules) != 2 || rules[0].Var != "location" || rules[0].Header != "Location" || rules[1].Var != "order_id" {
+		t.Errorf("правила извлечения = %+v", rules)
+	}
+	if got := orders.ResponseName(2); got != "orders/03-get-again.json" {
+		t.Errorf("ResponseName() = %s", got)
+	}
+	if scenarios[1].Name != "users" {
+		t.Errorf("имя сценария .scenario.json = %s", scenarios[1].Name)
+	}
+}
+
+// TestLoad_Errors проверяет ошибки в файлах сценариев
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"нет шагов", "steps: []", "нет шагов"},
+		{"нет request", "steps:\n  - name: a", "не задан request"},
+		{"нет файла запроса", "steps:\n  - request: missing.json", "шаг 1"},
+		{"повтор шага", "steps:\n  - request: a.json\n  - request: a.json", "повторяется"},
+		{"неизвестный ключ", "steps:\n  - request: a.json\n    extrakt: {}", "extrakt"},
+		{"неверный JSONPath", "steps:\n  - request: a.json\n    extract: {id: \"$[\"}", "id"},
+		{"пустой заголовок", "steps:\n  - request: a.json\n    extract: {id: \"header:\"}", "пустое имя заголовка"},
+		{"имя с разделителем", "name: a/b\nsteps:\n  - request: a.json", "директории ответов"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"a.json": "{}", "s.yaml": test.content})
+			_, err := Load(filepath.Join(dir, "s.yaml"))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Load() = %v, ожидалась ошибка с %q", err, test.want)
+			}
+		})
+	}
+
+	dir := writeFiles(t, map[string]string{
+		"a.json": "{}",
+		"a.yaml": "name: same\nsteps:\n  - request: a.json",
+		"b.yml":  "name: same\nsteps:\n  - request: a.json",
+	})
+	if _, err := Collect(dir); err == nil || !strings.Contains(err.Error(), "same") {
+		t.Errorf("повтор имени сценария: %v", err)
+	}
+}
+
+// TestStep_Extract проверяет извлечение переменных из ответа
+func TestStep_Extract(t *testing.T) {
+	var step Step
+	for name, expr := range map[string]string{"id": "$.id", "tags": "$.tags", "first": "$.items[0].sku", "location": "header:Location"} {
+		rule, err := ParseRule(name, expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		step.Rules = append(step.Rules, rule)
+	}
+	header := http.Header{"Location": []string{"/orders/42"}}
+
+	vars, err := step.Extract([]byte(`{"id": 42, "tags": ["a"], "items": [{"sku": "X-1"}]}`), header)
+	if err != nil {
+		t.Fatalf("Extract() вернул ошибку: %v", err)
+	}
+	want := map[string]string{"id": "42", "tags": `["a"]`, "first": "X-1", "location": "/orders/42"}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("Extract() = %v, ожидалось %v", vars, want)
+	}
+
+	for name, body := range map[string]string{"нет значения": `{"id": 1, "tags": []}`, "не JSON": `oops`} {
+		if _, err := step.Extract([]byte(body), header); err == nil {
+			t.Errorf("%s: ожидалась ошибка", name)
+		}
+	}
+	if _, err := step.Extract([]byte(`{"id": 1, "tags": [], "items": [{"sku": "a"}]}`), http.Header{}); err == nil {
+		t.Error("нет заголовка: ожидалась ошибка")
+	}
+}
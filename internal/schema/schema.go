@@ -0,0 +1,108 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// printer = язык сообщений о нарушениях схемы
+var printer = message.NewPrinter(language.English)
+
+// Schema = скомпилированная JSON Schema. Без $schema в файле используется draft 2020-12.
+// Проверка безопасна при одновременном вызове из нескольких воркеров.
+type Schema struct {
+	Path   string
+	schema *jsonschema.Schema
+}
+
+// Load читает и компилирует схему из файла. Ссылки $ref на соседние файлы разрешаются относительно него.
+func Load(path string) (*Schema, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("схема %s: %v", path, err)
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	compiled, err := compiler.Compile(abs)
+	if err != nil {
+		return nil, fmt.Errorf("схема %s: %v", path, err)
+	}
+	return &Schema{Path: path, schema: compiled}, nil
+}
+
+// Violation = нарушение схемы: место в документе (JSON Pointer, "" - корень) и причина
+type Violation struct {
+	Path    string
+	Message string
+}
+
+// String печатает нарушение: "/items/0/id: missing property ..."
+func (v Violation) String() string {
+	path := v.Path
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + v.Message
+}
+
+// Error = документ не соответствует схеме
+type Error struct {
+	Violations []Violation
+}
+
+// Error перечисляет нарушения через точку с запятой
+func (e *Error) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Validate проверяет JSON документ по схеме. Несоответствие возвращается как *Error.
+func (s *Schema) Validate(data []byte) error {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return &Error{Violations: []Violation{{Message: fmt.Sprintf("не JSON: %v", err)}}}
+	}
+
+	err = s.schema.Validate(doc)
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+	result := &Error{}
+	collect(validationErr, result)
+	return result
+}
+
+// collect собирает конечные нарушения: промежуточные узлы дерева ошибок (allOf, $ref) лишь группируют их
+func collect(err *jsonschema.ValidationError, result *Error) {
+	if len(err.Causes) == 0 {
+		result.Violations = append(result.Violations, Violation{
+			Path:    pointer(err.InstanceLocation),
+			Message: err.ErrorKind.LocalizedString(printer),
+		})
+		return
+	}
+	for _, cause := range err.Causes {
+		collect(cause, result)
+	}
+}
+
+// pointer собирает JSON Pointer из пути в документе
+func pointer(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		token = strings.ReplaceAll(token, "~", "~0")
+		b.WriteString("/" + strings.ReplaceAll(token, "/", "~1"))
+	}
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeSchema записывает схему во временную директорию
+func writeSchema(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestValidate проверяет нарушения схемы с путями в документе
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	writeSchema(t, dir, "item.json", `{
+		"type": "object",
+		"required": ["id"],
+		"properties": {"id": {"type": "integer", "minimum": 1}}
+	}`)
+	path := writeSchema(t, dir, "order.json", `{
+		"type": "object",
+		"required": ["items"],
+		"properties": {
+			"items": {"type": "array", "items": {"$ref": "item.json"}},
+			"a/b": {"type": "string"}
+		},
+		"unevaluatedProperties": false
+	}`)
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() вернул ошибку: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+		want []string // Пути нарушений
+	}{
+		{"соответствует", `{"items": [{"id": 1}], "a/b": "x"}`, nil},
+		{"нет обязательного поля", `{}`, []string{""}},
+		{"нарушения в элементах по $ref", `{"items": [{"id": 0}, {}, {"id": "x"}]}`, []string{"/items/0/id", "/items/1", "/items/2/id"}},
+		{"экранирование пути", `{"items": [], "a/b": 1}`, []string{"/a~1b"}},
+		{"лишнее поле (2020-12)", `{"items": [], "extra": 1}`, []string{"/extra"}},
+		{"не JSON", `{`, []string{""}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := s.Validate([]byte(test.doc))
+			if test.want == nil {
+				if err != nil {
+					t.Errorf("Validate() = %v, ожидалось соответствие", err)
+				}
+				return
+			}
+			var schemaErr *Error
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("Validate() = %v, ожидалась *Error", err)
+			}
+			var got []string
+			for _, v := range schemaErr.Violations {
+				got = append(got, v.Path)
+				if v.Message == "" {
+					t.Errorf("пустая причина нарушения %q", v.Path)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("пути нарушений = %q, ожидалось %q (%v)", got, test.want, err)
+			}
+		})
+	}
+}
+
+// TestLoad проверяет ошибки загрузки схемы
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"missing.json": "",
+		"broken.json":  `{"type": `,
+		"invalid.json": `{"type": "nope"}`,
+	} {
+		path := filepath.Join(dir, name)
+		if content != "" {
+			path = writeSchema(t, dir, name, content)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("Load(%s): ожидалась ошибка", name)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package source
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// regexPrefix = префикс шаблона-регулярного выражения: "re:^orders/.*\.json$"
+const regexPrefix = "re:"
+
+// Pattern = шаблон пути запроса относительно директории запросов (через "/"):
+// glob, где * и ? не выходят за пределы одной директории, а ** - любое количество директорий,
+// или регулярное выражение с префиксом "re:"
+type Pattern struct {
+	source   string
+	segments []string       // Части glob шаблона между "/"
+	regexp   *regexp.Regexp // Регулярное выражение (nil - glob)
+}
+
+// ParsePattern разбирает шаблон: "orders/**", "**/*.draft.json", "re:_v[0-9]+\.json$"
+func ParsePattern(s string) (Pattern, error) {
+	p := Pattern{source: s}
+	if expr, ok := strings.CutPrefix(s, regexPrefix); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return p, fmt.Errorf("шаблон %q: %v", s, err)
+		}
+		p.regexp = re
+		return p, nil
+	}
+
+	if s == "" {
+		return p, fmt.Errorf("пустой шаблон")
+	}
+	p.segments = strings.Split(strings.Trim(s, "/"), "/")
+	for _, segment := range p.segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return p, fmt.Errorf("шаблон %q: %v", s, err)
+		}
+	}
+	return p, nil
+}
+
+// String возвращает исходный шаблон
+func (p Pattern) String() string {
+	return p.source
+}
+
+// Match проверяет путь запроса относительно директории запросов
+func (p Pattern) Match(name string) bool {
+	if p.regexp != nil {
+		return p.regexp.MatchString(name)
+	}
+	return matchSegments(p.segments, strings.Split(name, "/"))
+}
+
+// matchSegments сопоставляет части glob шаблона с частями пути; ** совпадает с любым количеством частей, в том числе с нулем
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// Filter = отбор файлов запросов: файл берется, если подходит под один из Include (или Include пуст)
+// и не подходит ни под один из Exclude
+type Filter struct {
+	Include []Pattern
+	Exclude []Pattern
+}
+
+// NewFilter разбирает шаблоны отбора файлов
+func NewFilter(include, exclude []string) (Filter, error) {
+	var f Filter
+	for _, s := range include {
+		p, err := ParsePattern(s)
+		if err != nil {
+			return f, fmt.Errorf("include: %v", err)
+		}
+		f.Include = append(f.Include, p)
+	}
+	for _, s := range exclude {
+		p, err := ParsePattern(s)
+		if err != nil {
+			return f, fmt.Errorf("exclude: %v", err)
+		}
+		f.Exclude = append(f.Exclude, p)
+	}
+	return f, nil
+}
+
+// Match проверяет, берется ли файл с путем name относительно директории запросов
+func (f Filter) Match(name string) bool {
+	for _, p := range f.Exclude {
+		if p.Match(name) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, p := range f.Include {
+		if p.Match(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package source
+
+import "testing"
+
+// TestPattern_Match проверяет glob с ** и регулярные выражения
+func TestPattern_Match(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"orders/**", "orders/create.json", true},
+		{"orders/**", "orders/bulk/items.json", true},
+		{"orders/**", "users/create.json", false},
+		{"**/*.draft.json", "create.draft.json", true},
+		{"**/*.draft.json", "orders/bulk/create.draft.json", true},
+		{"**/*.draft.json", "orders/create.json", false},
+		{"*.json", "orders/create.json", false},
+		{"orders/*/items.json", "orders/bulk/items.json", true},
+		{"orders/*/items.json", "orders/items.json", false},
+		{"orders/**/items.json", "orders/items.json", true},
+		{"user?.json", "users.json", true},
+		{"re:^orders/.*_v[0-9]+\\.json$", "orders/bulk/create_v2.json", true},
+		{"re:^orders/", "users/orders/a.json", false},
+	}
+	for _, test := range tests {
+		p, err := ParsePattern(test.pattern)
+		if err != nil {
+			t.Fatalf("ParsePattern(%q) вернул ошибку: %v", test.pattern, err)
+		}
+		if got := p.Match(test.name); got != test.want {
+			t.Errorf("%q.Match(%q) = %v, ожидалось %v", test.pattern, test.name, got, test.want)
+		}
+	}
+
+	for _, bad := range []string{"", "orders/[", "re:("} {
+		if _, err := ParsePattern(bad); err == nil {
+			t.Errorf("ParsePattern(%q): ожидалась ошибка", bad)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"poster/internal/archive"
+	"poster/internal/expect"
+	"sort"
+	"strings"
+)
+
+// Job = единица работы воркера: целый файл запроса или одна строка JSONL
+type Job struct {
+	Name   string // Ключ ответа: имя файла или имя файла с номером строки
+	Path   string // Путь к файлу-источнику
+	Line   int    // Номер строки в JSONL (с 1), 0 для целого файла
+	Offset int64  // Смещение строки от начала файла в байтах
+	Data   []byte // Содержимое запроса (nil - читается из Path)
+	Index  int    // Номер задачи в порядке чтения (с 0)
+
+	Archive string // Архив, из которого прочитан запрос (пусто - файл на диске)
+	Sidecar []byte // Файл ожиданий name.expect.json из архива
+}
+
+// IsJSONL проверяет, является ли файл JSON Lines
+func IsJSONL(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return true
+	}
+	return false
+}
+
+// Read возвращает содержимое запроса
+func (j Job) Read() ([]byte, error) {
+	if j.Data != nil {
+		return j.Data, nil
+	}
+	return os.ReadFile(j.Path)
+}
+
+// Size возвращает размер исходных данных запроса
+func (j Job) Size() int64 {
+	if j.Data != nil {
+		return int64(len(j.Data))
+	}
+	info, err := os.Stat(j.Path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// Stdin = значение -requests для чтения запросов NDJSON из stdin
+const Stdin = "-"
+
+// stdinName = имя потока stdin в именах задач: stdin.1.json, stdin.2.json, ...
+const stdinName = "stdin"
+
+// Collect собирает задачи из директории с запросами (рекурсивно), из архива, из одного JSONL файла или из stdin.
+// Имена задач - пути относительно директории через "/": ответы повторяют структуру поддиректорий.
+// Скрытые файлы и директории пропускаются, filter отбирает файлы директории по относительному пути.
+func Collect(path string, filter Filter) ([]Job, error) {
+	var jobs []Job
+	err := Walk(path, filter, func(job Job) error {
+		jobs = append(jobs, job)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Walk передает fn задачи по мере чтения, отбирая их так же, как Collect, и нумеруя по порядку (Index).
+// Строки stdin передаются по мере поступления, файлы архива читаются по одному в порядке архива,
+// поэтому в памяти не оказывается весь поток или архив. Ошибка fn прерывает обход и возвращается.
+func Walk(path string, filter Filter, fn func(Job) error) error {
+	w := &walker{fn: fn}
+	if path == Stdin {
+		// Имена строк stdin не повторяются, а поток может быть бесконечным: имена не запоминаются
+		if err := EachLine(os.Stdin, stdinName, Stdin, w.emit); err != nil {
+			return fmt.Errorf("чтение stdin: %v", err)
+		}
+		return nil
+	}
+	w.seen = make(map[string]string)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		if archive.Is(path) {
+			return walkArchive(path, filter, w)
+		}
+		if IsJSONL(path) {
+			return eachFileLine(path, filepath.Base(path), w.emit)
+		}
+		return w.emit(Job{Name: filepath.Base(path), Path: path})
+	}
+
+	var names []string
+	err = filepath.WalkDir(path, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath != path && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || expect.IsSidecar(entry.Name()) {
+			return nil
+		}
+		if strings.ToLower(filepath.Ext(entry.Name())) != ".json" && !IsJSONL(entry.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(path, filePath)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); filter.Match(name) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		filePath := filepath.Join(path, filepath.FromSlash(name))
+		if IsJSONL(name) {
+			err = eachFileLine(filePath, name, w.emit)
+		} else {
+			err = w.emit(Job{Name: name, Path: filePath})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walker нумерует задачи обхода и проверяет, что их имена не повторяются: строка N файла x.jsonl, файл x.N.json
+// и строка N файла x.ndjson перезаписали бы ответы и записи журнала друг друга
+type walker struct {
+	fn    func(Job) error
+	index int
+	seen  map[string]string // Имя задачи -> ее источник для сообщения о повторе (nil - без проверки)
+}
+
+// emit нумерует задачу и передает ее дальше
+func (w *walker) emit(job Job) error {
+	if w.seen != nil {
+		if other, ok := w.seen[job.Name]; ok {
+			return fmt.Errorf("имя ответа %s повторяется: %s и %s", job.Name, other, job.Where())
+		}
+		w.seen[job.Name] = job.Where()
+	}
+	job.Index = w.index
+	w.index++
+	return w.fn(job)
+}
+
+// Where описывает источник задачи для сообщений: файл и, для JSONL, номер строки
+func (j Job) Where() string {
+	if j.Line > 0 {
+		return fmt.Sprintf("%s (строка %d)", j.Path, j.Line)
+	}
+	return j.Path
+}
+
+// walkArchive обходит запросы архива так же, как файлы директории: имена задач - пути файлов в архиве.
+// Архив не распаковывается на диск и не читается в память целиком: первый проход собирает только
+// небольшие файлы ожиданий, второй передает запросы по одному, JSONL - построчно.
+func walkArchive(path string, filter Filter, w *walker) error {
+	sidecars := make(map[string][]byte)
+	err := archive.Walk(path, func(entry string, r io.Reader) error {
+		if hidden(entry) || !expect.IsSidecar(entry) {
+			return nil
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %v", path, entry, err)
+		}
+		sidecars[entry] = data
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return archive.Walk(path, func(entry string, r io.Reader) error {
+		if hidden(entry) || expect.IsSidecar(entry) || !filter.Match(entry) {
+			return nil
+		}
+		if strings.ToLower(filepath.Ext(entry)) != ".json" && !IsJSONL(entry) {
+			return nil
+		}
+		job := Job{
+			Name:    entry,
+			Path:    filepath.Join(path, filepath.FromSlash(entry)),
+			Archive: path,
+			Sidecar: sidecars[expect.SidecarPath(entry)],
+		}
+		if IsJSONL(entry) {
+			err := EachLine(r, entry, job.Path, func(line Job) error {
+				line.Archive, line.Sidecar = job.Archive, job.Sidecar
+				return w.emit(line)
+			})
+			if err != nil {
+				return fmt.Errorf("чтение %s: %v", job.Path, err)
+			}
+			return nil
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %v", path, entry, err)
+		}
+		job.Data = data
+		return w.emit(job)
+	})
+}
+
+// hidden проверяет, лежит ли файл архива в скрытой директории или сам скрыт
+func hidden(entry string) bool {
+	for _, part := range strings.Split(entry, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Expectation возвращает ожидания к ответу: из конверта (inline), из файла ожиданий архива
+// или из файла name.expect.json рядом с файлом запроса. У запросов из stdin файла ожиданий нет.
+func (j Job) Expectation(loader *expect.Loader, inline json.RawMessage) (*expect.Expectation, error) {
+	if inline != nil || (j.Archive == "" && j.Path != Stdin) {
+		return loader.For(inline, j.Path)
+	}
+	if j.Sidecar == nil {
+		return nil, nil
+	}
+	e, err := expect.Parse(j.Sidecar)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", expect.SidecarPath(j.Path), err)
+	}
+	return e, nil
+}
+
+// eachFileLine передает fn задачи строк JSONL файла, name - имя файла в именах ответов строк
+func eachFileLine(path, name string, fn func(Job) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := EachLine(file, name, path, fn); err != nil {
+		return fmt.Errorf("чтение %s: %v", path, err)
+	}
+	return nil
+}
+
+// EachLine читает JSONL поток и передает fn задачу каждой непустой строки, как только строка прочитана
+func EachLine(r io.Reader, name, path string, fn func(Job) error) error {
+	reader := bufio.NewReader(r)
+	var offset int64
+	for line := 1; ; line++ {
+		raw, err := reader.ReadBytes('\n')
+		if len(raw) > 0 {
+			if data := bytes.TrimSpace(raw); len(data) > 0 {
+				job := Job{
+					Name:   LineName(name, line),
+					Path:   path,
+					Line:   line,
+					Offset: offset,
+					Data:   data,
+				}
+				if err := fn(job); err != nil {
+					return err
+				}
+			}
+			offset += int64(len(raw))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// LineName формирует имя ответа для строки JSONL: requests.jsonl, 5 -> requests.5.json
+func LineName(name string, line int) string {
+	return fmt.Sprintf("%s.%d.json", strings.TrimSuffix(name, filepath.Ext(name)), line)
+}
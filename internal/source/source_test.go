@@ -0,0 +1,346 @@
+package source
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"poster/internal/archive"
+	"poster/internal/expect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFiles создает файлы во временной директории
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("не удалось создать файл %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+// TestCollect_Directory проверяет сбор JSON и JSONL файлов из директории
+func TestCollect_Directory(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.json":        `{"a":1}`,
+		"b.jsonl":       "{\"b\":1}\n\n{\"b\":3}\n",
+		"c.txt":         "не запрос",
+		"d.ndjson":      `{"d":1}`,
+		"e.json.gz":     "",
+		"a.expect.json": `{"status":200}`,
+	})
+
+	jobs, err := Collect(dir, Filter{})
+	if err != nil {
+		t.Fatalf("Collect() вернул ошибку: %v", err)
+	}
+
+	wantNames := []string{"a.json", "b.1.json", "b.3.json", "d.1.json"}
+	if len(jobs) != len(wantNames) {
+		t.Fatalf("получено %d задач, ожидалось %d: %+v", len(jobs), len(wantNames), jobs)
+	}
+	for i, name := range wantNames {
+		if jobs[i].Name != name {
+			t.Errorf("jobs[%d].Name = %q, ожидалось %q", i, jobs[i].Name, name)
+		}
+	}
+
+	if jobs[0].Data != nil || jobs[0].Line != 0 {
+		t.Errorf("целый файл не должен содержать данных и номера строки: %+v", jobs[0])
+	}
+	if jobs[2].Line != 3 || jobs[2].Offset != int64(len("{\"b\":1}\n\n")) {
+		t.Errorf("неверная позиция строки: line=%d offset=%d", jobs[2].Line, jobs[2].Offset)
+	}
+}
+
+// TestCollect_Nested проверяет обход поддиректорий и отбор файлов по шаблонам
+func TestCollect_Nested(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"ping.json":                     `{}`,
+		"orders/create.json":            `{}`,
+		"orders/create.draft.json":      `{}`,
+		"orders/bulk/items.jsonl":       "{\"i\":1}\n{\"i\":2}\n",
+		"orders/bulk/items.expect.json": `{"status":200}`,
+		"users/create.json":             `{}`,
+		".git/config.json":              `{}`,
+	})
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{"все файлы", nil, nil, []string{"orders/bulk/items.1.json", "orders/bulk/items.2.json", "orders/create.draft.json", "orders/create.json", "ping.json", "users/create.json"}},
+		{"include и exclude", []string{"orders/**"}, []string{"**/*.draft.json"}, []string{"orders/bulk/items.1.json", "orders/bulk/items.2.json", "orders/create.json"}},
+		{"верхний уровень", []string{"*"}, nil, []string{"ping.json"}},
+		{"регулярное выражение", []string{"re:/create"}, []string{"re:^users/"}, []string{"orders/create.draft.json", "orders/create.json"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := NewFilter(test.include, test.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			jobs, err := Collect(dir, filter)
+			if err != nil {
+				t.Fatalf("Collect() вернул ошибку: %v", err)
+			}
+			var names []string
+			for _, job := range jobs {
+				names = append(names, job.Name)
+			}
+			if strings.Join(names, " ") != strings.Join(test.want, " ") {
+				t.Errorf("задачи = %v, ожидалось %v", names, test.want)
+			}
+		})
+	}
+}
+
+// TestCollect_DuplicateNames проверяет ошибку, если строка JSONL и файл получают одно имя ответа
+func TestCollect_DuplicateNames(t *testing.T) {
+	tests := map[string]map[string]string{
+		"строка и файл":   {"x.jsonl": "{}\n{}\n", "x.2.json": "{}"},
+		"jsonl и ndjson":  {"x.jsonl": "{}", "x.ndjson": "{}"},
+		"в поддиректории": {"a/x.jsonl": "{}", "a/x.1.json": "{}"},
+	}
+	for name, files := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Collect(writeFiles(t, files), Filter{})
+			if err == nil || !strings.Contains(err.Error(), "повторяется") {
+				t.Errorf("Collect() = %v, ожидалась ошибка повтора имени", err)
+			}
+		})
+	}
+
+	dir := writeFiles(t, map[string]string{"x.jsonl": "{}\n{}\n", "x.3.json": "{}", "a/x.1.json": "{}"})
+	if _, err := Collect(dir, Filter{}); err != nil {
+		t.Errorf("разные имена: Collect() вернул ошибку: %v", err)
+	}
+}
+
+// TestCollect_JSONLFile проверяет чтение одного JSONL файла
+func TestCollect_JSONLFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"dump.jsonl": "{\"x\":1}\r\n{\"x\":2}",
+	})
+
+	jobs, err := Collect(filepath.Join(dir, "dump.jsonl"), Filter{})
+	if err != nil {
+		t.Fatalf("Collect() вернул ошибку: %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("получено %d задач, ожидалось 2", len(jobs))
+	}
+
+	data, err := jobs[1].Read()
+	if err != nil {
+		t.Fatalf("Read() вернул ошибку: %v", err)
+	}
+	if string(data) != `{"x":2}` {
+		t.Errorf("Data = %q, ожидалось %q", data, `{"x":2}`)
+	}
+	if jobs[0].Size() != int64(len(`{"x":1}`)) {
+		t.Errorf("Size() = %d, пробелы и перевод строки не должны учитываться", jobs[0].Size())
+	}
+}
+
+// TestCollect_Archive проверяет сбор запросов из архива без распаковки
+func TestCollect_Archive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.tar.gz")
+	w, err := archive.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range [][2]string{
+		{"orders/b.json", `{"b":1}`},
+		{"orders/b.expect.json", `{"status":201}`},
+		{"a.json", `{"a":1}`},
+		{"c.jsonl", "{\"c\":1}\n{\"c\":2}\n"},
+		{"drafts/d.json", `{"d":1}`},
+		{".hidden/e.json", `{"e":1}`},
+		{"notes.txt", "не запрос"},
+	} {
+		if err := w.Add(file[0], []byte(file[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	filter, _ := NewFilter(nil, []string{"drafts/**"})
+	jobs, err := Collect(path, filter)
+	if err != nil {
+		t.Fatalf("Collect() вернул ошибку: %v", err)
+	}
+	var names []string
+	for _, job := range jobs {
+		names = append(names, job.Name)
+	}
+	// Порядок архива, а не сортировка: файлы не собираются в память перед отправкой
+	if want := []string{"orders/b.json", "a.json", "c.1.json", "c.2.json"}; strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Fatalf("задачи = %v, ожидалось %v", names, want)
+	}
+	for i, job := range jobs {
+		if job.Index != i {
+			t.Errorf("%s: Index = %d, ожидалось %d", job.Name, job.Index, i)
+		}
+	}
+
+	data, err := jobs[3].Read()
+	if err != nil || string(data) != `{"c":2}` {
+		t.Errorf("Read() = %q, %v", data, err)
+	}
+	if jobs[0].Path != filepath.Join(path, "orders", "b.json") || jobs[0].Archive != path {
+		t.Errorf("Path = %s, Archive = %s", jobs[0].Path, jobs[0].Archive)
+	}
+
+	// Файл ожиданий идет в архиве после запроса, но находится: его ищет отдельный проход
+	loader := expect.NewLoader()
+	e, err := jobs[0].Expectation(loader, nil)
+	if err != nil || e == nil || len(e.Status) != 1 {
+		t.Errorf("Expectation() = %+v, %v, ожидался файл ожиданий из архива", e, err)
+	}
+	if e, err := jobs[1].Expectation(loader, nil); e != nil || err != nil {
+		t.Errorf("Expectation() без файла ожиданий = %+v, %v", e, err)
+	}
+}
+
+// TestWalk_Archive проверяет, что задачи архива передаются по одной и ошибка fn прерывает чтение
+func TestWalk_Archive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.zip")
+	w, err := archive.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.json", "b.json", "c.json", "a.json"} {
+		if err := w.Add(name, []byte(`{}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := errors.New("стоп")
+	var names []string
+	err = Walk(path, Filter{}, func(job Job) error {
+		names = append(names, job.Name)
+		if job.Name == "b.json" {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || strings.Join(names, " ") != "a.json b.json" {
+		t.Errorf("Walk() = %v, задачи %v, ожидалась остановка после b.json", err, names)
+	}
+
+	// Повтор имени находится при обходе, после уже переданных задач
+	names = nil
+	err = Walk(path, Filter{}, func(job Job) error {
+		names = append(names, job.Name)
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "повторяется") || len(names) != 3 {
+		t.Errorf("Walk() = %v, задачи %v, ожидалась ошибка повтора a.json", err, names)
+	}
+}
+
+// TestCollect_Stdin проверяет чтение запросов NDJSON из stdin
+func TestCollect_Stdin(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"in.jsonl": "{\"a\":1}\n\n{\"a\":3}\n"})
+	stdin, err := os.Open(filepath.Join(dir, "in.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	oldStdin := os.Stdin
+	defer func() { os.Stdin = oldStdin }()
+	os.Stdin = stdin
+
+	jobs, err := Collect(Stdin, Filter{})
+	if err != nil {
+		t.Fatalf("Collect() вернул ошибку: %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].Name != "stdin.1.json" || jobs[1].Name != "stdin.3.json" || string(jobs[1].Data) != `{"a":3}` {
+		t.Fatalf("задачи = %+v", jobs)
+	}
+	if e, err := jobs[0].Expectation(expect.NewLoader(), nil); e != nil || err != nil {
+		t.Errorf("Expectation() = %+v, %v, у stdin нет файла ожиданий", e, err)
+	}
+}
+
+// TestWalk_Stdin проверяет, что строка stdin передается сразу, до конца ввода
+func TestWalk_Stdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStdin := os.Stdin
+	defer func() { os.Stdin = oldStdin }()
+	os.Stdin = r
+
+	jobs := make(chan Job)
+	done := make(chan error, 1)
+	go func() {
+		done <- Walk(Stdin, Filter{}, func(job Job) error {
+			jobs <- job
+			return nil
+		})
+	}()
+
+	for i, line := range []string{`{"a":1}`, `{"a":2}`} {
+		w.WriteString(line + "\n")
+		select {
+		case job := <-jobs:
+			if job.Index != i || string(job.Data) != line {
+				t.Errorf("задача = %d %s, ожидалось %d %s", job.Index, job.Data, i, line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("строка %d не передана до конца ввода", i+1)
+		}
+	}
+	w.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Walk() вернул ошибку: %v", err)
+	}
+}
+
+// TestCollect_NotExist проверяет ошибку для несуществующего пути
+func TestCollect_NotExist(t *testing.T) {
+	if _, err := Collect(filepath.Join(t.TempDir(), "нет"), Filter{}); err == nil {
+		t.Error("ожидалась ошибка для несуществующего пути")
+	}
+}
+
+// TestEachLine_LongLine проверяет строки длиннее буфера bufio
+func TestEachLine_LongLine(t *testing.T) {
+	long := `{"v":"` + strings.Repeat("x", 200*1024) + `"}`
+	var jobs []Job
+	err := EachLine(strings.NewReader(long+"\n"), "big.jsonl", "big.jsonl", func(job Job) error {
+		jobs = append(jobs, job)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachLine() вернул ошибку: %v", err)
+	}
+	if len(jobs) != 1 || len(jobs[0].Data) != len(long) {
+		t.Errorf("длинная строка прочитана неверно")
+	}
+}
+
+// TestLineName проверяет формирование имени ответа для строки
+func TestLineName(t *testing.T) {
+	if got := LineName("requests.jsonl", 12); got != "requests.12.json" {
+		t.Errorf("LineName() = %q, ожидалось %q", got, "requests.12.json")
+	}
+}
@@ -0,0 +1,390 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Sample = результат обработки одного запроса
+type Sample struct {
+	Start        time.Time     // Начало обработки
+	Duration     time.Duration // Время обработки
+	StatusCode   int           // HTTP статус код (0 - ответа нет)
+	FileSize     int64         // Размер файла запроса
+	RequestSize  int           // Размер тела запроса
+	ResponseSize int           // Размер ответа
+	Attempts     int           // Количество отправленных HTTP запросов с учетом повторов
+	Intended     time.Time     // Время отправки по расписанию open-loop (нулевое - без расписания)
+	ErrType      string        // Тип ошибки, пусто при успехе
+}
+
+// Bucket = столбец гистограммы задержек: запросы с Duration <= Upper
+type Bucket struct {
+	Upper time.Duration `json:"upper_ns"` // Верхняя граница (0 - бесконечность)
+	Count int           `json:"count"`
+}
+
+// Label возвращает подпись столбца
+func (b Bucket) Label() string {
+	if b.Upper == 0 {
+		return "+Inf"
+	}
+	return "<=" + b.Upper.String()
+}
+
+// bounds = границы гистограммы задержек
+var bounds = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Summary = итоговая статистика прогона
+type Summary struct {
+	Total       int     `json:"total"`
+	Successful  int     `json:"successful"`
+	Failed      int     `json:"failed"`
+	SuccessRate float64 `json:"success_rate"` // Доля успешных, %
+
+	WallTime   time.Duration `json:"wall_time_ns"` // От начала первого до конца последнего запроса
+	Throughput float64       `json:"throughput"`   // Запросов в секунду по wall-clock времени
+
+	Attempts    int     `json:"attempts"`              // Отправлено HTTP запросов с учетом повторов
+	RequestRate float64 `json:"request_rate"`          // Достигнутая скорость: HTTP запросов в секунду
+	TargetRate  float64 `json:"target_rate,omitempty"` // Целевая скорость ограничителя (0 - без ограничения)
+
+	// Задержки успешных запросов
+	Min time.Duration `json:"min_ns"`
+	Max time.Duration `json:"max_ns"`
+	Avg time.Duration `json:"avg_ns"`
+	P50 time.Duration `json:"p50_ns"`
+	P90 time.Duration `json:"p90_ns"`
+	P95 time.Duration `json:"p95_ns"`
+	P99 time.Duration `json:"p99_ns"`
+
+	// Задержки успешных запросов от времени по расписанию (open-loop): учитывают ожидание в очереди
+	Corrected *Latency `json:"corrected,omitempty"`
+
+	Histogram []Bucket `json:"histogram"`
+
+	TotalFileSize     int64 `json:"total_file_size"`
+	TotalRequestSize  int64 `json:"total_request_size"`
+	TotalResponseSize int64 `json:"total_response_size"`
+
+	StatusCodes map[int]int    `json:"status_codes"`
+	ErrorTypes  map[string]int `json:"error_types"`
+}
+
+// Latency = распределение задержек
+type Latency struct {
+	Min time.Duration `json:"min_ns"`
+	Max time.Duration `json:"max_ns"`
+	Avg time.Duration `json:"avg_ns"`
+	P50 time.Duration `json:"p50_ns"`
+	P90 time.Duration `json:"p90_ns"`
+	P95 time.Duration `json:"p95_ns"`
+	P99 time.Duration `json:"p99_ns"`
+}
+
+// LatencyOf вычисляет распределение отсортированных задержек
+func LatencyOf(sorted []time.Duration) Latency {
+	if len(sorted) == 0 {
+		return Latency{}
+	}
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	return Latency{
+		Min: sorted[0],
+		Max: sorted[len(sorted)-1],
+		Avg: sum / time.Duration(len(sorted)),
+		P50: Percentile(sorted, 50),
+		P90: Percentile(sorted, 90),
+		P95: Percentile(sorted, 95),
+		P99: Percentile(sorted, 99),
+	}
+}
+
+// Aggregator собирает статистику по результатам. Не потокобезопасен.
+type Aggregator struct {
+	summary   Summary
+	durations []time.Duration
+	corrected []time.Duration
+	begin     time.Time
+	end       time.Time
+}
+
+// New создает пустой агрегатор
+func New() *Aggregator {
+	return &Aggregator{
+		summary: Summary{
+			StatusCodes: make(map[int]int),
+			ErrorTypes:  make(map[string]int),
+		},
+	}
+}
+
+// Add учитывает результат запроса
+func (a *Aggregator) Add(s Sample) {
+	a.summary.Total++
+	a.summary.Attempts += s.Attempts
+	if !s.Start.IsZero() {
+		if a.begin.IsZero() || s.Start.Before(a.begin) {
+			a.begin = s.Start
+		}
+		if end := s.Start.Add(s.Duration); end.After(a.end) {
+			a.end = end
+		}
+	}
+	if s.StatusCode > 0 {
+		a.summary.StatusCodes[s.StatusCode]++
+	}
+
+	if s.ErrType != "" {
+		a.summary.Failed++
+		a.summary.ErrorTypes[s.ErrType]++
+		return
+	}
+
+	a.summary.Successful++
+	a.summary.TotalFileSize += s.FileSize
+	a.summary.TotalRequestSize += int64(s.RequestSize)
+	a.summary.TotalResponseSize += int64(s.ResponseSize)
+	a.durations = append(a.durations, s.Duration)
+	if !s.Intended.IsZero() {
+		a.corrected = append(a.corrected, s.Start.Add(s.Duration).Sub(s.Intended))
+	}
+}
+
+// Summary вычисляет итоговую статистику
+func (a *Aggregator) Summary() Summary {
+	s := a.summary
+	s.StatusCodes = cloneMap(a.summary.StatusCodes)
+	s.ErrorTypes = cloneMap(a.summary.ErrorTypes)
+
+	if s.Total > 0 {
+		s.SuccessRate = float64(s.Successful) * 100 / float64(s.Total)
+	}
+	if a.end.After(a.begin) {
+		s.WallTime = a.end.Sub(a.begin)
+		s.Throughput = float64(s.Total) / s.WallTime.Seconds()
+		s.RequestRate = float64(s.Attempts) / s.WallTime.Seconds()
+	}
+
+	durations := slices.Clone(a.durations)
+	slices.Sort(durations)
+	s.Histogram = Histogram(durations)
+
+	latency := LatencyOf(durations)
+	s.Min, s.Max, s.Avg = latency.Min, latency.Max, latency.Avg
+	s.P50, s.P90, s.P95, s.P99 = latency.P50, latency.P90, latency.P95, latency.P99
+
+	if len(a.corrected) > 0 {
+		corrected := slices.Clone(a.corrected)
+		slices.Sort(corrected)
+		latency := LatencyOf(corrected)
+		s.Corrected = &latency
+	}
+	return s
+}
+
+// Percentile возвращает перцентиль p (0..100) отсортированных задержек по методу ближайшего ранга
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	rank = min(max(rank, 1), len(sorted))
+	return sorted[rank-1]
+}
+
+// Histogram раскладывает отсортированные задержки по столбцам
+func Histogram(sorted []time.Duration) []Bucket {
+	buckets := make([]Bucket, len(bounds)+1)
+	for i, upper := range bounds {
+		buckets[i].Upper = upper
+	}
+	for _, d := range sorted {
+		i := sort.Search(len(bounds), func(i int) bool { return d <= bounds[i] })
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+// Fields возвращает статистику в виде полей для логгера
+func (s Summary) Fields() map[string]interface{} {
+	histogram := make(map[string]int, len(s.Histogram))
+	for _, b := range s.Histogram {
+		if b.Count > 0 {
+			histogram[b.Label()] = b.Count
+		}
+	}
+	statusCodes := make(map[string]int, len(s.StatusCodes))
+	for code, count := range s.StatusCodes {
+		statusCodes[fmt.Sprint(code)] = count
+	}
+
+	fields := map[string]interface{}{
+		"total_files":            s.Total,
+		"successful":             s.Successful,
+		"failed":                 s.Failed,
+		"success_rate":           fmt.Sprintf("%.2f%%", s.SuccessRate),
+		"wall_time":              s.WallTime.String(),
+		"throughput_per_sec":     fmt.Sprintf("%.2f", s.Throughput),
+		"attempts":               s.Attempts,
+		"request_rate_per_sec":   fmt.Sprintf("%.2f", s.RequestRate),
+		"target_rate_per_sec":    fmt.Sprintf("%.2f", s.TargetRate),
+		"min_duration_ms":        s.Min.Milliseconds(),
+		"avg_duration_ms":        s.Avg.Milliseconds(),
+		"max_duration_ms":        s.Max.Milliseconds(),
+		"p50_ms":                 s.P50.Milliseconds(),
+		"p90_ms":                 s.P90.Milliseconds(),
+		"p95_ms":                 s.P95.Milliseconds(),
+		"p99_ms":                 s.P99.Milliseconds(),
+		"histogram":              histogram,
+		"total_file_size_mb":     fmt.Sprintf("%.2f MB", float64(s.TotalFileSize)/(1024*1024)),
+		"total_request_size_mb":  fmt.Sprintf("%.2f MB", float64(s.TotalRequestSize)/(1024*1024)),
+		"total_response_size_mb": fmt.Sprintf("%.2f MB", float64(s.TotalResponseSize)/(1024*1024)),
+		"status_codes":           statusCodes,
+		"error_types":            s.ErrorTypes,
+	}
+	if s.Corrected != nil {
+		fields["corrected_p50_ms"] = s.Corrected.P50.Milliseconds()
+		fields["corrected_p90_ms"] = s.Corrected.P90.Milliseconds()
+		fields["corrected_p95_ms"] = s.Corrected.P95.Milliseconds()
+		fields["corrected_p99_ms"] = s.Corrected.P99.Milliseconds()
+		fields["corrected_max_ms"] = s.Corrected.Max.Milliseconds()
+	}
+	return fields
+}
+
+// WriteTable печатает статистику таблицей
+func (s Summary) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Всего\t%d\n", s.Total)
+	fmt.Fprintf(tw, "Успешно\t%d (%.2f%%)\n", s.Successful, s.SuccessRate)
+	fmt.Fprintf(tw, "Ошибок\t%d\n", s.Failed)
+	fmt.Fprintf(tw, "Время прогона\t%v\n", s.WallTime.Round(time.Millisecond))
+	fmt.Fprintf(tw, "Пропускная способность\t%.2f запросов/с\n", s.Throughput)
+	if s.TargetRate > 0 {
+		fmt.Fprintf(tw, "Скорость отправки\t%.2f запросов/с из %.2f (%.1f%%)\n", s.RequestRate, s.TargetRate, s.RequestRate*100/s.TargetRate)
+	}
+	fmt.Fprintf(tw, "Отправлено / получено\t%s / %s\n", FormatSize(s.TotalRequestSize), FormatSize(s.TotalResponseSize))
+
+	if s.Successful > 0 {
+		fmt.Fprintf(tw, "\nЗадержка\tmin\tavg\tp50\tp90\tp95\tp99\tmax\n")
+		label := ""
+		if s.Corrected != nil {
+			label = "от отправки"
+		}
+		fmt.Fprintf(tw, "%s\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", label,
+			round(s.Min), round(s.Avg), round(s.P50), round(s.P90), round(s.P95), round(s.P99), round(s.Max))
+		if c := s.Corrected; c != nil {
+			fmt.Fprintf(tw, "от расписания\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+				round(c.Min), round(c.Avg), round(c.P50), round(c.P90), round(c.P95), round(c.P99), round(c.Max))
+		}
+
+		fmt.Fprintf(tw, "\nГистограмма\tзапросов\t\n")
+		peak := 0
+		for _, b := range s.Histogram {
+			peak = max(peak, b.Count)
+		}
+		for _, b := range s.Histogram {
+			if b.Count == 0 {
+				continue
+			}
+			bar := strings.Repeat("#", max(1, b.Count*40/peak))
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", b.Label(), b.Count, bar)
+		}
+	}
+
+	if len(s.StatusCodes) > 0 {
+		fmt.Fprintf(tw, "\nHTTP статус\tзапросов\n")
+		for _, code := range sortedKeys(s.StatusCodes) {
+			fmt.Fprintf(tw, "%d\t%d\n", code, s.StatusCodes[code])
+		}
+	}
+	if len(s.ErrorTypes) > 0 {
+		fmt.Fprintf(tw, "\nТип ошибки\tзапросов\n")
+		for _, errType := range sortedKeys(s.ErrorTypes) {
+			fmt.Fprintf(tw, "%s\t%d\n", errType, s.ErrorTypes[errType])
+		}
+	}
+
+	return tw.Flush()
+}
+
+// Row = строка сравнительной таблицы: подпись и статистика
+type Row struct {
+	Name    string  `json:"name"`
+	Summary Summary `json:"summary"`
+}
+
+// WriteRows печатает краткую статистику по строкам таблицы, например по этапам нагрузки
+func WriteRows(w io.Writer, title string, rows []Row) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tвсего\tошибок\tзапросов/с\tp50\tp90\tp95\tp99\tmax\n", title)
+	for _, row := range rows {
+		s := row.Summary
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%v\t%v\t%v\t%v\t%v\n", row.Name, s.Total, s.Failed, s.Throughput,
+			round(s.P50), round(s.P90), round(s.P95), round(s.P99), round(s.Max))
+	}
+	return tw.Flush()
+}
+
+// round округляет задержку для печати
+func round(d time.Duration) time.Duration {
+	if d >= time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(10 * time.Microsecond)
+}
+
+// FormatSize печатает размер в удобных единицах
+func FormatSize(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.2f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.2f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
+
+// sortedKeys возвращает отсортированные ключи мапы
+func sortedKeys[K int | string](m map[K]int) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// cloneMap копирует мапу счетчиков
+func cloneMap[K comparable](m map[K]int) map[K]int {
+	out := make(map[K]int, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,220 @@
+package stats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPercentile проверяет перцентили по методу ближайшего ранга
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0, time.Millisecond},
+		{50, 50 * time.Millisecond},
+		{90, 90 * time.Millisecond},
+		{99, 99 * time.Millisecond},
+		{100, 100 * time.Millisecond},
+	}
+	for _, test := range tests {
+		if got := Percentile(sorted, test.p); got != test.want {
+			t.Errorf("Percentile(%v) = %v, ожидалось %v", test.p, got, test.want)
+		}
+	}
+
+	if got := Percentile(nil, 50); got != 0 {
+		t.Errorf("Percentile(nil) = %v, ожидалось 0", got)
+	}
+	if got := Percentile([]time.Duration{7}, 99); got != 7 {
+		t.Errorf("Percentile() одного значения = %v, ожидалось 7", got)
+	}
+}
+
+// TestHistogram проверяет раскладку по столбцам
+func TestHistogram(t *testing.T) {
+	buckets := Histogram([]time.Duration{
+		500 * time.Microsecond,
+		time.Millisecond,
+		3 * time.Millisecond,
+		time.Minute,
+	})
+
+	if len(buckets) != len(bounds)+1 {
+		t.Fatalf("столбцов %d, ожидалось %d", len(buckets), len(bounds)+1)
+	}
+	if buckets[0].Count != 2 {
+		t.Errorf("<=1ms: %d, ожидалось 2 (граница включается)", buckets[0].Count)
+	}
+	if buckets[2].Count != 1 {
+		t.Errorf("<=5ms: %d, ожидалось 1", buckets[2].Count)
+	}
+	if last := buckets[len(buckets)-1]; last.Count != 1 || last.Label() != "+Inf" {
+		t.Errorf("последний столбец = %+v, ожидалось +Inf с 1 запросом", last)
+	}
+}
+
+// TestAggregator проверяет итоговую статистику
+func TestAggregator(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	agg := New()
+
+	agg.Add(Sample{Start: start, Duration: 100 * time.Millisecond, StatusCode: 200, RequestSize: 10, ResponseSize: 100, Attempts: 1})
+	agg.Add(Sample{Start: start.Add(500 * time.Millisecond), Duration: 300 * time.Millisecond, StatusCode: 200, RequestSize: 10, ResponseSize: 100, Attempts: 2})
+	agg.Add(Sample{Start: start.Add(time.Second), Duration: time.Second, StatusCode: 500, ErrType: "http_status", Attempts: 3})
+	agg.Add(Sample{Start: start.Add(200 * time.Millisecond), Duration: 50 * time.Millisecond, ErrType: "transport", Attempts: 2})
+
+	s := agg.Summary()
+
+	if s.Total != 4 || s.Successful != 2 || s.Failed != 2 {
+		t.Errorf("счетчики = %d/%d/%d, ожидалось 4/2/2", s.Total, s.Successful, s.Failed)
+	}
+	if s.SuccessRate != 50 {
+		t.Errorf("SuccessRate = %v, ожидалось 50", s.SuccessRate)
+	}
+	if s.WallTime != 2*time.Second {
+		t.Errorf("WallTime = %v, ожидалось 2s", s.WallTime)
+	}
+	if s.Throughput != 2 {
+		t.Errorf("Throughput = %v, ожидалось 2 запроса/с по wall-clock времени", s.Throughput)
+	}
+	if s.Attempts != 8 || s.RequestRate != 4 {
+		t.Errorf("Attempts = %d, RequestRate = %v, ожидалось 8 и 4 запроса/с", s.Attempts, s.RequestRate)
+	}
+	if s.Min != 100*time.Millisecond || s.Max != 300*time.Millisecond || s.Avg != 200*time.Millisecond {
+		t.Errorf("min/avg/max = %v/%v/%v", s.Min, s.Avg, s.Max)
+	}
+	if s.P50 != 100*time.Millisecond || s.P99 != 300*time.Millisecond {
+		t.Errorf("p50/p99 = %v/%v", s.P50, s.P99)
+	}
+	if s.StatusCodes[200] != 2 || s.StatusCodes[500] != 1 {
+		t.Errorf("StatusCodes = %v", s.StatusCodes)
+	}
+	if s.ErrorTypes["http_status"] != 1 || s.ErrorTypes["transport"] != 1 {
+		t.Errorf("ErrorTypes = %v", s.ErrorTypes)
+	}
+	if s.TotalResponseSize != 200 {
+		t.Errorf("TotalResponseSize = %d, ожидалось 200", s.TotalResponseSize)
+	}
+
+	// Summary не должен зависеть от последующих Add
+	agg.Add(Sample{StatusCode: 200})
+	if s.StatusCodes[200] != 2 {
+		t.Error("Summary() должен возвращать копию счетчиков")
+	}
+}
+
+// TestAggregator_Empty проверяет статистику без результатов
+func TestAggregator_Empty(t *testing.T) {
+	s := New().Summary()
+	if s.Total != 0 || s.Throughput != 0 || s.P99 != 0 {
+		t.Errorf("пустая статистика = %+v", s)
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteTable(&buf); err != nil {
+		t.Fatalf("WriteTable() вернул ошибку: %v", err)
+	}
+}
+
+// TestSummary_WriteTable проверяет печать таблицы
+func TestSummary_WriteTable(t *testing.T) {
+	agg := New()
+	agg.Add(Sample{Start: time.Now(), Duration: 15 * time.Millisecond, StatusCode: 200})
+	agg.Add(Sample{Start: time.Now(), Duration: time.Second, ErrType: "transport"})
+
+	var buf bytes.Buffer
+	if err := agg.Summary().WriteTable(&buf); err != nil {
+		t.Fatalf("WriteTable() вернул ошибку: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"p95", "<=20ms", "transport", "200"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("таблица не содержит %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Скорость отправки") {
+		t.Error("без ограничения скорости целевая скорость не печатается")
+	}
+
+	limited := Summary{WallTime: time.Second, Attempts: 8, RequestRate: 8, TargetRate: 10}
+	buf.Reset()
+	if err := limited.WriteTable(&buf); err != nil {
+		t.Fatalf("WriteTable() вернул ошибку: %v", err)
+	}
+	if !strings.Contains(buf.String(), "8.00 запросов/с из 10.00 (80.0%)") {
+		t.Errorf("таблица не содержит достигнутую и целевую скорость:\n%s", buf.String())
+	}
+
+	fields := agg.Summary().Fields()
+	if fields["p99_ms"] != int64(15) {
+		t.Errorf("Fields()[p99_ms] = %v, ожидалось 15", fields["p99_ms"])
+	}
+}
+
+// TestWriteRows проверяет печать статистики по строкам
+func TestWriteRows(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	first, second := New(), New()
+	first.Add(Sample{Start: start, Duration: 10 * time.Millisecond, StatusCode: 200})
+	second.Add(Sample{Start: start, Duration: 30 * time.Millisecond, StatusCode: 200})
+	second.Add(Sample{Start: start.Add(time.Second), Duration: time.Second, ErrType: "transport"})
+
+	var buf bytes.Buffer
+	rows := []Row{{Name: "1 (10s -> 5 rps)", Summary: first.Summary()}, {Name: "2", Summary: second.Summary()}}
+	if err := WriteRows(&buf, "Этап", rows); err != nil {
+		t.Fatalf("WriteRows() вернул ошибку: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "Этап") {
+		t.Fatalf("таблица:\n%s", buf.String())
+	}
+	if !strings.Contains(lines[1], "10ms") || !strings.Contains(lines[2], "1.00") {
+		t.Errorf("строки таблицы неверные:\n%s", buf.String())
+	}
+}
+
+// TestAggregator_Corrected проверяет задержки от времени по расписанию
+func TestAggregator_Corrected(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	agg := New()
+	// По расписанию каждые 100ms, но сервер отвечает 250ms и запросы ждут в очереди
+	for i := 0; i < 4; i++ {
+		intended := start.Add(time.Duration(i) * 100 * time.Millisecond)
+		actual := start.Add(time.Duration(i) * 250 * time.Millisecond)
+		agg.Add(Sample{Start: actual, Duration: 250 * time.Millisecond, StatusCode: 200, Intended: intended})
+	}
+
+	s := agg.Summary()
+	if s.P99 != 250*time.Millisecond {
+		t.Errorf("P99 = %v, ожидалось 250ms без учета очереди", s.P99)
+	}
+	if s.Corrected == nil {
+		t.Fatal("Corrected = nil, ожидалась статистика от расписания")
+	}
+	if s.Corrected.Min != 250*time.Millisecond || s.Corrected.Max != 700*time.Millisecond {
+		t.Errorf("Corrected min/max = %v/%v, ожидалось 250ms/700ms", s.Corrected.Min, s.Corrected.Max)
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteTable(&buf); err != nil {
+		t.Fatalf("WriteTable() вернул ошибку: %v", err)
+	}
+	if !strings.Contains(buf.String(), "от расписания") || !strings.Contains(buf.String(), "700ms") {
+		t.Errorf("таблица не содержит задержки от расписания:\n%s", buf.String())
+	}
+	if _, ok := s.Fields()["corrected_p99_ms"]; !ok {
+		t.Error("поля лога не содержат corrected_p99_ms")
+	}
+
+	if New().Summary().Corrected != nil {
+		t.Error("без расписания Corrected должен быть nil")
+	}
+}
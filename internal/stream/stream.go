@@ -0,0 +1,101 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Stdout = значение -responses для вывода ответов в stdout: poster работает как фильтр в конвейере
+const Stdout = "-"
+
+// Порядок вывода ответов
+const (
+	OrderInput   = "input"   // В порядке запросов во входных данных
+	OrderArrival = "arrival" // По мере получения ответов
+)
+
+// Record = строка NDJSON с ответом на запрос
+type Record struct {
+	Index  int             `json:"index"`            // Номер запроса во входных данных (с 0)
+	Name   string          `json:"name"`             // Имя запроса
+	Status int             `json:"status,omitempty"` // HTTP статус (0 - ответа нет)
+	Body   json.RawMessage `json:"body,omitempty"`   // Тело ответа: JSON как есть, остальное - строкой
+	Error  string          `json:"error,omitempty"`  // Ошибка обработки запроса
+}
+
+// Body приводит тело ответа к значению записи: JSON сжимается в одну строку, чтобы не разорвать NDJSON,
+// остальное записывается строкой JSON. Пустое тело - nil.
+func Body(data []byte) json.RawMessage {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, data); err == nil {
+		return compacted.Bytes()
+	}
+	encoded, _ := json.Marshal(string(data))
+	return encoded
+}
+
+// Writer пишет записи NDJSON. В порядке OrderInput запись ждет, пока выведены все записи с меньшим Index.
+// Вызывается из одной горутины - цикла сбора результатов.
+type Writer struct {
+	w       io.Writer
+	ordered bool
+	next    int            // Index следующей записи в порядке OrderInput
+	pending map[int]Record // Записи, пришедшие раньше предыдущих
+}
+
+// NewWriter создает Writer с порядком order: OrderInput или OrderArrival
+func NewWriter(w io.Writer, order string) *Writer {
+	return &Writer{w: w, ordered: order != OrderArrival, pending: make(map[int]Record)}
+}
+
+// Write выводит запись или, в порядке OrderInput, откладывает ее до вывода предыдущих
+func (w *Writer) Write(r Record) error {
+	if !w.ordered {
+		return w.write(r)
+	}
+	w.pending[r.Index] = r
+	for {
+		next, ok := w.pending[w.next]
+		if !ok {
+			return nil
+		}
+		delete(w.pending, w.next)
+		w.next++
+		if err := w.write(next); err != nil {
+			return err
+		}
+	}
+}
+
+// Close выводит отложенные записи по порядку. Они остаются, если прогон остановлен и часть запросов
+// не отправлена: пропущенные номера не ждутся.
+func (w *Writer) Close() error {
+	indexes := make([]int, 0, len(w.pending))
+	for index := range w.pending {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		if err := w.write(w.pending[index]); err != nil {
+			return err
+		}
+		delete(w.pending, index)
+	}
+	return nil
+}
+
+// write выводит одну строку NDJSON
+func (w *Writer) write(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("запись %s: %v", r.Name, err)
+	}
+	_, err = w.w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,83 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestBody проверяет приведение тела ответа к значению записи
+func TestBody(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"JSON сжимается", "{\n  \"a\": [1, 2]\n}\n", `{"a":[1,2]}`},
+		{"не JSON", "bad\ngateway", `"bad\ngateway"`},
+		{"пустое тело", " \n", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := string(Body([]byte(test.data))); got != test.want {
+				t.Errorf("Body() = %s, ожидалось %s", got, test.want)
+			}
+		})
+	}
+}
+
+// TestWriter проверяет порядок вывода записей
+func TestWriter(t *testing.T) {
+	records := []Record{
+		{Index: 2, Name: "c", Status: 200, Body: json.RawMessage(`{"c":1}`)},
+		{Index: 0, Name: "a", Status: 200},
+		{Index: 3, Name: "d", Error: "отправка запроса: timeout"},
+		{Index: 1, Name: "b", Status: 500, Body: Body([]byte("oops"))},
+		{Index: 5, Name: "f", Status: 200},
+	}
+	tests := []struct {
+		order string
+		want  string // Имена записей по порядку вывода
+	}{
+		{OrderInput, "a b c d f"},
+		{OrderArrival, "c a d b f"},
+	}
+	for _, test := range tests {
+		t.Run(test.order, func(t *testing.T) {
+			var out bytes.Buffer
+			w := NewWriter(&out, test.order)
+			for _, r := range records {
+				if err := w.Write(r); err != nil {
+					t.Fatalf("Write() вернул ошибку: %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() вернул ошибку: %v", err)
+			}
+
+			var names []string
+			for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+				var r Record
+				if err := json.Unmarshal([]byte(line), &r); err != nil {
+					t.Fatalf("строка %q не JSON: %v", line, err)
+				}
+				names = append(names, r.Name)
+			}
+			if got := strings.Join(names, " "); got != test.want {
+				t.Errorf("порядок = %s, ожидалось %s", got, test.want)
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	w := NewWriter(&out, OrderInput)
+	w.Write(Record{Index: 1, Name: "b"})
+	if out.Len() != 0 {
+		t.Errorf("запись выведена до предыдущей: %s", out.String())
+	}
+	w.Write(Record{Index: 0, Name: "a", Status: 201, Body: json.RawMessage(`[1]`)})
+	if want := "{\"index\":0,\"name\":\"a\",\"status\":201,\"body\":[1]}\n{\"index\":1,\"name\":\"b\"}\n"; out.String() != want {
+		t.Errorf("вывод = %q, ожидалось %q", out.String(), want)
+	}
+}
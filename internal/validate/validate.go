@@ -0,0 +1,177 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"poster/internal/envelope"
+	"poster/internal/expect"
+	"poster/internal/journal"
+	"poster/internal/render"
+	"poster/internal/scenario"
+	"poster/internal/schema"
+	"poster/internal/source"
+	"poster/internal/stats"
+)
+
+// Item = результат проверки одного запроса: куда он был бы отправлен и что с ним не так
+type Item struct {
+	Name        string   // Ключ ответа: имя файла или имя файла с номером строки
+	Line        int      // Номер строки JSONL (0 для целого файла)
+	Method      string   // HTTP метод
+	URL         string   // Адрес запроса
+	Size        int64    // Размер запроса в байтах
+	Hash        string   // Хэш содержимого запроса
+	DuplicateOf string   // Первый запрос с тем же содержимым (пусто - содержимое уникально)
+	Problems    []string // Найденные ошибки
+}
+
+// OK проверяет, что запрос можно отправлять
+func (i Item) OK() bool {
+	return len(i.Problems) == 0
+}
+
+// Report = результат проверки всех запросов
+type Report struct {
+	Items      []Item
+	Problems   int   // Количество запросов с ошибками
+	Duplicates int   // Количество запросов, повторяющих содержимое более раннего
+	Size       int64 // Общий размер запросов в байтах
+}
+
+// OK проверяет, что все запросы можно отправлять
+func (r *Report) OK() bool {
+	return r.Problems == 0
+}
+
+// Options = настройки проверки
+type Options struct {
+	Base     string           // Базовый адрес, относительно которого разрешаются адреса конвертов
+	Schema   *schema.Schema   // Схема тела запроса (nil - без проверки)
+	Renderer *render.Renderer // Подстановка переменных в шаблоны (nil - запросы без подстановки, как в прогоне без -template)
+}
+
+// Check проверяет запросы без отправки: шаблон, JSON, конверт, адрес, ожидания к ответу и схему тела.
+// Шаблоны проверяются подстановкой, поэтому адрес и размер показываются для пробных значений генераторов.
+// Повторы содержимого не считаются ошибкой: одинаковые запросы бывают намеренно.
+func Check(jobs []source.Job, opts Options) *Report {
+	report := &Report{Items: make([]Item, 0, len(jobs))}
+	expectations := expect.NewLoader()
+	first := make(map[string]string) // Хэш содержимого - первый запрос с ним
+	for _, job := range jobs {
+		item := check(job, opts, expectations)
+		if !item.OK() {
+			report.Problems++
+		}
+		if item.Hash != "" {
+			if name, ok := first[item.Hash]; ok {
+				item.DuplicateOf = name
+				report.Duplicates++
+			} else {
+				first[item.Hash] = item.Name
+			}
+		}
+		report.Size += item.Size
+		report.Items = append(report.Items, item)
+	}
+	return report
+}
+
+// CheckScenarios проверяет шаги сценариев так же, как запросы. Значения, извлекаемые из ответов
+// предыдущих шагов, подставляются пробным 0: он допустим и внутри строки, и как JSON число.
+// Повторы не ищутся: один файл запроса в нескольких шагах и сценариях - обычное дело.
+func CheckScenarios(scenarios []*scenario.Scenario, opts Options) *Report {
+	report := &Report{}
+	expectations := expect.NewLoader()
+	for _, sc := range scenarios {
+		vars := make(map[string]string, len(sc.Vars))
+		for name, value := range sc.Vars {
+			vars[name] = value
+		}
+		for i, step := range sc.Steps {
+			stepOpts := opts
+			if opts.Renderer != nil {
+				stepOpts.Renderer = opts.Renderer.With(vars)
+			}
+			item := check(source.Job{Name: sc.ResponseName(i), Path: step.Request}, stepOpts, expectations)
+			if !item.OK() {
+				report.Problems++
+			}
+			report.Size += item.Size
+			report.Items = append(report.Items, item)
+			for _, rule := range step.Rules {
+				if _, ok := vars[rule.Var]; !ok {
+					vars[rule.Var] = "0"
+				}
+			}
+		}
+	}
+	return report
+}
+
+// check проверяет один запрос так же, как его разбирает воркер перед отправкой
+func check(job source.Job, opts Options, expectations *expect.Loader) Item {
+	item := Item{Name: job.Name, Line: job.Line}
+	data, err := job.Read()
+	if err != nil {
+		item.Problems = append(item.Problems, fmt.Sprintf("чтение файла: %v", err))
+		return item
+	}
+	item.Size = int64(len(data))
+	item.Hash = journal.Hash(data)
+	if opts.Renderer != nil && render.IsTemplate(data) {
+		if data, err = opts.Renderer.Render(data); err != nil {
+			item.Problems = append(item.Problems, fmt.Sprintf("шаблон: %v", err))
+			return item
+		}
+	}
+	if !json.Valid(data) {
+		item.Problems = append(item.Problems, "невалидный JSON")
+		return item
+	}
+
+	env, err := envelope.Parse(data)
+	if err != nil {
+		item.Problems = append(item.Problems, fmt.Sprintf("конверт запроса: %v", err))
+		return item
+	}
+	item.Method = env.Method
+	if item.URL, err = env.ResolveURL(opts.Base); err != nil {
+		item.Problems = append(item.Problems, fmt.Sprintf("адрес: %v", err))
+	}
+	if _, err := job.Expectation(expectations, env.Expect); err != nil {
+		item.Problems = append(item.Problems, fmt.Sprintf("ожидания: %v", err))
+	}
+	if opts.Schema != nil && len(env.Body) > 0 {
+		if err := opts.Schema.Validate(env.Body); err != nil {
+			item.Problems = append(item.Problems, fmt.Sprintf("схема запроса: %v", err))
+		}
+	}
+	return item
+}
+
+// WriteText выводит результат проверки: строка на запрос и итог
+func (r *Report) WriteText(w io.Writer) error {
+	for _, item := range r.Items {
+		status := "OK    "
+		if !item.OK() {
+			status = "ОШИБКА"
+		}
+		if _, err := fmt.Fprintf(w, "%s %s (%s)", status, item.Name, stats.FormatSize(item.Size)); err != nil {
+			return err
+		}
+		if item.Method != "" {
+			fmt.Fprintf(w, ": %s %s", item.Method, item.URL)
+		}
+		fmt.Fprintln(w)
+		if item.DuplicateOf != "" {
+			fmt.Fprintf(w, "       = повторяет %s\n", item.DuplicateOf)
+		}
+		for _, problem := range item.Problems {
+			fmt.Fprintf(w, "       - %s\n", problem)
+		}
+	}
+	_, err := fmt.Fprintf(w, "Проверено запросов: %d (%s), с ошибками: %d, повторов: %d\n",
+		len(r.Items), stats.FormatSize(r.Size), r.Problems, r.Duplicates)
+	return err
+}
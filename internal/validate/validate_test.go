@@ -0,0 +1,213 @@
+package validate
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"poster/internal/render"
+	"poster/internal/scenario"
+	"poster/internal/schema"
+	"poster/internal/source"
+	"strings"
+	"testing"
+)
+
+// TestCheck проверяет поиск ошибок в запросах без отправки
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"plain.json":          `{"a": 1}`,
+		"get.json":            `{"method": "get", "url": "/items", "query": {"page": 2}}`,
+		"broken.json":         `{"a": `,
+		"bad_method.json":     `{"method": "GE T", "url": "/"}`,
+		"bad_expect.json":     `{"url": "/x", "expect": {"status": "abc"}}`,
+		"sidecar.json":        `{"b": 2}`,
+		"sidecar.expect.json": `{`,
+		"copy.json":           `{"a": 1}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jobs, err := source.Collect(dir, source.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := Check(jobs, Options{Base: "http://localhost:8080/api/"})
+	items := make(map[string]Item)
+	for _, item := range report.Items {
+		items[item.Name] = item
+	}
+
+	tests := []struct {
+		name    string
+		ok      bool
+		method  string
+		url     string
+		problem string
+	}{
+		{"plain.json", true, "POST", "http://localhost:8080/api/", ""},
+		{"get.json", true, "GET", "http://localhost:8080/items?page=2", ""},
+		{"broken.json", false, "", "", "невалидный JSON"},
+		{"bad_method.json", false, "", "", "конверт запроса"},
+		{"bad_expect.json", false, "POST", "http://localhost:8080/x", "ожидания"},
+		{"sidecar.json", false, "POST", "http://localhost:8080/api/", "ожидания"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			item, ok := items[test.name]
+			if !ok {
+				t.Fatalf("запрос %s не проверен", test.name)
+			}
+			if item.OK() != test.ok {
+				t.Errorf("OK() = %v, ожидалось %v (%v)", item.OK(), test.ok, item.Problems)
+			}
+			if item.Method != test.method || item.URL != test.url {
+				t.Errorf("запрос = %s %s, ожидалось %s %s", item.Method, item.URL, test.method, test.url)
+			}
+			if test.problem != "" && (len(item.Problems) == 0 || !strings.Contains(item.Problems[0], test.problem)) {
+				t.Errorf("Problems = %v, ожидалось %q", item.Problems, test.problem)
+			}
+		})
+	}
+
+	if report.OK() || report.Problems != 4 {
+		t.Errorf("Problems = %d, ожидалось 4", report.Problems)
+	}
+
+	// Повтор содержимого отмечается, но ошибкой не считается
+	if copied := items["copy.json"]; !copied.OK() || copied.DuplicateOf != "" {
+		t.Errorf("первый из одинаковых запросов: %+v", copied)
+	}
+	if plain := items["plain.json"]; plain.DuplicateOf != "copy.json" || plain.Size != 8 {
+		t.Errorf("повтор содержимого: DuplicateOf = %q, Size = %d", plain.DuplicateOf, plain.Size)
+	}
+	if report.Duplicates != 1 {
+		t.Errorf("Duplicates = %d, ожидался 1", report.Duplicates)
+	}
+	var out bytes.Buffer
+	if err := report.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Проверено запросов: 7 (158 B), с ошибками: 4, повторов: 1") {
+		t.Errorf("WriteText() = %s", out.String())
+	}
+}
+
+// TestCheck_Schema проверяет тела запросов по JSON Schema
+func TestCheck_Schema(t *testing.T) {
+	dir := t.TempDir()
+	schemaPath := filepath.Join(t.TempDir(), "schema.json")
+	files := map[string]string{
+		"ok.json":       `{"id": 1}`,
+		"bad.json":      `{"id": "x"}`,
+		"get.json":      `{"method": "GET", "url": "/items"}`,
+		"envelope.json": `{"url": "/items", "body": {}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(schemaPath, []byte(`{"required": ["id"], "properties": {"id": {"type": "integer"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bodySchema, err := schema.Load(schemaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := source.Collect(dir, source.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := Check(jobs, Options{Base: "http://localhost:8080/", Schema: bodySchema})
+	want := map[string]string{
+		"bad.json":      "схема запроса: /id:",
+		"envelope.json": "схема запроса: /:",
+		"get.json":      "", // Без тела схема не проверяется
+		"ok.json":       "",
+	}
+	for _, item := range report.Items {
+		problem := strings.Join(item.Problems, "; ")
+		if want[item.Name] == "" && problem != "" || !strings.HasPrefix(problem, want[item.Name]) {
+			t.Errorf("%s: Problems = %q, ожидалось %q", item.Name, problem, want[item.Name])
+		}
+	}
+}
+
+// TestCheckScenarios проверяет шаги сценариев с пробными значениями извлекаемых переменных
+func TestCheckScenarios(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"create.json": `{"url": "/orders", "body": {"sku": "${sku}"}}`,
+		"get.json":    `{"method": "GET", "url": "/orders/${order_id}", "body": {"id": ${order_id}}}`,
+		"flow.yaml":   "vars: {sku: A-1}\nsteps:\n  - request: create.json\n    extract: {order_id: $.id}\n  - request: get.json\n",
+		"early.yaml":  "steps:\n  - request: get.json\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scenarios, err := scenario.Collect(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noEnv := func(string) (string, bool) { return "", false }
+
+	report := CheckScenarios(scenarios, Options{Base: "http://localhost:8080/", Renderer: render.New(nil, noEnv)})
+	want := map[string]string{
+		"early/01-get.json":   "шаблон: неизвестная переменная ${order_id}",
+		"flow/01-create.json": "",
+		"flow/02-get.json":    "",
+	}
+	if len(report.Items) != len(want) || report.Problems != 1 {
+		t.Fatalf("проверено %d шагов, с ошибками %d", len(report.Items), report.Problems)
+	}
+	for _, item := range report.Items {
+		if problem := strings.Join(item.Problems, "; "); problem != want[item.Name] {
+			t.Errorf("%s: Problems = %q, ожидалось %q", item.Name, problem, want[item.Name])
+		}
+		if item.Name == "flow/02-get.json" && item.URL != "http://localhost:8080/orders/0" {
+			t.Errorf("адрес с пробным значением = %s", item.URL)
+		}
+	}
+}
+
+// TestCheck_Template проверяет подстановку переменных в шаблоны без отправки
+func TestCheck_Template(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"ok.json":      `{"url": "/users/${user}", "body": {"id": "${uuid}"}}`,
+		"unknown.json": `{"url": "/users/${nobody}"}`,
+		"invalid.json": `{"n": ${user}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jobs, err := source.Collect(dir, source.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	noEnv := func(string) (string, bool) { return "", false }
+
+	report := Check(jobs, Options{Base: "http://localhost:8080/", Renderer: render.New(map[string]string{"user": "alice"}, noEnv)})
+	want := map[string]string{
+		"invalid.json": "невалидный JSON",
+		"ok.json":      "",
+		"unknown.json": "шаблон: неизвестная переменная ${nobody}",
+	}
+	for _, item := range report.Items {
+		if problem := strings.Join(item.Problems, "; "); problem != want[item.Name] {
+			t.Errorf("%s: Problems = %q, ожидалось %q", item.Name, problem, want[item.Name])
+		}
+		if item.Name == "ok.json" && item.URL != "http://localhost:8080/users/alice" {
+			t.Errorf("адрес после подстановки = %s", item.URL)
+		}
+	}
+}
@@ -2,41 +2,401 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"poster/internal/archive"
+	"poster/internal/compare"
 	"poster/internal/config"
+	"poster/internal/deps"
+	"poster/internal/envelope"
+	"poster/internal/expect"
+	"poster/internal/fdlimit"
+	"poster/internal/journal"
+	"poster/internal/load"
 	"poster/internal/logger"
+	"poster/internal/mock"
+	"poster/internal/ratelimit"
+	"poster/internal/render"
+	"poster/internal/report"
+	"poster/internal/retry"
+	"poster/internal/scenario"
+	"poster/internal/schema"
+	"poster/internal/source"
+	"poster/internal/stats"
+	"poster/internal/stream"
+	"poster/internal/validate"
+	"sort"
 	"sync"
+	"syscall"
 	"time"
 )
 
+// errNotSent = запрос не отправлен: остановка во время ожидания ограничителя скорости
+var errNotSent = errors.New("запрос не отправлен из-за остановки")
+
+// Коды завершения
+const (
+	exitOK      = 0 // Прогон завершен
+	exitError   = 1 // Ошибка запуска
+	exitChanged = 2 // Ответы отличаются от эталона
+	exitFailed  = 3 // Есть непройденные сценарии
+)
+
+// schemas = JSON Schema тел запросов и ответов (nil - без проверки)
+type schemas struct {
+	request  *schema.Schema
+	response *schema.Schema
+}
+
+// loadSchemas загружает схемы из конфигурации
+func loadSchemas(cfg *config.Config) (schemas, error) {
+	var s schemas
+	var err error
+	if cfg.RequestSchema != "" {
+		if s.request, err = schema.Load(cfg.RequestSchema); err != nil {
+			return s, err
+		}
+	}
+	if cfg.ResponseSchema != "" {
+		if s.response, err = schema.Load(cfg.ResponseSchema); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// newRenderer создает подстановщик переменных из файла cfg.Vars и окружения. Без -template и -vars
+// запросы отправляются как есть (nil), сценариям подстановка нужна всегда: в шаги попадают значения из ответов.
+func newRenderer(cfg *config.Config) (*render.Renderer, error) {
+	if !cfg.Template && cfg.Scenarios == "" {
+		return nil, nil
+	}
+	var vars map[string]string
+	if cfg.Vars != "" {
+		var err error
+		if vars, err = render.LoadVars(cfg.Vars); err != nil {
+			return nil, fmt.Errorf("%s: %v", cfg.Vars, err)
+		}
+	}
+	return render.New(vars, os.LookupEnv), nil
+}
+
+// Task = задача воркера: файл запроса с номером прохода и этапа нагрузочного прогона
+type Task struct {
+	source.Job
+	Iteration int       // Номер прохода по файлам (с 1)
+	Stage     int       // Номер этапа нагрузки (с 0)
+	Intended  time.Time // Время отправки по расписанию open-loop (нулевое - без расписания)
+}
+
 // Result содержит результат обработки файла
 type Result struct {
 	FileName     string
+	Index        int           // Номер запроса во входных данных (с 0)
+	Start        time.Time     // Начало обработки, у отправленных запросов - начало последней попытки
+	Line         int           // Номер строки JSONL (0 для целого файла)
+	Offset       int64         // Смещение строки JSONL в байтах
+	Method       string        // HTTP метод
+	URL          string        // Адрес запроса
 	FileSize     int64         // Размер файла запроса
 	RequestSize  int           // Размер JSON данных
 	ResponseSize int           // Размер ответа
-	Duration     time.Duration // Время обработки
+	Duration     time.Duration // Время обработки, у отправленных запросов - задержка ответа на последнюю попытку
 	StatusCode   int           // HTTP статус код
+	Attempts     int           // Количество попыток отправки
+	Backoff      time.Duration // Паузы перед повторами
+	Throttle     time.Duration // Ожидание ограничителя скорости
+	Iteration    int           // Номер прохода по файлам
+	Stage        int           // Номер этапа нагрузки
+	Intended     time.Time     // Время отправки по расписанию open-loop
+	Hash         string        // Хэш содержимого запроса для журнала
+	BodyPreview  string        // Начало тела ответа при ошибке сервера
+	Rendered     string        // Начало запроса после подстановки шаблона (пусто - запрос не шаблон)
+	Body         []byte        // Тело ответа для извлечения значений сценария и вывода в stdout
+	Header       http.Header   // Заголовки ответа для извлечения значений сценария
 	Err          error
+	ErrType      string            // Тип ошибки (report.ErrRead, report.ErrJSON, ...)
+	Diff         *compare.FileDiff // Сравнение с эталоном (nil - ответ не сохранен или сравнение выключено)
+}
+
+// Record возвращает результат в виде записи отчета
+func (r Result) Record() report.Record {
+	rec := report.Record{
+		File:         r.FileName,
+		Line:         r.Line,
+		Offset:       r.Offset,
+		Method:       r.Method,
+		URL:          r.URL,
+		Start:        r.Start,
+		Duration:     r.Duration,
+		StatusCode:   r.StatusCode,
+		Attempts:     r.Attempts,
+		Backoff:      r.Backoff,
+		Throttle:     r.Throttle,
+		FileSize:     r.FileSize,
+		RequestSize:  r.RequestSize,
+		ResponseSize: r.ResponseSize,
+		ErrorType:    r.ErrType,
+		BodyPreview:  r.BodyPreview,
+		Rendered:     r.Rendered,
+	}
+	if r.Err != nil {
+		rec.Error = r.Err.Error()
+	}
+	return rec
+}
+
+// Sample возвращает результат в виде выборки для статистики
+func (r Result) Sample() stats.Sample {
+	return stats.Sample{
+		Start:        r.Start,
+		Duration:     r.Duration,
+		StatusCode:   r.StatusCode,
+		FileSize:     r.FileSize,
+		RequestSize:  r.RequestSize,
+		ResponseSize: r.ResponseSize,
+		Attempts:     r.Attempts,
+		Intended:     r.Intended,
+		ErrType:      r.ErrType,
+	}
 }
 
 func main() {
+	command, args := config.Split(os.Args[1:])
+	stdout, stderr := io.Writer(os.Stdout), io.Writer(os.Stderr)
+	switch command {
+	case config.CommandRun, config.CommandValidate:
+		os.Exit(run(stdout, stderr))
+	case config.CommandDiff:
+		os.Exit(diffCommand(args, stdout, stderr))
+	case config.CommandReport:
+		os.Exit(reportCommand(args, stdout, stderr))
+	case config.CommandServe:
+		os.Exit(serveCommand(args, stdout, stderr))
+	case config.CommandHelp:
+		config.PrintUsage(stdout)
+	default:
+		fmt.Fprintf(stderr, "Неизвестная подкоманда %q\n\n", command)
+		config.PrintUsage(stderr)
+		os.Exit(exitError)
+	}
+}
+
+// validateCommand проверяет запросы без отправки и печатает, что и куда было бы отправлено
+func validateCommand(cfg *config.Config, stdout, stderr io.Writer, log *logger.Logger) int {
+	bodySchemas, err := loadSchemas(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "Ошибка загрузки схемы: %v\n", err)
+		return exitError
+	}
+
+	renderer, err := newRenderer(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "Ошибка чтения файла переменных: %v\n", err)
+		return exitError
+	}
+
+	opts := validate.Options{
+		Base:     cfg.URL,
+		Schema:   bodySchemas.request,
+		Renderer: renderer,
+	}
+	var result *validate.Report
+	var depsErr error // Ошибка графа depends_on: неизвестное имя или цикл
+	if cfg.Scenarios != "" {
+		scenarios, err := scenario.Collect(cfg.Scenarios)
+		if err != nil {
+			fmt.Fprintf(stderr, "Ошибка загрузки сценариев: %v\n", err)
+			return exitError
+		}
+		result = validate.CheckScenarios(scenarios, opts)
+	} else {
+		filter, err := source.NewFilter(cfg.Include, cfg.Exclude)
+		if err != nil {
+			fmt.Fprintf(stderr, "Ошибка шаблонов отбора файлов: %v\n", err)
+			return exitError
+		}
+		jobs, err := source.Collect(cfg.RequestsDir, filter)
+		if err != nil {
+			fmt.Fprintf(stderr, "Ошибка чтения запросов %s: %v\n", cfg.RequestsDir, err)
+			return exitError
+		}
+		result = validate.Check(jobs, opts)
+		if _, err := deps.Build(jobs); err != nil {
+			depsErr = err
+		}
+	}
+	if err := result.WriteText(stdout); err != nil {
+		log.Error("Ошибка вывода проверки", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	log.Info("Проверка запросов", map[string]interface{}{
+		"requests":  cfg.RequestsDir,
+		"scenarios": cfg.Scenarios,
+		"count":     len(result.Items),
+		"problems":  result.Problems,
+	})
+	if depsErr != nil {
+		fmt.Fprintf(stderr, "Ошибка зависимостей запросов: %v\n", depsErr)
+		log.Error("Ошибка зависимостей запросов", map[string]interface{}{
+			"error": depsErr.Error(),
+		})
+	}
+	if !result.OK() || depsErr != nil {
+		return exitError
+	}
+	return exitOK
+}
+
+// diffCommand сравнивает две директории ответов без прогона
+func diffCommand(args []string, stdout, stderr io.Writer) int {
+	cfg, err := config.ParseDiff(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Ошибка конфигурации: %v\n", err)
+		return exitError
+	}
+
+	comparer, err := compare.New(cfg.Baseline, cfg.Ignore)
+	if err != nil {
+		fmt.Fprintf(stderr, "Ошибка правил сравнения: %v\n", err)
+		return exitError
+	}
+	diffs, err := comparer.Dir(cfg.Responses)
+	if err != nil {
+		fmt.Fprintf(stderr, "Ошибка сравнения %s с %s: %v\n", cfg.Responses, cfg.Baseline, err)
+		return exitError
+	}
+	if err := diffs.WriteText(stdout); err != nil {
+		fmt.Fprintf(stderr, "Ошибка вывода сравнения: %v\n", err)
+		return exitError
+	}
+	if cfg.Out != "" {
+		if err := diffs.Write(cfg.Out); err != nil {
+			fmt.Fprintf(stderr, "Ошибка записи отчета сравнения %s: %v\n", cfg.Out, err)
+			return exitError
+		}
+	}
+	if diffs.HasChanges() {
+		return exitChanged
+	}
+	return exitOK
+}
+
+// reportCommand строит отчеты по JSON отчету прошлого прогона
+func reportCommand(args []string, stdout, stderr io.Writer) int {
+	cfg, err := config.ParseReport(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Ошибка конфигурации: %v\n", err)
+		return exitError
+	}
+
+	runReport, err := report.Read(cfg.Input)
+	if err != nil {
+		fmt.Fprintf(stderr, "Ошибка чтения отчета %s: %v\n", cfg.Input, err)
+		return exitError
+	}
+	if len(cfg.Outputs) == 0 {
+		if err := runReport.Summary.WriteTable(stdout); err != nil {
+			fmt.Fprintf(stderr, "Ошибка вывода статистики: %v\n", err)
+			return exitError
+		}
+		if len(runReport.Stages) > 0 {
+			fmt.Fprintln(stdout)
+			if err := stats.WriteRows(stdout, "Этап", runReport.Stages); err != nil {
+				fmt.Fprintf(stderr, "Ошибка вывода статистики по этапам: %v\n", err)
+				return exitError
+			}
+		}
+		return exitOK
+	}
+	exitCode := exitOK
+	for _, path := range cfg.Outputs {
+		if err := report.Write(path, runReport); err != nil {
+			fmt.Fprintf(stderr, "Ошибка записи отчета %s: %v\n", path, err)
+			exitCode = exitError
+			continue
+		}
+		fmt.Fprintf(stdout, "Отчет: %s\n", path)
+	}
+	return exitCode
+}
+
+// serveCommand запускает тестовый сервер до SIGINT/SIGTERM
+func serveCommand(args []string, stdout, stderr io.Writer) int {
+	cfg, err := config.ParseServe(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Ошибка конфигурации: %v\n", err)
+		return exitError
+	}
+
+	server := &http.Server{
+		Addr: cfg.Addr,
+		Handler: mock.New(mock.Options{
+			Delay:     cfg.Delay,
+			Status:    cfg.Status,
+			ErrorRate: cfg.ErrorRate,
+			Fixtures:  cfg.Fixtures,
+		}),
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(stdout, "Тестовый сервер: http://%s\n", cfg.Addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(stderr, "Ошибка сервера: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// run выполняет прогон и возвращает код завершения. Ответы при -responses - пишутся в stdout, сообщения
+// и статистика - в stdout или, если он занят ответами, в stderr; ошибки конфигурации - в stderr
+func run(stdout, stderr io.Writer) int {
 	cfg, err := config.New()
 	if err != nil {
-		fmt.Printf("Ошибка конфигурации %+v: %v", cfg, err)
-		return
+		fmt.Fprintf(stderr, "Ошибка конфигурации %+v: %v\n", cfg, err)
+		return exitError
+	}
+
+	// Сообщения, статистика и лог уровня stdout: при -responses - stdout занят ответами, они выводятся в stderr
+	console := stdout
+	if cfg.Command == config.CommandRun && cfg.ResponsesDir == stream.Stdout {
+		console = stderr
 	}
 
 	// Создание логгера
 	mainLogger, err := logger.New(cfg.Log, "log.json")
 	if err != nil {
-		fmt.Printf("Ошибка инициализации логгера: %v\n", err)
-		return
+		fmt.Fprintf(console, "Ошибка инициализации логгера: %v\n", err)
+		return exitError
+	}
+	if cfg.Log == "stdout" {
+		mainLogger.SetOutput(console)
 	}
 	defer mainLogger.Info("Приложение завершено")
 
@@ -52,116 +412,762 @@ func main() {
 		"file":  "log.json",
 	})
 
+	// Итоговая конфигурация после файла, профиля, окружения и флагов, с источниками значений
 	mainLogger.Info("Запуск приложения", map[string]interface{}{
-		"config": map[string]interface{}{
-			"url":           cfg.URL,
-			"requests_dir":  cfg.RequestsDir,
-			"responses_dir": cfg.ResponsesDir,
-			"timeout":       cfg.Timeout,
-			"workers":       cfg.Workers,
-			"level":         cfg.Log,
-			"file":          "log.json",
-		},
+		"config":      cfg.Effective,
+		"config_file": cfg.ConfigFile,
+		"profile":     cfg.Profile,
+		"sources":     cfg.Sources,
+		"file":        "log.json",
 	})
 
-	// Проверка наличия директории с запросами
-	if _, err := os.Stat(cfg.RequestsDir); os.IsNotExist(err) {
+	// Проверка запросов без отправки
+	if cfg.Command == config.CommandValidate {
+		return validateCommand(cfg, stdout, stderr, mainLogger)
+	}
+
+	// Проверка наличия директории с запросами (сценарии сами ссылаются на файлы запросов, stdin проверять не нужно)
+	if _, err := os.Stat(cfg.RequestsDir); cfg.Scenarios == "" && cfg.RequestsDir != source.Stdin && os.IsNotExist(err) {
 		mainLogger.Fatal("Директория с запросами не существует", map[string]interface{}{
 			"directory": cfg.RequestsDir,
 		})
 	}
 
-	// Создание директории для ответов, если её нет
-	if err := os.MkdirAll(cfg.ResponsesDir, 0755); err != nil {
-		mainLogger.Fatal("Ошибка создания директории для ответов", map[string]interface{}{
-			"directory": cfg.ResponsesDir,
-			"error":     err.Error(),
+	// Создание директории для ответов, если её нет (архив ответов создается перед запуском воркеров)
+	if !archive.Is(cfg.ResponsesDir) && cfg.ResponsesDir != stream.Stdout {
+		if err := os.MkdirAll(cfg.ResponsesDir, 0755); err != nil {
+			mainLogger.Fatal("Ошибка создания директории для ответов", map[string]interface{}{
+				"directory": cfg.ResponsesDir,
+				"error":     err.Error(),
+			})
+		}
+	}
+
+	// Сравнение ответов с эталонной директорией
+	var comparer *compare.Comparer
+	if cfg.Baseline != "" {
+		if _, err := os.Stat(cfg.Baseline); err != nil {
+			fmt.Fprintf(console, "Директория эталонных ответов недоступна: %v\n", err)
+			mainLogger.Error("Директория эталонных ответов недоступна", map[string]interface{}{
+				"directory": cfg.Baseline,
+				"error":     err.Error(),
+			})
+			return exitError
+		}
+		if comparer, err = compare.New(cfg.Baseline, cfg.Ignore); err != nil {
+			fmt.Fprintf(console, "Ошибка правил сравнения: %v\n", err)
+			mainLogger.Error("Ошибка правил сравнения", map[string]interface{}{
+				"ignore": cfg.Ignore,
+				"error":  err.Error(),
+			})
+			return exitError
+		}
+	}
+
+	// JSON Schema тел запросов и ответов
+	bodySchemas, err := loadSchemas(cfg)
+	if err != nil {
+		fmt.Fprintf(console, "Ошибка загрузки схемы: %v\n", err)
+		mainLogger.Error("Ошибка загрузки схемы", map[string]interface{}{
+			"request_schema":  cfg.RequestSchema,
+			"response_schema": cfg.ResponseSchema,
+			"error":           err.Error(),
+		})
+		return exitError
+	}
+
+	// Сценарии вместо директории запросов: шаги ссылаются на файлы запросов
+	var scenarios []*scenario.Scenario
+	var jobs []source.Job
+	var filter source.Filter
+	tasks := 0 // Запросов в прогоне без повторов: файлы или шаги сценариев
+
+	// Архив и stdin в одиночном прогоне читаются потоком: запросы уходят воркерам по мере чтения,
+	// а не собираются в память (с stdin - не дожидаясь EOF, как в tail -f | poster -requests -).
+	// Продолжению прогона и нагрузке нужен весь список запросов заранее
+	streamed := cfg.Scenarios == "" && (archive.Is(cfg.RequestsDir) || cfg.RequestsDir == source.Stdin) &&
+		!cfg.Resume && !cfg.LoadMode()
+	if cfg.Scenarios != "" {
+		if scenarios, err = scenario.Collect(cfg.Scenarios); err != nil {
+			fmt.Fprintf(console, "Ошибка загрузки сценариев: %v\n", err)
+			mainLogger.Fatal("Ошибка загрузки сценариев", map[string]interface{}{
+				"scenarios": cfg.Scenarios,
+				"error":     err.Error(),
+			})
+		}
+		if len(scenarios) == 0 {
+			mainLogger.Info("Сценарии не найдены", map[string]interface{}{
+				"scenarios": cfg.Scenarios,
+			})
+			return exitOK
+		}
+		for _, sc := range scenarios {
+			tasks += len(sc.Steps)
+		}
+		mainLogger.Info("Найдены сценарии", map[string]interface{}{
+			"count": len(scenarios),
+			"steps": tasks,
+		})
+	} else if filter, err = source.NewFilter(cfg.Include, cfg.Exclude); err != nil {
+		fmt.Fprintf(console, "Ошибка шаблонов отбора файлов: %v\n", err)
+		mainLogger.Error("Ошибка шаблонов отбора файлов", map[string]interface{}{
+			"include": cfg.Include,
+			"exclude": cfg.Exclude,
+			"error":   err.Error(),
+		})
+		return exitError
+	} else if streamed {
+		mainLogger.Info("Запросы читаются потоком", map[string]interface{}{
+			"requests": cfg.RequestsDir,
 		})
+	} else {
+		// Чтение всех запросов из директории и поддиректорий: *.json целиком, *.jsonl построчно
+		if jobs, err = source.Collect(cfg.RequestsDir, filter); err != nil {
+			fmt.Fprintf(console, "Ошибка чтения запросов %s: %v\n", cfg.RequestsDir, err)
+			mainLogger.Fatal("Ошибка чтения директории с запросами", map[string]interface{}{
+				"directory": cfg.RequestsDir,
+				"error":     err.Error(),
+			})
+		}
+
+		if len(jobs) == 0 {
+			mainLogger.Info("В папке requests не найдено JSON файлов")
+			return exitOK
+		}
+		mainLogger.Info("Найдены файлы для отправки", map[string]interface{}{
+			"count": len(jobs),
+		})
+	}
+	allJobs := jobs
+
+	// Продолжение прогона: пропускаем файлы, уже успешно обработанные с тем же содержимым
+	if cfg.Resume {
+		completed, err := journal.Load(cfg.Journal)
+		if err != nil {
+			fmt.Fprintf(console, "Ошибка журнала %s: %v\n", cfg.Journal, err)
+			mainLogger.Error("Ошибка чтения журнала", map[string]interface{}{
+				"journal": cfg.Journal,
+				"error":   err.Error(),
+			})
+			return exitError
+		}
+		total := len(jobs)
+		jobs = resumeJobs(jobs, completed, mainLogger)
+		fmt.Fprintf(console, "Продолжение прогона: пропущено %d из %d файлов\n", total-len(jobs), total)
+		if len(jobs) == 0 {
+			mainLogger.Info("Все файлы уже обработаны")
+			return exitOK
+		}
 	}
 
-	// Чтение всех запросов
-	filePaths, err := filepath.Glob(cfg.RequestsDir + "/*.json")
+	// Зависимости запросов (depends_on) проверяются до начала прогона: неизвестные имена и циклы - ошибка.
+	// Граф строится по всем файлам: при -resume уже выполненные зависимости не ждут
+	var scheduler *deps.Scheduler
+	if len(scenarios) == 0 && !streamed {
+		graph, err := deps.Build(allJobs)
+		if err != nil {
+			fmt.Fprintf(console, "Ошибка зависимостей запросов: %v\n", err)
+			mainLogger.Fatal("Ошибка зависимостей запросов", map[string]interface{}{
+				"directory": cfg.RequestsDir,
+				"error":     err.Error(),
+			})
+		}
+		if !graph.Empty() {
+			if cfg.LoadMode() {
+				fmt.Fprintln(console, "depends_on несовместим с нагрузочным прогоном (duration, iterations, stages)")
+				mainLogger.Fatal("depends_on несовместим с нагрузочным прогоном", map[string]interface{}{
+					"duration":   cfg.Duration.String(),
+					"iterations": cfg.Iterations,
+					"stages":     fmt.Sprint(cfg.Stages),
+				})
+			}
+			scheduler = graph.Schedule(jobs)
+			mainLogger.Info("Запросы отправляются в порядке зависимостей")
+		}
+	}
+
+	// Журнал обработанных файлов: без -resume начинается заново
+	jrnl, err := journal.Open(cfg.Journal, cfg.Resume)
 	if err != nil {
-		mainLogger.Fatal("Ошибка чтения директории с запросами", map[string]interface{}{
-			"directory": cfg.RequestsDir,
-			"error":     err.Error(),
+		fmt.Fprintf(console, "Ошибка журнала %s: %v\n", cfg.Journal, err)
+		mainLogger.Error("Ошибка открытия журнала", map[string]interface{}{
+			"journal": cfg.Journal,
+			"error":   err.Error(),
 		})
+		return exitError
 	}
+	defer jrnl.Close()
 
-	if len(filePaths) == 0 {
-		mainLogger.Info("В папке requests не найдено JSON файлов")
-		return
+	// План прогона: один проход или нагрузка по времени, итерациям и этапам
+	plan := cfg.Plan()
+	if cfg.LoadMode() {
+		mainLogger.Info("Нагрузочный прогон", map[string]interface{}{
+			"duration":   plan.Total().String(),
+			"iterations": plan.Iterations,
+			"order":      plan.Order,
+			"ramp":       plan.Ramp,
+			"stages":     fmt.Sprint(plan.Stages),
+			"open_loop":  cfg.OpenLoop,
+		})
 	}
-	mainLogger.Info("Найдены файлы для отправки", map[string]interface{}{
-		"count": len(filePaths),
-	})
 
 	// Ограничиваем количество одновременных горутин
-	if len(filePaths) < cfg.Workers {
-		cfg.Workers = len(filePaths)
+	var gate *load.Gate
+	if len(plan.Stages) > 0 && plan.Ramp == load.RampWorkers {
+		// Воркеры запускаются на пик нагрузки, активны - по этапу
+		cfg.Workers = load.Workers(plan.MaxTarget())
+		gate = load.NewGate(1)
+	} else if len(scenarios) > 0 && len(scenarios) < cfg.Workers {
+		// Шаги сценария идут по порядку: воркеров больше, чем сценариев, не нужно
+		cfg.Workers = len(scenarios)
+	} else if len(scenarios) == 0 && !streamed && !cfg.LoadMode() && len(jobs) < cfg.Workers {
+		cfg.Workers = len(jobs)
 	}
 
 	mainLogger.Debug("Настройка воркеров", map[string]interface{}{
-		"workers": cfg.Workers,
-		"files":   len(filePaths),
+		"workers":   cfg.Workers,
+		"files":     len(jobs),
+		"scenarios": len(scenarios),
 	})
 
-	// Каналы для работы
-	filesChan := make(chan string, len(filePaths))
-	resultsChan := make(chan Result, len(filePaths))
+	// Каналы для работы: задачи выдаются по одной, чтобы после сигнала остановки
+	// в канале не оставалось уже выданных, но не начатых файлов
+	filesChan := make(chan Task)
+	buffered := len(jobs) + tasks
+	if streamed {
+		buffered = cfg.Workers // Количество запросов заранее неизвестно
+	}
+	resultsChan := make(chan Result, buffered)
+	outcomes := make(chan report.Scenario, len(scenarios))
+
+	// Корневой контекст отменяется по SIGINT/SIGTERM: новые файлы не выдаются,
+	// а отправленным запросам дается cfg.Drain на завершение
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	reqCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-reqCtx.Done():
+			return
+		}
+		stop() // Повторный сигнал завершает процесс сразу
+		mainLogger.Warn("Получен сигнал остановки, новые файлы не отправляются", map[string]interface{}{
+			"drain": cfg.Drain.String(),
+		})
+		fmt.Fprintf(console, "\nОстановка: ожидание завершения отправленных запросов (до %v)...\n", cfg.Drain)
+
+		timer := time.NewTimer(cfg.Drain)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			mainLogger.Warn("Время ожидания истекло, незавершенные запросы прерваны")
+			cancelRequests()
+		case <-reqCtx.Done():
+		}
+	}()
+
+	// Каждый воркер держит открытыми соединение и файл ответа: сотни воркеров упираются в ulimit -n
+	if soft, exceeded := fdlimit.Check(cfg.Workers); exceeded {
+		mainLogger.Warn("Лимит открытых файлов меньше нужного воркерам", map[string]interface{}{
+			"workers": cfg.Workers,
+			"need":    fdlimit.Need(cfg.Workers),
+			"limit":   soft,
+		})
+		fmt.Fprintf(console, "Внимание: %d воркерам нужно до %d открытых файлов, лимит %d (ulimit -n): возможны ошибки \"too many open files\"\n",
+			cfg.Workers, fdlimit.Need(cfg.Workers), soft)
+	}
 
 	// Создание HTTP клиента с таймаутом
 	client := &http.Client{
 		Timeout: time.Duration(cfg.Timeout) * time.Second,
 		Transport: &http.Transport{
-			MaxIdleConns:        cfg.Workers * 10, // Максимальное общее количество "бездействующих" (idle) соединений в пуле ко всем хостам.
-			MaxIdleConnsPerHost: cfg.Workers * 10, // Максимальное количество idle-соединений к одному конкретному хосту.
-			MaxConnsPerHost:     cfg.Workers * 20, // Максимальное общее количество соединений к одному хосту (idle + active).
+			// У воркера одновременно не больше одного запроса, поэтому пул рассчитан на количество воркеров:
+			// соединений не больше, чем нужно, и открытые файлы не растут сверх оценки fdlimit.Need
+			MaxIdleConns:        cfg.Workers, // Максимальное общее количество "бездействующих" (idle) соединений в пуле ко всем хостам.
+			MaxIdleConnsPerHost: cfg.Workers, // Максимальное количество idle-соединений к одному конкретному хосту.
+			MaxConnsPerHost:     cfg.Workers, // Максимальное общее количество соединений к одному хосту (idle + active).
 
 			IdleConnTimeout: time.Duration(cfg.Timeout*3) * time.Second, // Таймаут на неактивные соединения
 		},
 	}
 
+	// Политика повторов запросов
+	policy := retry.Policy{
+		MaxAttempts: cfg.RetryAttempts,
+		BaseDelay:   cfg.RetryBaseDelay,
+		MaxDelay:    cfg.RetryMaxDelay,
+		Jitter:      cfg.RetryJitter,
+		StatusCodes: cfg.RetryStatusCodes,
+		Network:     cfg.RetryNetwork,
+	}
+
+	// Ограничение скорости: общее на всех воркеров и по хостам.
+	// В open-loop скорость задает расписание выдачи задач, общий ограничитель не нужен
+	rate := cfg.RPS
+	if cfg.OpenLoop {
+		rate = 0
+	}
+	limits := ratelimit.NewSet(rate, cfg.Burst, cfg.HostRPS)
+
+	// Ожидания к ответам из файлов name.expect.json загружаются один раз на файл
+	expectations := expect.NewLoader()
+
+	// Подстановка переменных в шаблоны запросов, счетчик seq общий на весь прогон
+	renderer, err := newRenderer(cfg)
+	if err != nil {
+		fmt.Fprintf(console, "Ошибка чтения файла переменных: %v\n", err)
+		mainLogger.Error("Ошибка чтения файла переменных", map[string]interface{}{
+			"vars":  cfg.Vars,
+			"error": err.Error(),
+		})
+		return exitError
+	}
+
+	// Архив ответов, если -responses - файл .tar, .tar.gz, .tgz или .zip
+	var responsesArchive *archive.Writer
+	if archive.Is(cfg.ResponsesDir) {
+		if responsesArchive, err = archive.Create(cfg.ResponsesDir); err != nil {
+			fmt.Fprintf(console, "Ошибка создания архива ответов: %v\n", err)
+			mainLogger.Fatal("Ошибка создания архива ответов", map[string]interface{}{
+				"archive": cfg.ResponsesDir,
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	// Ответы в stdout: строки NDJSON с номером запроса во входных данных
+	var responsesStream *stream.Writer
+	if cfg.ResponsesDir == stream.Stdout {
+		responsesStream = stream.NewWriter(stdout, cfg.OutputOrder)
+	}
+
 	// Запускаем воркеров
+	started := time.Now()
+	if gate != nil {
+		go gate.Follow(ctx, started, plan)
+	}
 	var wg sync.WaitGroup
 	workerLogger := mainLogger.WithFields(map[string]interface{}{
 		"component": "worker",
 	})
-	for i := 0; i < cfg.Workers; i++ {
-		wg.Add(1)
-		go work(i, client, cfg.URL, cfg.ResponsesDir, filesChan, resultsChan, &wg, workerLogger)
+	p := &pipeline{
+		client:       client,
+		url:          cfg.URL,
+		headers:      cfg.RequestHeaders(),
+		responsesDir: cfg.ResponsesDir,
+		policy:       policy,
+		limits:       limits,
+		expectations: expectations,
+		schemas:      bodySchemas,
+		renderer:     renderer,
+		comparer:     comparer,
+		scheduler:    scheduler,
+
+		responsesArchive: responsesArchive,
+		responsesStream:  responsesStream != nil,
+		streamed:         streamed,
+	}
+	if len(scenarios) > 0 {
+		// Сценарии выполняются параллельно, шаги каждого - по порядку в одном воркере
+		scenarioChan := make(chan *scenario.Scenario, len(scenarios))
+		for _, sc := range scenarios {
+			scenarioChan <- sc
+		}
+		close(scenarioChan)
+		for i := 0; i < cfg.Workers; i++ {
+			wg.Add(1)
+			go scenarioWork(ctx, reqCtx, i, p, scenarioChan, resultsChan, outcomes, &wg, workerLogger)
+		}
+	} else {
+		for i := 0; i < cfg.Workers; i++ {
+			wg.Add(1)
+			go work(ctx, reqCtx, i, p, gate, filesChan, resultsChan, &wg, workerLogger)
+		}
 	}
 
-	// Отправляем задачи в канал
-	for _, filePath := range filePaths {
-		filesChan <- filePath
+	// Выдача задач ограничена длительностью нагрузочного прогона
+	dispatchCtx, cancelDispatch := context.WithCancel(ctx)
+	if total := plan.Total(); total > 0 {
+		dispatchCtx, cancelDispatch = context.WithTimeout(ctx, total)
 	}
-	close(filesChan)
-	mainLogger.Debug("Все задачи отправлены в канал")
+	defer cancelDispatch()
+
+	// Чтение запросов потоком: задачи передаются выдаче по мере чтения. Ошибка чтения завершает выдачу,
+	// отправленные запросы завершаются
+	var incoming chan source.Job
+	readErrs := make(chan error, 1)
+	if streamed {
+		incoming = make(chan source.Job)
+		go func() {
+			defer close(incoming)
+			err := source.Walk(cfg.RequestsDir, filter, func(job source.Job) error {
+				select {
+				case incoming <- job:
+					return nil
+				case <-dispatchCtx.Done():
+					return dispatchCtx.Err()
+				}
+			})
+			if err != nil && dispatchCtx.Err() == nil {
+				readErrs <- err
+			}
+		}()
+	}
+
+	// Наращивание скорости по этапам: задачи выдаются с текущей скоростью плана
+	var pacer *load.Pacer
+	if len(plan.Stages) > 0 && plan.Ramp == load.RampRPS && !cfg.OpenLoop {
+		pacer = load.NewPacer(started, plan.TargetAt)
+	}
+
+	// Open-loop: задачи выдаются по расписанию независимо от завершения запросов.
+	// Если воркеры заняты, задача ждет в очереди, и это время входит в задержку от расписания
+	var schedule *load.Schedule
+	if cfg.OpenLoop {
+		rateAt := func(time.Duration) float64 { return cfg.RPS }
+		if len(plan.Stages) > 0 {
+			rateAt = plan.TargetAt
+		}
+		schedule = load.NewSchedule(started, rateAt, plan.Total())
+	}
+
+	// Отправляем задачи в канал, пока не получен сигнал остановки или не истекло время прогона.
+	// Выдача идет параллельно со сбором результатов: в нагрузочном прогоне результатов больше, чем файлов.
+	// В режиме сценариев файлов нет, канал сразу закрывается
+	go func() {
+		next := load.NewSequence(jobs, plan.Iterations, plan.Order, nil).Next
+		if scheduler != nil {
+			// Запрос выдается, когда выполнены его зависимости
+			next = func() (source.Job, int, bool) {
+				job, ok := scheduler.Next(dispatchCtx)
+				return job, 1, ok
+			}
+		}
+		if streamed {
+			next = func() (source.Job, int, bool) {
+				select {
+				case job, ok := <-incoming:
+					return job, 1, ok
+				case <-dispatchCtx.Done():
+					return source.Job{}, 0, false
+				}
+			}
+		}
+		dispatched := 0
+	dispatch:
+		for {
+			job, iteration, ok := next()
+			if !ok {
+				break
+			}
+			if pacer != nil && pacer.Wait(dispatchCtx) != nil {
+				break
+			}
+			var intended time.Time
+			if schedule != nil {
+				var err error
+				if intended, err = schedule.Wait(dispatchCtx); err != nil {
+					break
+				}
+			}
+			if dispatchCtx.Err() != nil {
+				break
+			}
+			task := Task{Job: job, Iteration: iteration, Stage: plan.StageAt(time.Since(started)), Intended: intended}
+			select {
+			case <-dispatchCtx.Done():
+				break dispatch
+			case filesChan <- task:
+				dispatched++
+			}
+		}
+		close(filesChan)
+		gate.Open()
+		mainLogger.Debug("Задачи отправлены в канал", map[string]interface{}{
+			"dispatched": dispatched,
+			"total":      len(jobs),
+		})
+	}()
 
 	// Ждем завершения воркеров
 	go func() {
 		wg.Wait()
 		close(resultsChan)
+		close(outcomes)
 		mainLogger.Debug("Все воркеры завершили работу")
 	}()
 
-	// Собираем результаты
-	successCount, errorCount := 0, 0
+	// Собираем результаты в агрегатор статистики и отчет
+	agg := stats.New()
+	stageAggs := make([]*stats.Aggregator, len(plan.Stages))
+	for i := range stageAggs {
+		stageAggs[i] = stats.New()
+	}
+	diffs := &compare.Report{Baseline: cfg.Baseline}
+	records := make([]report.Record, 0, len(jobs))
+	attempted := make(map[string]bool, len(jobs))
 	for result := range resultsChan {
+		attempted[result.FileName] = true
+		agg.Add(result.Sample())
+		if len(stageAggs) > 0 {
+			stageAggs[result.Stage].Add(result.Sample())
+		}
+		if len(cfg.Reports) > 0 {
+			records = append(records, result.Record())
+		}
+		if comparer != nil {
+			if result.Diff != nil {
+				diffs.Add(*result.Diff)
+			} else if diff, ok := comparer.Missing(result.FileName); ok {
+				diffs.Add(diff)
+			}
+		}
+
+		entry := journal.Entry{
+			Name:       result.FileName,
+			Status:     journal.StatusOK,
+			Hash:       result.Hash,
+			StatusCode: result.StatusCode,
+		}
 		if result.Err != nil {
-			errorCount++
-			fmt.Printf("Ошибка обработки файла %s: %v\n", result.FileName, result.Err)
+			entry.Status = journal.StatusFailed
+			entry.Error = result.Err.Error()
+		}
+		if err := jrnl.Append(entry); err != nil {
+			mainLogger.Error("Ошибка записи в журнал", map[string]interface{}{
+				"file":  result.FileName,
+				"error": err.Error(),
+			})
+		}
+
+		if responsesStream != nil {
+			record := stream.Record{
+				Index:  result.Index,
+				Name:   result.FileName,
+				Status: result.StatusCode,
+				Body:   stream.Body(result.Body),
+			}
+			if result.Err != nil {
+				record.Error = result.Err.Error()
+			}
+			if err := responsesStream.Write(record); err != nil {
+				mainLogger.Error("Ошибка вывода ответа в stdout", map[string]interface{}{
+					"file":  result.FileName,
+					"error": err.Error(),
+				})
+			}
+		}
+
+		if result.Err == nil {
+			continue
+		}
+		if result.ErrType == report.ErrAssert {
+			fmt.Fprintf(console, "Проверка ответа не пройдена %s: %v\n", result.FileName, result.Err)
+			continue
+		}
+		if result.ErrType == report.ErrSkipped {
+			fmt.Fprintf(console, "Пропущен %s: %v\n", result.FileName, result.Err)
+			continue
+		}
+		if result.Line > 0 {
+			fmt.Fprintf(console, "Ошибка обработки файла %s (строка %d, смещение %d): %v\n", result.FileName, result.Line, result.Offset, result.Err)
+		} else {
+			fmt.Fprintf(console, "Ошибка обработки файла %s: %v\n", result.FileName, result.Err)
+		}
+	}
+	// Ошибка чтения потока запросов: отправлены только прочитанные до нее
+	var readErr error
+	select {
+	case readErr = <-readErrs:
+		fmt.Fprintf(console, "Ошибка чтения запросов %s: %v\n", cfg.RequestsDir, readErr)
+		mainLogger.Error("Ошибка чтения запросов", map[string]interface{}{
+			"requests": cfg.RequestsDir,
+			"error":    readErr.Error(),
+		})
+	default:
+	}
+
+	// Ответы, которые ждали предыдущих по порядку, но те не отправлены из-за остановки
+	if responsesStream != nil {
+		if err := responsesStream.Close(); err != nil {
+			mainLogger.Error("Ошибка вывода ответов в stdout", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+	summary := agg.Summary()
+	summary.TargetRate = cfg.RPS
+	statistic(console, summary, mainLogger)
+
+	// Архив ответов появляется после записи последнего ответа
+	if responsesArchive != nil {
+		if err := responsesArchive.Close(); err != nil {
+			mainLogger.Error("Ошибка записи архива ответов", map[string]interface{}{
+				"archive": cfg.ResponsesDir,
+				"error":   err.Error(),
+			})
+			fmt.Fprintf(console, "Ошибка записи архива ответов: %v\n", err)
 		} else {
-			successCount++
+			fmt.Fprintf(console, "Ответы: %s\n", cfg.ResponsesDir)
 		}
 	}
-	fmt.Printf("\nОбработка завершена! Успешно: %d, Ошибок: %d\n", successCount, errorCount)
+
+	// Итоги сценариев в порядке загрузки
+	var scenarioRows []report.Scenario
+	for outcome := range outcomes {
+		scenarioRows = append(scenarioRows, outcome)
+	}
+	if len(scenarios) > 0 {
+		order := make(map[string]int, len(scenarios))
+		for i, sc := range scenarios {
+			order[sc.Name] = i
+		}
+		sort.Slice(scenarioRows, func(i, j int) bool { return order[scenarioRows[i].Name] < order[scenarioRows[j].Name] })
+		scenarioStatistic(console, scenarioRows, len(scenarios), mainLogger)
+	}
+
+	// Статистика по этапам нагрузки
+	var stageRows []stats.Row
+	for i, stageAgg := range stageAggs {
+		stageRows = append(stageRows, stats.Row{Name: plan.StageName(i), Summary: stageAgg.Summary()})
+	}
+	if len(stageRows) > 0 {
+		stageStatistic(console, stageRows, mainLogger)
+	}
+
+	// Отчеты о прогоне
+	if len(cfg.Reports) > 0 {
+		sort.SliceStable(records, func(i, j int) bool { return records[i].Start.Before(records[j].Start) })
+		runReport := &report.Report{
+			Started:   started,
+			Finished:  time.Now(),
+			URL:       cfg.URL,
+			Summary:   summary,
+			Stages:    stageRows,
+			Scenarios: scenarioRows,
+			Records:   records,
+		}
+		for _, path := range cfg.Reports {
+			if err := report.Write(path, runReport); err != nil {
+				mainLogger.Error("Ошибка записи отчета", map[string]interface{}{
+					"report": path,
+					"error":  err.Error(),
+				})
+				fmt.Fprintf(console, "Ошибка записи отчета %s: %v\n", path, err)
+				continue
+			}
+			mainLogger.Info("Отчет записан", map[string]interface{}{
+				"report": path,
+			})
+			fmt.Fprintf(console, "Отчет: %s\n", path)
+		}
+	}
+
+	// Сравнение с эталоном
+	exitCode := exitOK
+	if readErr != nil {
+		exitCode = exitError
+	}
+	if comparer != nil {
+		diffs.Sort()
+		fmt.Fprintln(console)
+		if err := diffs.WriteText(console); err != nil {
+			mainLogger.Error("Ошибка вывода сравнения", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		if cfg.DiffReport != "" {
+			if err := diffs.Write(cfg.DiffReport); err != nil {
+				mainLogger.Error("Ошибка записи отчета сравнения", map[string]interface{}{
+					"report": cfg.DiffReport,
+					"error":  err.Error(),
+				})
+				fmt.Fprintf(console, "Ошибка записи отчета сравнения %s: %v\n", cfg.DiffReport, err)
+			}
+		}
+		mainLogger.Info("Сравнение с эталоном", map[string]interface{}{
+			"baseline": cfg.Baseline,
+			"same":     diffs.Same,
+			"changed":  diffs.Changed,
+			"new":      diffs.New,
+			"missing":  diffs.Missing,
+		})
+		if diffs.HasChanges() {
+			exitCode = exitChanged
+		}
+	}
+	for _, outcome := range scenarioRows {
+		if !outcome.Passed {
+			exitCode = exitFailed
+		}
+	}
+
+	// Файлы, до которых не дошла очередь из-за остановки (в нагрузочном прогоне файлы повторяются)
+	var skipped []string
+	for _, job := range jobs {
+		if !cfg.LoadMode() && !attempted[job.Name] {
+			skipped = append(skipped, job.Name)
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(console, "Не отправлено из-за остановки: %d\n", len(skipped))
+		for _, name := range skipped {
+			fmt.Fprintf(console, "  %s\n", name)
+		}
+		mainLogger.Warn("Файлы не отправлены из-за остановки", map[string]interface{}{
+			"count": len(skipped),
+			"files": skipped,
+		})
+	}
+	return exitCode
 }
 
-// work обрабатывает файлы из канала
-func work(id int, client *http.Client, url, responsesDir string,
-	filesChan <-chan string, resultsChan chan<- Result, wg *sync.WaitGroup,
+// resumeJobs отбирает задачи для продолжения прогона по журналу:
+// успешно обработанные файлы с тем же хэшем пропускаются, упавшие и измененные отправляются снова
+func resumeJobs(jobs []source.Job, completed map[string]journal.Entry, log *logger.Logger) []source.Job {
+	pending := make([]source.Job, 0, len(jobs))
+	changed, failed := 0, 0
+	for _, job := range jobs {
+		entry, ok := completed[job.Name]
+		if !ok {
+			pending = append(pending, job)
+			continue
+		}
+		data, err := job.Read()
+		if err != nil {
+			pending = append(pending, job) // Ошибка чтения будет обработана воркером
+			continue
+		}
+		hash := journal.Hash(data)
+		switch {
+		case entry.Done(hash):
+			continue
+		case entry.Status == journal.StatusOK:
+			changed++
+			log.Info("Содержимое файла изменилось, файл будет отправлен снова", map[string]interface{}{
+				"file": job.Name,
+			})
+		default:
+			failed++
+		}
+		pending = append(pending, job)
+	}
+
+	log.Info("Продолжение прогона по журналу", map[string]interface{}{
+		"total":   len(jobs),
+		"skipped": len(jobs) - len(pending),
+		"changed": changed,
+		"failed":  failed,
+		"pending": len(pending),
+	})
+	return pending
+}
+
+// work обрабатывает файлы из канала.
+// После отмены ctx оставшиеся файлы пропускаются, reqCtx прерывает отправленные запросы.
+func work(ctx, reqCtx context.Context, id int, p *pipeline, gate *load.Gate, filesChan <-chan Task, resultsChan chan<- Result, wg *sync.WaitGroup,
 	log *logger.Logger) {
 	defer wg.Done()
 
@@ -172,158 +1178,660 @@ func work(id int, client *http.Client, url, responsesDir string,
 	workerLogger.Debug("Воркер запущен")
 
 	done := 0
-	for filePath := range filesChan {
+	for {
+		// При наращивании воркеров по этапам ждем своей очереди; после остановки ждать нечего
+		_ = gate.Wait(ctx, id)
+		job, ok := <-filesChan
+		if !ok {
+			break
+		}
+		if ctx.Err() != nil {
+			workerLogger.Debug("Файл пропущен из-за остановки", map[string]interface{}{
+				"file": job.Name,
+			})
+			continue
+		}
 		done++
-		fileName := filepath.Base(filePath)
-
-		startTime := time.Now()
-		workerLogger.Debug("Начало обработки файла", map[string]interface{}{
-			"file":  fileName,
-			"done":  done,
-			"start": startTime.Format(time.RFC3339),
-		})
+		result, sent := p.process(ctx, reqCtx, job.Job, p.renderer, workerLogger)
+		if !sent {
+			continue
+		}
+		result.Index = job.Index
+		result.Iteration = job.Iteration
+		result.Stage = job.Stage
+		result.Intended = job.Intended
+		resultsChan <- result
 
-		// Чтение JSON файла
-		jsonData, err := os.ReadFile(filePath)
-		if err != nil {
-			workerLogger.Error("Ошибка чтения файла", map[string]interface{}{
-				"file":  fileName,
-				"error": err.Error(),
+		// Зависимые от ошибочного запроса не отправляются, о них сообщается как о пропущенных
+		for _, skip := range p.scheduler.Done(result.FileName, result.Err == nil) {
+			workerLogger.Warn("Запрос пропущен: зависимость не выполнена", map[string]interface{}{
+				"file":       skip.Job.Name,
+				"dependency": skip.Dependency,
 			})
 			resultsChan <- Result{
-				FileName: fileName,
-				Duration: time.Since(startTime),
-				Err:      fmt.Errorf("чтение файла: %v", err),
+				FileName:  skip.Job.Name,
+				Index:     skip.Job.Index,
+				Start:     time.Now(),
+				Line:      skip.Job.Line,
+				Offset:    skip.Job.Offset,
+				Iteration: job.Iteration,
+				Err:       fmt.Errorf("зависимость %s не выполнена", skip.Dependency),
+				ErrType:   report.ErrSkipped,
 			}
+		}
+	}
+
+	workerLogger.Debug("Воркер завершен", map[string]interface{}{
+		"done": done,
+	})
+}
+
+// scenarioWork выполняет сценарии из канала: сценарии разных воркеров идут параллельно, шаги сценария - по порядку.
+// Результаты шагов попадают в общую статистику и отчеты, итог сценария - в outcomes.
+func scenarioWork(ctx, reqCtx context.Context, id int, p *pipeline, scenarios <-chan *scenario.Scenario, resultsChan chan<- Result,
+	outcomes chan<- report.Scenario, wg *sync.WaitGroup, log *logger.Logger) {
+	defer wg.Done()
+
+	workerLogger := log.WithFields(map[string]interface{}{
+		"worker_id": id,
+	})
+
+	for sc := range scenarios {
+		if ctx.Err() != nil {
+			workerLogger.Debug("Сценарий пропущен из-за остановки", map[string]interface{}{
+				"scenario": sc.Name,
+			})
 			continue
 		}
+		outcomes <- runScenario(ctx, reqCtx, p, sc, resultsChan, workerLogger.WithFields(map[string]interface{}{
+			"scenario": sc.Name,
+		}))
+	}
+}
+
+// runScenario выполняет шаги сценария по порядку. Значения, извлеченные из ответа шага, подставляются в следующие шаги.
+// После первой ошибки сценарий прерывается: следующие шаги зависят от предыдущих.
+func runScenario(ctx, reqCtx context.Context, p *pipeline, sc *scenario.Scenario, resultsChan chan<- Result, log *logger.Logger) report.Scenario {
+	startTime := time.Now()
+	outcome := report.Scenario{Name: sc.Name, Total: len(sc.Steps)}
+	vars := make(map[string]string, len(sc.Vars))
+	for name, value := range sc.Vars {
+		vars[name] = value
+	}
+
+	for i, step := range sc.Steps {
+		if ctx.Err() != nil {
+			outcome.FailedStep = step.Name
+			outcome.Error = "сценарий прерван остановкой"
+			break
+		}
+		job := source.Job{Name: sc.ResponseName(i), Path: step.Request}
+		result, sent := p.process(ctx, reqCtx, job, p.renderer.With(vars), log)
+		if !sent {
+			outcome.FailedStep = step.Name
+			outcome.Error = "сценарий прерван остановкой"
+			break
+		}
 
-		// Получаем размер файла
-		fileInfo, _ := os.Stat(filePath)
-		fileSize := int64(0)
-		if fileInfo != nil {
-			fileSize = fileInfo.Size()
+		if result.Err == nil && len(step.Rules) > 0 {
+			extracted, err := step.Extract(result.Body, result.Header)
+			if err != nil {
+				log.Error("Ошибка извлечения значений из ответа", map[string]interface{}{
+					"file":  result.FileName,
+					"error": err.Error(),
+				})
+				result.Err = fmt.Errorf("извлечение значений: %v", err)
+				result.ErrType = report.ErrExtract
+			} else {
+				for name, value := range extracted {
+					vars[name] = value
+				}
+				log.Debug("Значения извлечены из ответа", map[string]interface{}{
+					"file": result.FileName,
+					"vars": extracted,
+				})
+			}
 		}
+		resultsChan <- result
+		if result.Err != nil {
+			outcome.FailedStep = step.Name
+			outcome.Error = result.Err.Error()
+			break
+		}
+		outcome.Steps++
+	}
+
+	outcome.Passed = outcome.Steps == outcome.Total
+	outcome.Duration = time.Since(startTime)
+	if outcome.Passed {
+		log.Info("Сценарий пройден", map[string]interface{}{
+			"steps":    outcome.Steps,
+			"duration": outcome.Duration.String(),
+		})
+	} else {
+		log.Warn("Сценарий не пройден", map[string]interface{}{
+			"step":     outcome.FailedStep,
+			"steps":    outcome.Steps,
+			"total":    outcome.Total,
+			"error":    outcome.Error,
+			"duration": outcome.Duration.String(),
+		})
+	}
+	return outcome
+}
+
+// pipeline = общие для воркеров настройки обработки запроса
+type pipeline struct {
+	client       *http.Client
+	url          string
+	headers      http.Header // Общие заголовки и авторизация конфигурации
+	responsesDir string
+	policy       retry.Policy
+	limits       *ratelimit.Set
+	expectations *expect.Loader
+	schemas      schemas
+	renderer     *render.Renderer // Подстановка шаблонов (nil - запросы отправляются как есть)
+	comparer     *compare.Comparer
+	scheduler    *deps.Scheduler // Порядок зависимостей depends_on (nil - без зависимостей)
+
+	responsesArchive *archive.Writer // Архив ответов (nil - ответы пишутся в responsesDir)
+	responsesStream  bool            // Ответы выводятся в stdout из цикла результатов и не сохраняются
+	streamed         bool            // Запросы читаются потоком: список для порядка depends_on неизвестен заранее
+}
 
-		// Проверка валидности JSON
-		if !json.Valid(jsonData) {
-			workerLogger.Error("Невалидный JSON", map[string]interface{}{
-				"file":      fileName,
-				"file_size": fileSize,
+// process обрабатывает один запрос: чтение, подстановка, проверки, отправка с повторами и сохранение ответа.
+// renderer подставляет переменные (у шагов сценария - свои). sent = false - запрос не отправлен из-за остановки.
+func (p *pipeline) process(ctx, reqCtx context.Context, job source.Job, renderer *render.Renderer, log *logger.Logger) (result Result, sent bool) {
+	fileName := job.Name
+	var rendered []byte
+	defer func() {
+		if rendered != nil {
+			result.Rendered = report.Preview(rendered)
+		}
+	}()
+
+	startTime := time.Now()
+	log.Debug("Начало обработки файла", map[string]interface{}{
+		"file":  fileName,
+		"line":  job.Line,
+		"start": startTime.Format(time.RFC3339),
+	})
+
+	// Чтение JSON файла или строки JSONL
+	jsonData, err := job.Read()
+	if err != nil {
+		log.Error("Ошибка чтения файла", map[string]interface{}{
+			"file":  fileName,
+			"error": err.Error(),
+		})
+		return Result{
+			FileName: fileName,
+			Start:    startTime,
+			Line:     job.Line,
+			Offset:   job.Offset,
+			Duration: time.Since(startTime),
+			Err:      fmt.Errorf("чтение файла: %v", err),
+			ErrType:  report.ErrRead,
+		}, true
+	}
+
+	// Получаем размер файла и хэш содержимого (до подстановки: журнал следит за изменением файла)
+	fileSize := job.Size()
+	hash := journal.Hash(jsonData)
+
+	// Подстановка переменных: генераторы дают новые значения при каждой отправке
+	if renderer != nil && render.IsTemplate(jsonData) {
+		rendered, err = renderer.Render(jsonData)
+		if err != nil {
+			log.Error("Ошибка подстановки в шаблон запроса", map[string]interface{}{
+				"file":  fileName,
+				"line":  job.Line,
+				"error": err.Error(),
 			})
-			resultsChan <- Result{
+			return Result{
 				FileName:    fileName,
+				Start:       startTime,
+				Line:        job.Line,
+				Offset:      job.Offset,
 				FileSize:    fileSize,
+				Hash:        hash,
 				RequestSize: len(jsonData),
 				Duration:    time.Since(startTime),
-				Err:         fmt.Errorf("невалидный JSON"),
-			}
-			continue
+				Err:         fmt.Errorf("шаблон: %v", err),
+				ErrType:     report.ErrTemplate,
+			}, true
 		}
+		log.Debug("Запрос после подстановки", map[string]interface{}{
+			"file":    fileName,
+			"line":    job.Line,
+			"request": string(rendered),
+		})
+		jsonData = rendered
+	}
 
-		workerLogger.Debug("JSON файл прочитан", map[string]interface{}{
+	// Проверка валидности JSON
+	if !json.Valid(jsonData) {
+		log.Error("Невалидный JSON", map[string]interface{}{
 			"file":      fileName,
+			"line":      job.Line,
+			"offset":    job.Offset,
 			"file_size": fileSize,
-			"json_size": len(jsonData),
 		})
+		return Result{
+			FileName:    fileName,
+			Start:       startTime,
+			Line:        job.Line,
+			Offset:      job.Offset,
+			FileSize:    fileSize,
+			Hash:        hash,
+			RequestSize: len(jsonData),
+			Duration:    time.Since(startTime),
+			Err:         fmt.Errorf("невалидный JSON"),
+			ErrType:     report.ErrJSON,
+		}, true
+	}
 
-		// Отправка запроса на сервер
-		response, statusCode, err := sendRequest(client, url, jsonData, workerLogger)
-		requestDuration := time.Since(startTime)
-		if err != nil {
-			workerLogger.Error("Ошибка отправки запроса", map[string]interface{}{
-				"file":      fileName,
-				"duration":  requestDuration.String(),
-				"error":     err.Error(),
-				"file_size": fileSize,
+	log.Debug("JSON файл прочитан", map[string]interface{}{
+		"file":      fileName,
+		"file_size": fileSize,
+		"json_size": len(jsonData),
+	})
+
+	// Разбор конверта запроса (метод, адрес, заголовки, параметры)
+	env, err := envelope.Parse(jsonData)
+	if err != nil {
+		log.Error("Некорректный конверт запроса", map[string]interface{}{
+			"file":  fileName,
+			"line":  job.Line,
+			"error": err.Error(),
+		})
+		return Result{
+			FileName:    fileName,
+			Start:       startTime,
+			Line:        job.Line,
+			Offset:      job.Offset,
+			FileSize:    fileSize,
+			Hash:        hash,
+			RequestSize: len(jsonData),
+			Duration:    time.Since(startTime),
+			Err:         fmt.Errorf("конверт запроса: %v", err),
+			ErrType:     report.ErrEnvelope,
+		}, true
+	}
+	if p.streamed && len(env.DependsOn) > 0 {
+		log.Error("depends_on при чтении запросов потоком", map[string]interface{}{
+			"file": fileName,
+			"line": job.Line,
+		})
+		return Result{
+			FileName:    fileName,
+			Start:       startTime,
+			Line:        job.Line,
+			Offset:      job.Offset,
+			FileSize:    fileSize,
+			Hash:        hash,
+			RequestSize: len(jsonData),
+			Duration:    time.Since(startTime),
+			Err:         fmt.Errorf("конверт запроса: depends_on не поддерживается при чтении запросов потоком (архив, stdin)"),
+			ErrType:     report.ErrEnvelope,
+		}, true
+	}
+	// Адрес запроса: с неразрешимым адресом запрос не отправляется
+	target, err := env.ResolveURL(p.url)
+	if err != nil {
+		log.Error("Некорректный адрес запроса", map[string]interface{}{
+			"file":  fileName,
+			"line":  job.Line,
+			"error": err.Error(),
+		})
+		return Result{
+			FileName:    fileName,
+			Start:       startTime,
+			Line:        job.Line,
+			Offset:      job.Offset,
+			FileSize:    fileSize,
+			Hash:        hash,
+			RequestSize: len(jsonData),
+			Method:      env.Method,
+			Duration:    time.Since(startTime),
+			Err:         fmt.Errorf("конверт запроса: %v", err),
+			ErrType:     report.ErrEnvelope,
+		}, true
+	}
+
+	// Ожидания к ответу: из конверта или из файла name.expect.json
+	exp, err := job.Expectation(p.expectations, env.Expect)
+	if err != nil {
+		log.Error("Некорректные ожидания к ответу", map[string]interface{}{
+			"file":  fileName,
+			"error": err.Error(),
+		})
+		return Result{
+			FileName:    fileName,
+			Start:       startTime,
+			Line:        job.Line,
+			Offset:      job.Offset,
+			FileSize:    fileSize,
+			Hash:        hash,
+			RequestSize: len(jsonData),
+			Method:      env.Method,
+			URL:         target,
+			Duration:    time.Since(startTime),
+			Err:         fmt.Errorf("ожидания: %v", err),
+			ErrType:     report.ErrEnvelope,
+		}, true
+	}
+
+	// Проверка тела запроса по схеме: запрос с нарушениями не отправляется
+	if p.schemas.request != nil && len(env.Body) > 0 {
+		if err := p.schemas.request.Validate(env.Body); err != nil {
+			log.Error("Запрос не соответствует схеме", map[string]interface{}{
+				"file":   fileName,
+				"line":   job.Line,
+				"schema": p.schemas.request.Path,
+				"error":  err.Error(),
 			})
-			resultsChan <- Result{
+			return Result{
 				FileName:    fileName,
+				Start:       startTime,
+				Line:        job.Line,
+				Offset:      job.Offset,
 				FileSize:    fileSize,
-				RequestSize: len(jsonData),
-				Duration:    requestDuration,
-				StatusCode:  statusCode,
-				Err:         fmt.Errorf("отправка запроса: %v", err),
-			}
-			continue
+				Hash:        hash,
+				RequestSize: len(env.Body),
+				Method:      env.Method,
+				URL:         target,
+				Duration:    time.Since(startTime),
+				Err:         fmt.Errorf("схема запроса: %v", err),
+				ErrType:     report.ErrSchema,
+			}, true
 		}
+	}
 
-		workerLogger.Info("Запрос успешно отправлен", map[string]interface{}{
+	// Отправка запроса на сервер с повторами. Задержка - время последней попытки: ожидание ограничителя
+	// скорости и паузы перед повторами в нее не входят и записываются отдельно
+	delivered, err := sendWithRetry(ctx, reqCtx, p.client, env, p.url, p.headers, p.policy, p.limits, exp, log)
+	response, statusCode, header, attempts := delivered.body, delivered.status, delivered.header, delivered.attempts
+	requestDuration := delivered.latency
+	if errors.Is(err, errNotSent) {
+		log.Debug("Файл пропущен из-за остановки", map[string]interface{}{
+			"file": fileName,
+		})
+		return Result{}, false
+	}
+	if err != nil {
+		log.Error("Ошибка отправки запроса", map[string]interface{}{
+			"file":      fileName,
+			"duration":  requestDuration.String(),
+			"error":     err.Error(),
+			"line":      job.Line,
+			"attempts":  attempts,
+			"file_size": fileSize,
+		})
+		return Result{
+			FileName:    fileName,
+			Start:       delivered.start,
+			Line:        job.Line,
+			Offset:      job.Offset,
+			FileSize:    fileSize,
+			Hash:        hash,
+			RequestSize: len(env.Body),
+			Method:      env.Method,
+			URL:         target,
+			Duration:    requestDuration,
+			StatusCode:  statusCode,
+			Attempts:    attempts,
+			Backoff:     delivered.backoff,
+			Throttle:    delivered.throttle,
+			BodyPreview: report.Preview(response),
+			Body:        response,
+			Err:         fmt.Errorf("отправка запроса: %v", err),
+			ErrType:     sendErrType(statusCode),
+		}, true
+	}
+
+	log.Info("Запрос успешно отправлен", map[string]interface{}{
+		"file":        fileName,
+		"method":      env.Method,
+		"url":         target,
+		"duration":    requestDuration.String(),
+		"status_code": statusCode,
+		"attempts":    attempts,
+		"file_size":   fileSize,
+		"resp_size":   len(response),
+	})
+
+	// Проверка ожиданий: ответ сохраняется и при непройденных проверках
+	assertErr := exp.Verify(expect.Response{
+		StatusCode: statusCode,
+		Header:     header,
+		Body:       response,
+		Duration:   requestDuration,
+	})
+	if assertErr != nil {
+		log.Warn("Ответ не прошел проверки", map[string]interface{}{
 			"file":        fileName,
-			"duration":    requestDuration.String(),
 			"status_code": statusCode,
-			"file_size":   fileSize,
-			"resp_size":   len(response),
+			"error":       assertErr.Error(),
 		})
+	}
 
-		// Сохранение ответа
-		err = saveResponse(fileName, response, responsesDir, workerLogger)
-		totalDuration := time.Since(startTime)
-
-		// Сохранение ответа
-		if err != nil {
-			workerLogger.Error("Ошибка сохранения ответа", map[string]interface{}{
-				"file":      fileName,
-				"duration":  totalDuration.String(),
-				"error":     err.Error(),
-				"resp_size": len(response),
+	// Проверка тела ответа по схеме: ответ сохраняется и при нарушениях
+	var schemaErr error
+	if p.schemas.response != nil {
+		if err := p.schemas.response.Validate(response); err != nil {
+			log.Warn("Ответ не соответствует схеме", map[string]interface{}{
+				"file":        fileName,
+				"status_code": statusCode,
+				"schema":      p.schemas.response.Path,
+				"error":       err.Error(),
 			})
-			resultsChan <- Result{
-				FileName:     fileName,
-				FileSize:     fileSize,
-				RequestSize:  len(jsonData),
-				ResponseSize: len(response),
-				Duration:     totalDuration,
-				StatusCode:   statusCode,
-				Err:          fmt.Errorf("сохранение ответа: %v", err),
-			}
-			continue
+			schemaErr = fmt.Errorf("схема ответа: %v", err)
 		}
+	}
 
-		workerLogger.Info("Ответ успешно сохранен", map[string]interface{}{
-			"file":         fileName,
-			"total_time":   totalDuration.String(),
-			"request_time": requestDuration.String(),
-			"save_time":    (totalDuration - requestDuration).String(),
-			"status_code":  statusCode,
-			"file_size":    fileSize,
-			"req_size":     len(jsonData),
-			"resp_size":    len(response),
-		})
+	// Сохранение ответа
+	if !p.responsesStream {
+		err = saveResponse(fileName, response, p.responsesDir, p.responsesArchive, log)
+	}
+	totalDuration := time.Since(startTime)
 
-		resultsChan <- Result{
+	// Сохранение ответа
+	if err != nil {
+		log.Error("Ошибка сохранения ответа", map[string]interface{}{
+			"file":      fileName,
+			"duration":  totalDuration.String(),
+			"error":     err.Error(),
+			"resp_size": len(response),
+		})
+		return Result{
 			FileName:     fileName,
+			Start:        delivered.start,
+			Line:         job.Line,
+			Offset:       job.Offset,
 			FileSize:     fileSize,
-			RequestSize:  len(jsonData),
+			Hash:         hash,
+			RequestSize:  len(env.Body),
 			ResponseSize: len(response),
-			Duration:     totalDuration,
+			Method:       env.Method,
+			URL:          target,
+			Duration:     requestDuration,
 			StatusCode:   statusCode,
-			Err:          nil,
-		}
+			Attempts:     attempts,
+			Backoff:      delivered.backoff,
+			Throttle:     delivered.throttle,
+			Err:          fmt.Errorf("сохранение ответа: %v", err),
+			ErrType:      report.ErrSave,
+		}, true
 	}
 
-	workerLogger.Debug("Воркер завершен", map[string]interface{}{
-		"done": done,
+	log.Info("Ответ успешно сохранен", map[string]interface{}{
+		"file":         fileName,
+		"total_time":   totalDuration.String(),
+		"request_time": requestDuration.String(),
+		"backoff":      delivered.backoff.String(),
+		"throttle":     delivered.throttle.String(),
+		"status_code":  statusCode,
+		"file_size":    fileSize,
+		"req_size":     len(env.Body),
+		"resp_size":    len(response),
 	})
+
+	result = Result{
+		FileName:     fileName,
+		Start:        delivered.start,
+		Line:         job.Line,
+		Offset:       job.Offset,
+		FileSize:     fileSize,
+		Hash:         hash,
+		RequestSize:  len(env.Body),
+		ResponseSize: len(response),
+		Method:       env.Method,
+		URL:          target,
+		Duration:     requestDuration,
+		StatusCode:   statusCode,
+		Attempts:     attempts,
+		Backoff:      delivered.backoff,
+		Throttle:     delivered.throttle,
+		Body:         response,
+		Header:       header,
+		Err:          nil,
+	}
+	if assertErr != nil {
+		result.Err = assertErr
+		result.ErrType = report.ErrAssert
+		result.BodyPreview = report.Preview(response)
+	} else if schemaErr != nil {
+		result.Err = schemaErr
+		result.ErrType = report.ErrSchema
+		result.BodyPreview = report.Preview(response)
+	}
+
+	// Сравнение ответа с эталоном
+	if p.comparer != nil {
+		diff, err := p.comparer.File(fileName, response)
+		if err != nil {
+			log.Error("Ошибка сравнения с эталоном", map[string]interface{}{
+				"file":  fileName,
+				"error": err.Error(),
+			})
+		} else {
+			result.Diff = &diff
+			if diff.Changed() {
+				log.Warn("Ответ отличается от эталона", map[string]interface{}{
+					"file":    fileName,
+					"status":  diff.Status,
+					"changes": len(diff.Changes),
+				})
+			}
+		}
+	}
+	return result, true
 }
 
-// sendRequest отправляет JSON на сервер (без изменений)
-func sendRequest(client *http.Client, url string, jsonData []byte, log *logger.Logger) ([]byte, int, error) {
-	// Создание POST запроса
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonData))
+// delivery = итог отправки запроса с повторами: ответ последней попытки и время, разделенное на задержку
+// ответа и ожидание, чтобы ограничение скорости и повторы не попадали в задержку сервера
+type delivery struct {
+	body     []byte
+	status   int
+	header   http.Header
+	attempts int           // Количество попыток
+	start    time.Time     // Начало последней попытки
+	latency  time.Duration // Время последней попытки: от отправки до прочтения ответа
+	backoff  time.Duration // Паузы перед повторами
+	throttle time.Duration // Ожидание ограничителя скорости перед попытками
+}
+
+// sendWithRetry отправляет запрос, повторяя его по политике повторов. headers - общие заголовки
+// конфигурации, они добавляются в каждую попытку. Каждая попытка ждет разрешения ограничителя скорости.
+// Статус, явно указанный в ожиданиях, считается ответом, а не ошибкой, и не повторяется.
+// После отмены ctx новые попытки не делаются, а не отправленный запрос возвращает errNotSent.
+func sendWithRetry(ctx, reqCtx context.Context, client *http.Client, env *envelope.Envelope, baseURL string, headers http.Header, policy retry.Policy,
+	limits *ratelimit.Set, exp *expect.Expectation, log *logger.Logger) (delivery, error) {
+	host := targetHost(env, baseURL)
+
+	var (
+		d   delivery
+		err error
+	)
+	for attempt := 1; ; attempt++ {
+		waitStart := time.Now()
+		if waitErr := limits.Wait(ctx, host); waitErr != nil {
+			d.throttle += time.Since(waitStart)
+			if attempt == 1 {
+				return d, errNotSent
+			}
+			return d, err
+		}
+		d.throttle += time.Since(waitStart)
+
+		log.Debug("Попытка отправки запроса", map[string]interface{}{
+			"attempt":      attempt,
+			"max_attempts": policy.MaxAttempts,
+		})
+
+		d.attempts = attempt
+		d.start = time.Now()
+		d.body, d.status, d.header, err = sendRequest(reqCtx, client, env, baseURL, headers, log)
+		d.latency = time.Since(d.start)
+		if err == nil || exp.Accepts(d.status) {
+			return d, nil
+		}
+		if attempt >= policy.MaxAttempts || !policy.Retryable(d.status, err) {
+			return d, err
+		}
+
+		delay := policy.Delay(attempt, d.header)
+		log.Warn("Повтор запроса", map[string]interface{}{
+			"attempt":      attempt,
+			"max_attempts": policy.MaxAttempts,
+			"status_code":  d.status,
+			"error":        err.Error(),
+			"retry_after":  d.header.Get("Retry-After"),
+			"delay":        delay.String(),
+		})
+
+		backoffStart := time.Now()
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			d.backoff += time.Since(backoffStart)
+			return d, err
+		case <-timer.C:
+		}
+		d.backoff += time.Since(backoffStart)
+	}
+}
+
+// targetHost возвращает хост (без порта) адреса запроса для ограничителя скорости
+func targetHost(env *envelope.Envelope, baseURL string) string {
+	target, err := env.ResolveURL(baseURL)
+	if err != nil {
+		return ""
+	}
+	u, err := url.Parse(target)
 	if err != nil {
-		return nil, 0, err
+		return ""
 	}
+	return u.Hostname()
+}
 
-	// Установка заголовков
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+// sendRequest отправляет запрос по конверту, относительные адреса разрешаются от baseURL
+func sendRequest(ctx context.Context, client *http.Client, env *envelope.Envelope, baseURL string, headers http.Header, log *logger.Logger) ([]byte, int, http.Header, error) {
+	// Создание запроса: метод, адрес, заголовки и тело из конверта поверх общих заголовков
+	req, err := env.NewRequest(ctx, baseURL, headers)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	url := req.URL.String()
 
 	log.Debug("Отправка HTTP запроса", map[string]interface{}{
 		"url":          url,
 		"method":       req.Method,
 		"content_type": req.Header.Get("Content-Type"),
-		"data_size":    len(jsonData),
+		"data_size":    len(env.Body),
+		"envelope":     !env.Plain,
 		"timestamp":    time.Now().Format(time.RFC3339Nano),
 	})
 
@@ -338,7 +1846,7 @@ func sendRequest(client *http.Client, url string, jsonData []byte, log *logger.L
 			"error":       err.Error(),
 			"url":         url,
 		})
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 	defer resp.Body.Close()
 
@@ -352,7 +1860,7 @@ func sendRequest(client *http.Client, url string, jsonData []byte, log *logger.L
 			"url":          url,
 			"content_type": resp.Header.Get("Content-Type"),
 		})
-		return nil, resp.StatusCode, err
+		return nil, resp.StatusCode, resp.Header, err
 	}
 
 	// Логируем получение ответа
@@ -380,14 +1888,14 @@ func sendRequest(client *http.Client, url string, jsonData []byte, log *logger.L
 			"status_code":  resp.StatusCode,
 			"body_preview": string(body[:min(200, len(body))]),
 		})
-		return body, resp.StatusCode, fmt.Errorf("сервер вернул статус: %d", resp.StatusCode)
+		return body, resp.StatusCode, resp.Header, fmt.Errorf("сервер вернул статус: %d", resp.StatusCode)
 	}
 
-	return body, resp.StatusCode, nil
+	return body, resp.StatusCode, resp.Header, nil
 }
 
-// saveResponse сохраняет ответ директорию
-func saveResponse(fileName string, response []byte, path string, log *logger.Logger) error {
+// saveResponse сохраняет ответ в директорию или, если задан out, в архив ответов
+func saveResponse(fileName string, response []byte, path string, out *archive.Writer, log *logger.Logger) error {
 	startTime := time.Now()
 
 	log.Debug("Начало сохранения ответа", map[string]interface{}{
@@ -410,6 +1918,18 @@ func saveResponse(fileName string, response []byte, path string, log *logger.Log
 	}
 	formatDuration := time.Since(formatStart)
 
+	if out != nil {
+		if err := out.Add(fileName, formattedJSON.Bytes()); err != nil {
+			log.Error("Ошибка записи ответа в архив", map[string]interface{}{
+				"file_name": fileName,
+				"archive":   path,
+				"error":     err.Error(),
+			})
+			return fmt.Errorf("запись %s в архив %s: %v", fileName, path, err)
+		}
+		return nil
+	}
+
 	// Определяем полный путь к файлу
 	filePath := filepath.Join(path, fileName)
 
@@ -422,9 +1942,14 @@ func saveResponse(fileName string, response []byte, path string, log *logger.Log
 		"compression_ratio": fmt.Sprintf("%.2f%%", float64(formattedJSON.Len())*100/float64(len(response))),
 	})
 
-	// Записываем файл
+	// Ответы шагов сценария лежат в поддиректории сценария
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("создание директории %s: %v", filepath.Dir(filePath), err)
+	}
+
+	// Записываем файл атомарно: при остановке не остается наполовину записанных ответов
 	writeStart := time.Now()
-	if err := os.WriteFile(filePath, formattedJSON.Bytes(), 0644); err != nil {
+	if err := writeFileAtomic(filePath, formattedJSON.Bytes(), 0644); err != nil {
 		log.Error("Ошибка записи файла", map[string]interface{}{
 			"file_path":     filePath,
 			"file_size":     formattedJSON.Len(),
@@ -439,94 +1964,88 @@ func saveResponse(fileName string, response []byte, path string, log *logger.Log
 	return nil
 }
 
-func statistic(resultsChan <-chan Result, log *logger.Logger) {
-	// Собираем результаты с расширенной статистикой
-	successCount, errorCount := 0, 0
-	var totalDuration time.Duration
-	var totalFileSize int64
-	var totalRequestSize int
-	var totalResponseSize int
-	statusCodeStats := make(map[int]int)
-	durationStats := struct {
-		min time.Duration
-		max time.Duration
-		sum time.Duration
-	}{min: time.Hour} // Инициализируем большим значением
+// writeFileAtomic записывает данные во временный файл и переименовывает его в path
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // После успешного переименования ничего не удаляет
 
-	for result := range resultsChan {
-		if result.Err != nil {
-			errorCount++
-			log.Error("Ошибка обработки файла", map[string]interface{}{
-				"file_name":     result.FileName,
-				"error":         result.Err.Error(),
-				"duration_ms":   result.Duration.Milliseconds(),
-				"status_code":   result.StatusCode,
-				"file_size":     result.FileSize,
-				"request_size":  result.RequestSize,
-				"response_size": result.ResponseSize,
-			})
-		} else {
-			successCount++
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
 
-			// Обновляем статистику
-			totalDuration += result.Duration
-			totalFileSize += result.FileSize
-			totalRequestSize += result.RequestSize
-			totalResponseSize += result.ResponseSize
+// statistic печатает итоговую статистику таблицей и записывает ее в лог
+func statistic(w io.Writer, summary stats.Summary, log *logger.Logger) {
+	fmt.Fprintf(w, "\nОбработка завершена! Успешно: %d, Ошибок: %d\n\n", summary.Successful, summary.Failed)
+	if err := summary.WriteTable(w); err != nil {
+		log.Error("Ошибка вывода статистики", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
 
-			// Статистика по статус кодам
-			if result.StatusCode > 0 {
-				statusCodeStats[result.StatusCode]++
-			}
+	log.Info("Статистика обработки файлов", summary.Fields())
+}
 
-			// Минимальное/максимальное время
-			if result.Duration < durationStats.min {
-				durationStats.min = result.Duration
-			}
-			if result.Duration > durationStats.max {
-				durationStats.max = result.Duration
-			}
-			durationStats.sum += result.Duration
-
-			log.Info("Файл успешно обработан", map[string]interface{}{
-				"file_name":     result.FileName,
-				"duration_ms":   result.Duration.Milliseconds(),
-				"status_code":   result.StatusCode,
-				"file_size":     result.FileSize,
-				"request_size":  result.RequestSize,
-				"response_size": result.ResponseSize,
-				"success":       true,
-			})
+// scenarioStatistic выводит итоги сценариев; total - все сценарии, включая не запущенные из-за остановки
+func scenarioStatistic(w io.Writer, outcomes []report.Scenario, total int, log *logger.Logger) {
+	passed := 0
+	fmt.Fprintln(w)
+	for _, outcome := range outcomes {
+		if outcome.Passed {
+			passed++
+			fmt.Fprintf(w, "PASS  %s (шагов: %d, %v)\n", outcome.Name, outcome.Total, outcome.Duration.Round(time.Millisecond))
+			continue
 		}
+		fmt.Fprintf(w, "FAIL  %s: шаг %s (%d из %d): %s\n", outcome.Name, outcome.FailedStep, outcome.Steps+1, outcome.Total, outcome.Error)
+	}
+	fmt.Fprintf(w, "Сценариев: %d, пройдено: %d, не пройдено: %d", total, passed, len(outcomes)-passed)
+	if skipped := total - len(outcomes); skipped > 0 {
+		fmt.Fprintf(w, ", не запущено из-за остановки: %d", skipped)
+	}
+	fmt.Fprintln(w)
+
+	log.Info("Итоги сценариев", map[string]interface{}{
+		"total":   total,
+		"passed":  passed,
+		"failed":  len(outcomes) - passed,
+		"skipped": total - len(outcomes),
+	})
+}
+
+// stageStatistic печатает статистику по этапам нагрузки и записывает ее в лог
+func stageStatistic(w io.Writer, rows []stats.Row, log *logger.Logger) {
+	fmt.Fprintln(w)
+	if err := stats.WriteRows(w, "Этап", rows); err != nil {
+		log.Error("Ошибка вывода статистики по этапам", map[string]interface{}{
+			"error": err.Error(),
+		})
 	}
 
-	// Выводим итоговую статистику
-	if successCount > 0 {
-		avgDuration := durationStats.sum / time.Duration(successCount)
-		log.Info("Статистика обработки файлов", map[string]interface{}{
-			"total_files":              successCount + errorCount,
-			"successful":               successCount,
-			"failed":                   errorCount,
-			"success_rate":             fmt.Sprintf("%.2f%%", float64(successCount)*100/float64(successCount+errorCount)),
-			"total_duration_sec":       totalDuration.Seconds(),
-			"avg_duration_ms":          avgDuration.Milliseconds(),
-			"min_duration_ms":          durationStats.min.Milliseconds(),
-			"max_duration_ms":          durationStats.max.Milliseconds(),
-			"total_file_size_mb":       fmt.Sprintf("%.2f MB", float64(totalFileSize)/(1024*1024)),
-			"total_request_size_mb":    fmt.Sprintf("%.2f MB", float64(totalRequestSize)/(1024*1024)),
-			"total_response_size_mb":   fmt.Sprintf("%.2f MB", float64(totalResponseSize)/(1024*1024)),
-			"avg_file_size_kb":         fmt.Sprintf("%.2f KB", float64(totalFileSize)/float64(successCount)/1024),
-			"avg_request_size_kb":      fmt.Sprintf("%.2f KB", float64(totalRequestSize)/float64(successCount)/1024),
-			"avg_response_size_kb":     fmt.Sprintf("%.2f KB", float64(totalResponseSize)/float64(successCount)/1024),
-			"throughput_files_per_sec": fmt.Sprintf("%.2f", float64(successCount)/totalDuration.Seconds()),
-			"status_codes":             statusCodeStats,
-		})
-	}
-
-	log.Info("Обработка завершена", map[string]interface{}{
-		"successful": successCount,
-		"failed":     errorCount,
-		"total":      successCount + errorCount,
-		"duration":   totalDuration.String(),
+	stages := make(map[string]interface{}, len(rows))
+	for _, row := range rows {
+		stages[row.Name] = row.Summary.Fields()
+	}
+	log.Info("Статистика по этапам нагрузки", map[string]interface{}{
+		"stages": stages,
 	})
 }
+
+// sendErrType определяет тип ошибки отправки: есть ответ сервера или нет
+func sendErrType(statusCode int) string {
+	if statusCode > 0 {
+		return report.ErrStatus
+	}
+	return report.ErrTransport
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"poster/internal/auth"
+	"poster/internal/envelope"
+	"poster/internal/expect"
+	"poster/internal/logger"
+	"poster/internal/ratelimit"
+	"poster/internal/render"
+	"poster/internal/report"
+	"poster/internal/retry"
+	"poster/internal/source"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testPolicy = политика повторов с короткими задержками
+func testPolicy(attempts int) retry.Policy {
+	return retry.Policy{
+		MaxAttempts: attempts,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+		StatusCodes: []int{429, 503},
+		Network:     true,
+	}
+}
+
+// statusServer отвечает статусами по очереди, последний статус повторяется; calls - количество запросов
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	calls := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+// testLogger возвращает логгер без вывода
+func testLogger(t *testing.T) *logger.Logger {
+	t.Helper()
+	log, err := logger.New("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return log
+}
+
+// TestSendWithRetry проверяет повторы по статусам ответа
+func TestSendWithRetry(t *testing.T) {
+	accept503, err := expect.Parse([]byte(`{"status": 503}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		statuses     []int
+		attempts     int
+		exp          *expect.Expectation
+		wantAttempts int
+		wantStatus   int
+		wantErr      bool
+	}{
+		{"успех с первой попытки", []int{200}, 3, nil, 1, 200, false},
+		{"повтор до успеха", []int{503, 429, 200}, 3, nil, 3, 200, false},
+		{"попытки закончились", []int{503}, 2, nil, 2, 503, true},
+		{"статус не повторяется", []int{400, 200}, 3, nil, 1, 400, true},
+		{"статус из ожиданий - ответ", []int{503, 200}, 3, accept503, 1, 503, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, calls := statusServer(t, test.statuses...)
+			env, _ := envelope.Parse([]byte(`{"a": 1}`))
+
+			d, err := sendWithRetry(context.Background(), context.Background(), server.Client(), env, server.URL, nil,
+				testPolicy(test.attempts), ratelimit.NewSet(0, 1, nil), test.exp, testLogger(t))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ошибка = %v, ожидалась ошибка: %v", err, test.wantErr)
+			}
+			if d.attempts != test.wantAttempts || int(calls.Load()) != test.wantAttempts {
+				t.Errorf("попыток = %d, запросов = %d, ожидалось %d", d.attempts, calls.Load(), test.wantAttempts)
+			}
+			if d.status != test.wantStatus || string(d.body) != `{"ok": true}` {
+				t.Errorf("статус = %d, тело = %s", d.status, d.body)
+			}
+		})
+	}
+}
+
+// TestSendWithRetry_Network проверяет повторы при сетевых ошибках
+func TestSendWithRetry_Network(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close() // Соединение будет отклонено
+
+	env, _ := envelope.Parse([]byte(`{}`))
+	for _, network := range []bool{true, false} {
+		policy := testPolicy(3)
+		policy.Network = network
+		d, err := sendWithRetry(context.Background(), context.Background(), http.DefaultClient, env, server.URL, nil,
+			policy, ratelimit.NewSet(0, 1, nil), nil, testLogger(t))
+		if err == nil {
+			t.Fatalf("network=%v: ожидалась ошибка соединения", network)
+		}
+		want := 1
+		if network {
+			want = 3
+		}
+		if d.attempts != want {
+			t.Errorf("network=%v: попыток = %d, ожидалось %d", network, d.attempts, want)
+		}
+	}
+}
+
+// TestSendWithRetry_Cancel проверяет, что остановка прерывает ожидание повтора и ограничителя скорости
+func TestSendWithRetry_Cancel(t *testing.T) {
+	server, calls := statusServer(t, 503)
+	env, _ := envelope.Parse([]byte(`{}`))
+	policy := testPolicy(5)
+	policy.BaseDelay, policy.MaxDelay = time.Hour, time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	d, err := sendWithRetry(ctx, context.Background(), server.Client(), env, server.URL, nil,
+		policy, ratelimit.NewSet(0, 1, nil), nil, testLogger(t))
+	if time.Since(started) > 5*time.Second {
+		t.Fatalf("ожидание повтора не прервано остановкой: %v", time.Since(started))
+	}
+	if err == nil || d.status != 503 || d.attempts != 1 || calls.Load() != 1 {
+		t.Errorf("ошибка = %v, статус = %d, попыток = %d, запросов = %d", err, d.status, d.attempts, calls.Load())
+	}
+
+	// Остановка во время ожидания ограничителя скорости до первой попытки: запрос не отправлен
+	limits := ratelimit.NewSet(1, 1, nil)
+	limits.Wait(context.Background(), "")
+	stopped, stop := context.WithCancel(context.Background())
+	stop()
+	d, err = sendWithRetry(stopped, context.Background(), server.Client(), env, server.URL, nil,
+		policy, limits, nil, testLogger(t))
+	if !errors.Is(err, errNotSent) || d.attempts != 0 || calls.Load() != 1 {
+		t.Errorf("ошибка = %v, попыток = %d, запросов = %d, ожидался errNotSent", err, d.attempts, calls.Load())
+	}
+}
+
+// TestSendWithRetry_Timing проверяет, что ожидание ограничителя скорости и паузы перед повторами
+// не входят в задержку ответа
+func TestSendWithRetry_Timing(t *testing.T) {
+	server, _ := statusServer(t, 503, 200)
+	env, _ := envelope.Parse([]byte(`{}`))
+	policy := testPolicy(2)
+	policy.BaseDelay, policy.MaxDelay = 100*time.Millisecond, 100*time.Millisecond
+
+	limits := ratelimit.NewSet(5, 1, nil) // Токен раз в 200ms
+	limits.Wait(context.Background(), "")
+	d, err := sendWithRetry(context.Background(), context.Background(), server.Client(), env, server.URL, nil,
+		policy, limits, nil, testLogger(t))
+	if err != nil || d.attempts != 2 {
+		t.Fatalf("ошибка = %v, попыток = %d", err, d.attempts)
+	}
+	if d.backoff < 100*time.Millisecond || d.throttle < 100*time.Millisecond {
+		t.Errorf("backoff = %v, throttle = %v, ожидалось не меньше 100ms", d.backoff, d.throttle)
+	}
+	if d.latency >= 100*time.Millisecond {
+		t.Errorf("задержка %v включает ожидание", d.latency)
+	}
+	if d.start.Add(d.latency).After(time.Now()) || time.Since(d.start) > time.Second {
+		t.Errorf("start = %v - не начало последней попытки", d.start)
+	}
+}
+
+// TestProcess_Attempts проверяет, что количество попыток доходит до записи отчета и ответ сохраняется
+func TestProcess_Attempts(t *testing.T) {
+	server, _ := statusServer(t, 503, 200)
+	dir := t.TempDir()
+	p := &pipeline{
+		client:       server.Client(),
+		url:          server.URL,
+		responsesDir: dir,
+		policy:       testPolicy(3),
+		limits:       ratelimit.NewSet(0, 1, nil),
+		expectations: expect.NewLoader(),
+		renderer:     render.New(nil, os.LookupEnv),
+	}
+	job := source.Job{Name: "a.json", Path: filepath.Join(dir, "a.json"), Data: []byte(`{"a": 1}`)}
+
+	result, sent := p.process(context.Background(), context.Background(), job, p.renderer, testLogger(t))
+	if !sent || result.Err != nil {
+		t.Fatalf("process() = %v, отправлен: %v", result.Err, sent)
+	}
+	if record := result.Record(); record.Attempts != 2 || record.StatusCode != 200 || record.Backoff <= 0 {
+		t.Errorf("попыток = %d, статус = %d, backoff = %v, ожидалось 2, 200 и паузу перед повтором", record.Attempts, record.StatusCode, record.Backoff)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.json")); err != nil {
+		t.Errorf("ответ не сохранен: %v", err)
+	}
+}
+
+// TestSendWithRetry_Headers проверяет, что общие заголовки конфигурации уходят в каждой попытке
+func TestSendWithRetry_Headers(t *testing.T) {
+	var (
+		auths []string
+		calls int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auths = append(auths, r.Header.Get("Authorization")+" "+r.Header.Get("X-Tenant"))
+		if calls++; calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	credentials, _ := auth.New("", "t0k", "", "")
+	headers := auth.Headers(map[string]string{"X-Tenant": "42"}, credentials)
+	env, _ := envelope.Parse([]byte(`{}`))
+	d, err := sendWithRetry(context.Background(), context.Background(), server.Client(), env, server.URL, headers,
+		testPolicy(2), ratelimit.NewSet(0, 1, nil), nil, testLogger(t))
+	if err != nil || d.attempts != 2 {
+		t.Fatalf("ошибка = %v, попыток = %d", err, d.attempts)
+	}
+	for i, got := range auths {
+		if got != "Bearer t0k 42" {
+			t.Errorf("попытка %d: заголовки %q, ожидалось Bearer t0k 42", i+1, got)
+		}
+	}
+
+	// Заголовок конверта важнее общего
+	env, _ = envelope.Parse([]byte(`{"method": "POST", "headers": {"Authorization": "Bearer own"}}`))
+	auths = nil
+	if _, err := sendWithRetry(context.Background(), context.Background(), server.Client(), env, server.URL, headers,
+		testPolicy(1), ratelimit.NewSet(0, 1, nil), nil, testLogger(t)); err != nil {
+		t.Fatal(err)
+	}
+	if len(auths) != 1 || auths[0] != "Bearer own 42" {
+		t.Errorf("заголовки %v, ожидалось Bearer own 42", auths)
+	}
+}
+
+// TestProcess_Template проверяет, что без -template запрос отправляется как есть, а с ним - после подстановки
+func TestProcess_Template(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	t.Setenv("POSTER_TEST_TOKEN", "secret")
+	data := `{"text": "${seq} ${env.POSTER_TEST_TOKEN} {{x}}"}`
+	tests := []struct {
+		name     string
+		renderer *render.Renderer
+		want     string
+	}{
+		{"без шаблонов", nil, data},
+		{"с шаблонами", render.New(nil, os.LookupEnv), `{"text": "1 secret {{x}}"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := &pipeline{
+				client:       server.Client(),
+				url:          server.URL,
+				policy:       testPolicy(1),
+				limits:       ratelimit.NewSet(0, 1, nil),
+				expectations: expect.NewLoader(),
+				renderer:     test.renderer,
+
+				responsesStream: true,
+			}
+			job := source.Job{Name: "a.json", Data: []byte(data)}
+			if test.renderer != nil {
+				job.Data = []byte(`{"text": "${seq} ${env.POSTER_TEST_TOKEN} {{"{{"}}x}}"}`)
+			}
+			result, _ := p.process(context.Background(), context.Background(), job, p.renderer, testLogger(t))
+			if result.Err != nil || body != test.want {
+				t.Errorf("ошибка = %v, отправлено %s, ожидалось %s", result.Err, body, test.want)
+			}
+		})
+	}
+}
+
+// TestProcess_BadURL проверяет, что запрос с неразрешимым адресом не отправляется
+func TestProcess_BadURL(t *testing.T) {
+	server, calls := statusServer(t, 200)
+	p := &pipeline{
+		client:       server.Client(),
+		url:          server.URL,
+		policy:       testPolicy(3),
+		limits:       ratelimit.NewSet(0, 1, nil),
+		expectations: expect.NewLoader(),
+
+		responsesStream: true,
+	}
+	job := source.Job{Name: "a.json", Data: []byte(`{"method": "GET", "url": "http://[::1/orders"}`)}
+
+	result, _ := p.process(context.Background(), context.Background(), job, p.renderer, testLogger(t))
+	if result.Err == nil || result.ErrType != report.ErrEnvelope || calls.Load() != 0 {
+		t.Errorf("ошибка = %v, тип = %s, запросов = %d, ожидалась ошибка конверта без отправки", result.Err, result.ErrType, calls.Load())
+	}
+}
+
+// TestSubcommands_Output проверяет, что подкоманды пишут результат в stdout, а ошибки - в stderr
+func TestSubcommands_Output(t *testing.T) {
+	baseline, responses := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(baseline, "a.json"), []byte(`{"id": 1}`), 0644)
+	os.WriteFile(filepath.Join(responses, "a.json"), []byte(`{"id": 2}`), 0644)
+	missing := filepath.Join(t.TempDir(), "нет")
+
+	tests := []struct {
+		name       string
+		command    func(args []string, stdout, stderr io.Writer) int
+		args       []string
+		wantCode   int
+		wantStdout bool // Ожидается вывод в stdout
+		wantStderr bool // Ожидается вывод в stderr
+	}{
+		{"diff с изменениями", diffCommand, []string{baseline, responses}, exitChanged, true, false},
+		{"diff без директории ответов", diffCommand, []string{baseline, missing}, exitError, false, true},
+		{"report без отчета", reportCommand, []string{missing}, exitError, false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := test.command(test.args, &stdout, &stderr)
+			if code != test.wantCode || (stdout.Len() > 0) != test.wantStdout || (stderr.Len() > 0) != test.wantStderr {
+				t.Errorf("код = %d, stdout = %q, stderr = %q", code, stdout.String(), stderr.String())
+			}
+		})
+	}
+}